@@ -0,0 +1,163 @@
+package statuspage
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// renderLimit identifies one of the RenderLimits
+type renderLimit int
+
+const (
+	limitDepth renderLimit = 1 << iota
+	limitNodes
+	limitBytes
+)
+
+func (l renderLimit) String() string {
+	switch l {
+	case limitDepth:
+		return "depth limit"
+	case limitNodes:
+		return "value limit"
+	case limitBytes:
+		return "size limit"
+	default:
+		return "limit " + strconv.Itoa(int(l))
+	}
+}
+
+// nodeOverheadBytes approximates the markup generated around each value
+// (tags, attributes, borders, etc.) for the purposes of RenderLimits.MaxBytes.
+const nodeOverheadBytes = 24
+
+// renderBudget tracks a render's usage against its RenderLimits
+type renderBudget struct {
+	// depth is the nesting depth of the value currently being rendered
+	depth int
+	nodes int
+	bytes int
+	// hit is the set of limits that have been reached
+	hit renderLimit
+}
+
+// exhausted returns the global limit (nodes or bytes) that's been used up,
+// or 0 if there's still room. Loops over the elements of a value (which
+// can be arbitrarily long, unlike the fields of a struct) check this so they
+// can stop early instead of emitting a truncation marker per element.
+func (r *renderer) exhausted() renderLimit {
+	return r.budget.exhausted(r.opts.limits)
+}
+
+// exhausted returns the global limit of l (nodes or bytes) the budget has
+// used up, or 0 if there's still room
+func (b *renderBudget) exhausted(l RenderLimits) renderLimit {
+	switch {
+	case l.MaxNodes > 0 && b.nodes >= l.MaxNodes:
+		b.hit |= limitNodes
+		return limitNodes
+	case l.MaxBytes > 0 && b.bytes >= l.MaxBytes:
+		b.hit |= limitBytes
+		return limitBytes
+	default:
+		return 0
+	}
+}
+
+// descends reports whether rendering v means descending into its contents
+// (following any pointers and interfaces)
+func descends(v reflect.Value) bool {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return rendersTable(v)
+}
+
+// enterValue accounts for rendering one more value, v. If a limit prevents
+// it, that limit is returned (and the value should be replaced with a
+// truncation marker); otherwise it returns 0 and a func to call when done
+// with the value. The depth limit only applies to values with contents to
+// descend into; scalars at the deepest level are still rendered.
+func (r *renderer) enterValue(v reflect.Value) (renderLimit, func()) {
+	if l := r.exhausted(); l != 0 {
+		return l, nil
+	}
+	r.budget.nodes++
+	r.budget.bytes += nodeOverheadBytes
+	if !descends(v) {
+		return 0, func() {}
+	}
+	if r.opts.limits.MaxDepth > 0 && r.budget.depth >= r.opts.limits.MaxDepth {
+		r.budget.hit |= limitDepth
+		return limitDepth, nil
+	}
+	r.budget.depth++
+	return 0, func() { r.budget.depth-- }
+}
+
+// spendBytes accounts for n bytes of rendered text
+func (r *renderer) spendBytes(n int) {
+	r.budget.bytes += n
+}
+
+// truncationLabel returns the text of a truncation marker for limit l
+func truncationLabel(l renderLimit) string {
+	return "⋯ truncated (" + l.String() + ")"
+}
+
+// truncated returns the marker rendered in place of the current value when
+// limit l prevents rendering it. The value's sub-page gets a fresh budget,
+// so we link to it if we can.
+func (r *renderer) truncated(l renderLimit) []*html.Node {
+	if !r.subPages {
+		return []*html.Node{r.scalarNode("sp-truncated", truncationLabel(l))}
+	}
+	a := createElemClass(atom.A, "sp-truncated")
+	a.Attr = append(a.Attr, html.Attribute{Key: atom.Href.String(), Val: r.pathURL(r.path)})
+	a.AppendChild(textNode(truncationLabel(l)))
+	return []*html.Node{a}
+}
+
+// truncatedText is the plain-text counterpart to truncated, including the
+// sub-page's URL path if we know it.
+func (r *renderer) truncatedText(l renderLimit) string {
+	if !r.subPages {
+		return truncationLabel(l)
+	}
+	return truncationLabel(l) + ": " + r.pathURL(r.path)
+}
+
+// truncatedRow returns a table row spanning nCols columns marking where a
+// loop over elements stopped due to limit l. If nCols is 0 (the width isn't
+// known yet), it's up to the caller to set the cell's span once it is.
+func (r *renderer) truncatedRow(l renderLimit, nCols int) *html.Node {
+	row := createElemClass(atom.Tr, "sp-truncated-row")
+	cell := createElemAtom(atom.Td)
+	setColspan(cell, nCols)
+	for _, n := range r.truncated(l) {
+		cell.AppendChild(n)
+	}
+	row.AppendChild(cell)
+	return row
+}
+
+// limitsHit describes the limits reached during the render (empty if none
+// were)
+func (r *renderer) limitsHit() string {
+	return r.budget.limitsHit()
+}
+
+// limitsHit describes the limits the budget reached (empty if none were)
+func (b *renderBudget) limitsHit() string {
+	hit := []string{}
+	for _, l := range []renderLimit{limitDepth, limitNodes, limitBytes} {
+		if b.hit&l != 0 {
+			hit = append(hit, l.String())
+		}
+	}
+	return strings.Join(hit, ", ")
+}
@@ -0,0 +1,104 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderBudget(t *testing.T) {
+	l := RenderLimits{MaxNodes: 10, MaxBytes: 100}
+	for _, tc := range []struct {
+		b    renderBudget
+		want renderLimit
+	}{
+		{renderBudget{nodes: 9, bytes: 99}, 0},
+		{renderBudget{nodes: 10}, limitNodes},
+		{renderBudget{bytes: 100}, limitBytes},
+		{renderBudget{nodes: 10, bytes: 100}, limitNodes},
+		// depth only stops descending, so it doesn't exhaust the budget
+		{renderBudget{depth: 1000}, 0},
+	} {
+		b := tc.b
+		if got := b.exhausted(l); got != tc.want {
+			t.Errorf("%+v exhausted = %s; want %s", tc.b, got, tc.want)
+		}
+		if got := b.hit; got != tc.want {
+			t.Errorf("%+v hit = %s after exhausted; want %s", tc.b, got, tc.want)
+		}
+	}
+	if b := (renderBudget{nodes: 1 << 30}); b.exhausted(RenderLimits{}) != 0 {
+		t.Error("zero limits are exhausted")
+	}
+
+	b := renderBudget{hit: limitBytes | limitDepth}
+	if got, want := b.limitsHit(), "depth limit, size limit"; got != want {
+		t.Errorf("limitsHit = %q; want %q", got, want)
+	}
+}
+
+type budgetNode struct {
+	N    int
+	Next *budgetNode
+}
+
+type budgetVal struct {
+	Deep *budgetNode
+	Strs []string
+}
+
+func TestRenderLimits(t *testing.T) {
+	v := budgetVal{
+		Deep: &budgetNode{N: 1, Next: &budgetNode{N: 2, Next: &budgetNode{N: 3}}},
+		Strs: []string{strings.Repeat("x", 100), "y", "z"},
+	}
+	for _, tc := range []struct {
+		name   string
+		limits RenderLimits
+		format string
+		want   []string
+		header string
+	}{
+		{"depth", RenderLimits{MaxDepth: 3}, "", []string{
+			// the truncated value links to its sub-page, which has a fresh
+			// budget
+			`<a class="sp-truncated" href="/status/Deep/Next">⋯ truncated (depth limit)</a>`,
+			"Rendering was truncated (reached depth limit)",
+		}, "depth limit"},
+		{"depth in JSON", RenderLimits{MaxDepth: 3}, "json", []string{`"$truncated": "depth limit"`}, "depth limit"},
+		{"depth in text", RenderLimits{MaxDepth: 3}, "text", []string{
+			"⋯ truncated (depth limit): /status/Deep/Next",
+			"Rendering was truncated (reached depth limit).",
+		}, "depth limit"},
+		{"nodes", RenderLimits{MaxNodes: 10}, "", []string{
+			`<a class="sp-truncated" href="/status/Strs">⋯ truncated (value limit)</a>`,
+			"Rendering was truncated (reached value limit)",
+		}, "value limit"},
+		{"bytes", RenderLimits{MaxBytes: 300}, "json", []string{`"Strs": [
+    "` + strings.Repeat("x", 100) + `",
+    {
+      "$truncated": "size limit"
+    }
+  ]`}, "size limit"},
+		{"unlimited", RenderLimits{}, "json", []string{`"z"`}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/status/", New("Test", func() budgetVal { return v }, WithRenderLimits(tc.limits)))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/?format="+tc.format, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, rec.Body)
+				}
+			}
+			if got := rec.Header().Get(truncatedHeader); got != tc.header {
+				t.Errorf("%s = %q; want %q", truncatedHeader, got, tc.header)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxCachedOutputs bounds the number of renderings (formats, sub-pages and
+// pages of long sequences) cached per snapshot.
+const maxCachedOutputs = 64
+
+// snapshot is a value returned by a Status's callback, along with any
+// renderings of it that have been cached.
+type snapshot[T any] struct {
+	v        T
+	loadedAt time.Time
+
+	mu      sync.Mutex
+	outputs map[outputKey]*bufferedResponse
+
+	// health is the verdict of the snapshot's health checks, computed on
+	// first use
+	healthOnce sync.Once
+	health     healthVerdict
+}
+
+// outputKey identifies a rendering of a snapshot
+type outputKey struct {
+	format   outputFormat
+	basePath string
+	subPath  string
+	query    string
+}
+
+// cachedOutput returns the cached rendering for key, if there is one
+func (sn *snapshot[T]) cachedOutput(key outputKey) (*bufferedResponse, bool) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	out, ok := sn.outputs[key]
+	return out, ok
+}
+
+// cacheOutput caches resp as the rendering for key (if there's room)
+func (sn *snapshot[T]) cacheOutput(key outputKey, resp *bufferedResponse) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	if sn.outputs == nil {
+		sn.outputs = map[outputKey]*bufferedResponse{}
+	}
+	if len(sn.outputs) < maxCachedOutputs {
+		sn.outputs[key] = resp
+	}
+}
+
+// loadCall is a call to a Status's callback that concurrent requests can
+// wait on.
+type loadCall[T any] struct {
+	done chan struct{}
+	snap *snapshot[T]
+	err  error
+}
+
+// statusCache coalesces concurrent calls to a Status's callback, caches its
+// result (if a TTL is set), and limits concurrent renders.
+type statusCache[T any] struct {
+	mu       sync.Mutex
+	cur      *snapshot[T]
+	inflight *loadCall[T]
+	// renders is a semaphore bounding concurrent renders (nil if unbounded)
+	renders chan struct{}
+}
+
+// snapshot returns the current snapshot, calling the callback if there isn't
+// a fresh one cached. Requests arriving while a call is in progress wait for
+// it instead of making calls of their own. Since the call is shared, it
+// isn't canceled when the request that made it is; WithTimeout bounds it
+// instead.
+func (s *Status[T]) snapshot(ctx context.Context) (*snapshot[T], error) {
+	s.cache.mu.Lock()
+	if cur := s.cache.cur; cur != nil && time.Since(cur.loadedAt) < s.opts.cacheTTL {
+		s.cache.mu.Unlock()
+		return cur, nil
+	}
+	call := s.cache.inflight
+	if call == nil {
+		call = &loadCall[T]{done: make(chan struct{})}
+		s.cache.inflight = call
+		go s.runLoad(context.WithoutCancel(ctx), call)
+	}
+	s.cache.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.snap, call.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gave up waiting for status callback: %w", context.Cause(ctx))
+	}
+}
+
+func (s *Status[T]) runLoad(ctx context.Context, call *loadCall[T]) {
+	loadedAt := time.Now()
+	v, err := s.load(ctx)
+	if err == nil {
+		call.snap = &snapshot[T]{v: v, loadedAt: loadedAt}
+		if s.trends != nil {
+			s.trends.record(reflect.ValueOf(&v).Elem(), loadedAt)
+		}
+	}
+	call.err = err
+
+	s.cache.mu.Lock()
+	s.cache.inflight = nil
+	if err == nil && s.opts.cacheTTL > 0 {
+		s.cache.cur = call.snap
+	}
+	s.cache.mu.Unlock()
+	close(call.done)
+}
+
+// acquireRender reserves one of the concurrent render slots, returning a
+// func releasing it, or false if they're all in use.
+func (s *Status[T]) acquireRender() (func(), bool) {
+	if s.cache.renders == nil {
+		return func() {}, true
+	}
+	select {
+	case s.cache.renders <- struct{}{}:
+		return func() { <-s.cache.renders }, true
+	default:
+		return nil, false
+	}
+}
+
+// renderRetryAfter is the Retry-After (in seconds) sent with responses
+// rejected because too many renders are in progress.
+const renderRetryAfter = 1
+
+// bufferedResponse is an http.ResponseWriter that holds onto the response,
+// so it can be cached.
+type bufferedResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: http.Header{}, code: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponse) WriteHeader(code int) { b.code = code }
+
+// writeTo copies the response to w, along with an Age header for a snapshot
+// loaded at loadedAt.
+func (b *bufferedResponse) writeTo(w http.ResponseWriter, loadedAt time.Time) {
+	maps.Copy(w.Header(), b.header)
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(loadedAt).Seconds())))
+	w.WriteHeader(b.code)
+	w.Write(b.body.Bytes())
+}
+
+// snapshotTimeLayout is the layout of the snapshot times shown on cached pages
+const snapshotTimeLayout = "2006-01-02 15:04:05 MST"
+
+// snapshotSource describes where the snapshot being rendered came from, to
+// follow its time
+func (r *renderer) snapshotSource() string {
+	if r.historical {
+		return " (from the history)"
+	}
+	return " (cached for up to " + r.opts.cacheTTL.String() + ")"
+}
@@ -0,0 +1,182 @@
+package statuspage_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	statuspage "github.com/vimeo/go-status-page"
+	"golang.org/x/net/html"
+)
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestCacheTTL(t *testing.T) {
+	var calls atomic.Int32
+	s := statuspage.New("Test", func() elem {
+		return elem{A: int(calls.Add(1))}
+	}, statuspage.WithCacheTTL(time.Hour))
+
+	first := get(s, "/")
+	second := get(s, "/")
+	if n := calls.Load(); n != 1 {
+		t.Errorf("callback called %d times within the TTL; want 1", n)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("cached page differs from the first:\n%s\nvs\n%s", first.Body, second.Body)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{first, second} {
+		if rec.Code != http.StatusOK || rec.Header().Get("Age") != "0" {
+			t.Errorf("status %d, Age %q; want 200 with an Age of 0", rec.Code, rec.Header().Get("Age"))
+		}
+	}
+	if !strings.Contains(first.Body.String(), "(cached for up to 1h0m0s)") {
+		t.Errorf("page doesn't say the snapshot's cached:\n%s", first.Body)
+	}
+	if text := get(s, "/?format=text").Body.String(); !strings.Contains(text, "Snapshot as of ") {
+		t.Errorf("text page doesn't give the snapshot's time:\n%s", text)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("callback called %d times for another format of a cached snapshot; want 1", n)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	var calls atomic.Int32
+	s := statuspage.New("Test", func() elem {
+		return elem{A: int(calls.Add(1))}
+	}, statuspage.WithCacheTTL(time.Millisecond))
+	get(s, "/")
+	time.Sleep(5 * time.Millisecond)
+	if body := get(s, "/").Body.String(); !strings.Contains(body, ">2 (0x2)<") {
+		t.Errorf("expired snapshot was served:\n%s", body)
+	}
+
+	// without a TTL, each request calls the callback
+	calls.Store(0)
+	s = statuspage.New("Test", func() elem {
+		return elem{A: int(calls.Add(1))}
+	})
+	get(s, "/")
+	rec := get(s, "/")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("callback called %d times for 2 requests without a TTL; want 2", n)
+	}
+	if age := rec.Header().Get("Age"); age != "" {
+		t.Errorf("uncached page has an Age of %q", age)
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	var calls atomic.Int32
+	s := statuspage.NewCtx("Test", func(context.Context) (elem, error) {
+		if calls.Add(1) == 1 {
+			return elem{}, errors.New("not yet")
+		}
+		return elem{A: 1}, nil
+	}, statuspage.WithCacheTTL(time.Hour))
+	if rec := get(s, "/"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("first request: status %d; want 503", rec.Code)
+	}
+	if rec := get(s, "/"); rec.Code != http.StatusOK {
+		t.Errorf("request after a failed call: status %d; want 200 (failures aren't cached)", rec.Code)
+	}
+}
+
+func TestCallCoalescing(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	s := statuspage.New("Test", func() elem {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return elem{A: 1}
+	}, statuspage.WithCacheTTL(time.Hour))
+
+	const requests = 20
+	wg := sync.WaitGroup{}
+	codes := make([]int, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = get(s, "/").Code
+		}()
+	}
+	<-started
+	// give the other requests time to pile up behind the call (any that
+	// arrive after it are served from the cache anyway)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("callback called %d times for %d concurrent requests; want 1", n, requests)
+	}
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: status %d", i, code)
+		}
+	}
+}
+
+// slowRenderer blocks rendering until release is closed, once it's been
+// armed
+type slowRenderer struct {
+	armed            *atomic.Bool
+	started, release chan struct{}
+}
+
+func (s slowRenderer) StatusPageNodes(ctx statuspage.RenderContext) ([]*html.Node, error) {
+	if s.armed.CompareAndSwap(true, false) {
+		close(s.started)
+		<-s.release
+	}
+	return ctx.RenderDefault()
+}
+
+func TestMaxConcurrentRenders(t *testing.T) {
+	v := struct{ Slow slowRenderer }{Slow: slowRenderer{
+		armed:   &atomic.Bool{},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}}
+	s := statuspage.New("Test", func() struct{ Slow slowRenderer } { return v },
+		statuspage.WithCacheTTL(time.Hour), statuspage.WithMaxConcurrentRenders(1))
+	if rec := get(s, "/?format=text"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	v.Slow.armed.Store(true)
+	done := make(chan int)
+	go func() { done <- get(s, "/").Code }()
+	<-v.Slow.started
+
+	rec := get(s, "/?format=json")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("render beyond the limit: status %d, Retry-After %q; want 429 with Retry-After 1",
+			rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// cached renderings don't need a slot
+	if rec := get(s, "/?format=text"); rec.Code != http.StatusOK {
+		t.Errorf("cached rendering: status %d; want 200", rec.Code)
+	}
+
+	close(v.Slow.release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("slow render: status %d", code)
+	}
+	if rec := get(s, "/?format=json"); rec.Code != http.StatusOK {
+		t.Errorf("render once the slot's free: status %d; want 200", rec.Code)
+	}
+}
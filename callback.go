@@ -0,0 +1,92 @@
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultTimeout bounds calls to the Status's callback unless overridden
+// with WithTimeout.
+const defaultTimeout = 30 * time.Second
+
+// load calls the Status's callback with ctx, bounded by the configured
+// timeout. (Since calls are shared, ctx doesn't carry the cancellation of the
+// request that made the call; see Status.snapshot.) If the timeout expires
+// before the callback returns, load gives up on it and returns an error; the
+// callback is left to finish in the background, with its context canceled.
+// Panics in the callback are returned as errors, since they happen on a
+// goroutine of their own.
+func (s *Status[T]) load(ctx context.Context) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.timeout)
+	defer cancel()
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("status callback panicked: %v", p)}
+			}
+		}()
+		v, err := s.cb(ctx)
+		done <- result{v: v, err: err}
+	}()
+	select {
+	case res := <-done:
+		return res.v, res.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, fmt.Errorf("status callback timed out after %s", s.opts.timeout)
+		}
+		return zero, fmt.Errorf("status callback abandoned: %w", context.Cause(ctx))
+	}
+}
+
+// errorMessage is the heading for error responses
+const errorMessage = "Failed to load status"
+
+// serveError responds with err and the status code in the requested format.
+func serveError(w http.ResponseWriter, rn *renderer, format outputFormat, code int, err error) {
+	switch format {
+	case formatJSON:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(jsonObject{{Key: "error", Val: err.Error()}, {Key: "status", Val: code}})
+	case formatText, formatOpenMetrics:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(code)
+		title := rn.pageTitle()
+		io.WriteString(w, title+"\n"+strings.Repeat("═", utf8.RuneCountInString(title))+"\n\n"+
+			errorMessage+": "+err.Error()+"\n")
+	default:
+		if rn.opts.layout != nil {
+			serveLayoutError(w, rn, code, errorMessage, err)
+			return
+		}
+		root, main := rn.htmlDocument()
+		heading := createElemClass(atom.H2, "sp-error")
+		heading.AppendChild(textNode(errorMessage))
+		main.AppendChild(heading)
+		msg := createElemClass(atom.Pre, "sp-error")
+		msg.AppendChild(textNode(err.Error()))
+		main.AppendChild(msg)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(code)
+		html.Render(w, root)
+	}
+}
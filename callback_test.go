@@ -0,0 +1,133 @@
+package statuspage_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	statuspage "github.com/vimeo/go-status-page"
+)
+
+type ctxKey struct{}
+
+func TestNewCtx(t *testing.T) {
+	s := statuspage.NewCtx("Test", func(ctx context.Context) (elem, error) {
+		user, _ := ctx.Value(ctxKey{}).(string)
+		return elem{A: 1, B: user}, nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "from the request"))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	// the callback sees the request's values
+	if !strings.Contains(rec.Body.String(), ">from the request<") {
+		t.Errorf("page doesn't show the value from the request's context:\n%s", rec.Body)
+	}
+}
+
+func TestCallbackErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		cb      func(ctx context.Context) (elem, error)
+		opts    []statuspage.Option
+		wantErr string
+	}{
+		{"error", func(context.Context) (elem, error) {
+			return elem{}, errors.New("pool exhausted")
+		}, nil, "pool exhausted"},
+		{"timeout", func(ctx context.Context) (elem, error) {
+			<-ctx.Done()
+			return elem{}, ctx.Err()
+		}, []statuspage.Option{statuspage.WithTimeout(10 * time.Millisecond)}, "status callback timed out after 10ms"},
+		{"panic", func(context.Context) (elem, error) {
+			panic("out of cheese")
+		}, nil, "status callback panicked: out of cheese"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := statuspage.NewCtx("Test", tc.cb, tc.opts...)
+			for _, format := range []struct {
+				query, contentType, want string
+			}{
+				{"", "text/html", `<pre class="sp-error">` + tc.wantErr},
+				{"?format=text", "text/plain", "Failed to load status: " + tc.wantErr},
+				{"?format=openmetrics", "text/plain", "Failed to load status: " + tc.wantErr},
+			} {
+				rec := httptest.NewRecorder()
+				s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+format.query, nil))
+				if rec.Code != http.StatusServiceUnavailable {
+					t.Errorf("GET /%s: status %d; want 503", format.query, rec.Code)
+				}
+				if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, format.contentType) {
+					t.Errorf("GET /%s: Content-Type %q; want %s", format.query, ct, format.contentType)
+				}
+				if !strings.Contains(rec.Body.String(), format.want) {
+					t.Errorf("GET /%s: body doesn't contain %q:\n%s", format.query, format.want, rec.Body)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+			var body struct {
+				Error  string
+				Status int
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("error JSON: %s:\n%s", err, rec.Body)
+			}
+			if rec.Code != http.StatusServiceUnavailable || body.Status != http.StatusServiceUnavailable || body.Error != tc.wantErr {
+				t.Errorf("GET /?format=json: status %d, body %+v; want 503 with the error %q", rec.Code, body, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallbackTimeoutCancels(t *testing.T) {
+	canceled := make(chan error, 1)
+	s := statuspage.NewCtx("Test", func(ctx context.Context) (elem, error) {
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return elem{}, ctx.Err()
+	}, statuspage.WithTimeout(10*time.Millisecond))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d; want 503", rec.Code)
+	}
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("callback's context ended with %v; want the deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback's context wasn't canceled at the timeout")
+	}
+}
+
+func TestCallbackRequestCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	s := statuspage.NewCtx("Test", func(ctx context.Context) (elem, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return elem{}, ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	// the request gives up, but the call goes on for any other requests
+	// waiting on it
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "gave up waiting for status callback") {
+		t.Errorf("status %d: %s; want a 503 for giving up on the callback", rec.Code, rec.Body)
+	}
+}
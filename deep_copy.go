@@ -0,0 +1,153 @@
+package statuspage
+
+import (
+	"reflect"
+	"time"
+)
+
+// sharedTypes are pointer types whose targets are treated as immutable, so
+// deep copies share them rather than copying them.
+var sharedTypes = map[reflect.Type]struct{}{
+	// time.Local and time.UTC are compared by address
+	reflect.TypeFor[*time.Location](): {},
+}
+
+// deepCopy returns a copy of the parts of v that render (with the options o),
+// sharing no memory with v, so the copy renders the same way after later
+// mutations of v. That leaves out unexported fields and fields tagged to be
+// skipped (which stay zero), since copying the internals of mutexes, pools,
+// clients and the like isn't safe. Values that render themselves (with
+// String, Error or RenderStatus methods, or a renderer set with WithRenderer
+// or RegisterRenderer) are copied by assignment, so whatever they point to is
+// shared, as are channels, funcs and unsafe pointers, which can't be copied.
+//
+// Values reached through the same pointer or map (including cycles), or the
+// same slice (with the same length), are copied once and shared in the copy,
+// so the history renders aliasing as the live value does.
+func deepCopy[T any](v T, o *options) T {
+	c := copier{opts: o, copies: map[visitKey]reflect.Value{}}
+	out := reflect.New(reflect.TypeFor[T]()).Elem()
+	c.copyInto(out, reflect.ValueOf(&v).Elem())
+	return out.Interface().(T)
+}
+
+// copier holds the state for a single deepCopy
+type copier struct {
+	opts *options
+	// copies maps the pointers, maps and slices copied so far to their
+	// copies
+	copies map[visitKey]reflect.Value
+}
+
+// rendersItselfType reports whether values of type t render themselves,
+// rather than by their contents
+func (c *copier) rendersItselfType(t reflect.Type) bool {
+	if _, ok := c.opts.typeRendererFor(t); ok {
+		return true
+	}
+	if c.opts.pointsToRendered(t) {
+		// the target is what's rendered
+		return false
+	}
+	return t.Implements(statusRendererReflectType) || t.Implements(errorType) || eligibleStringer(t)
+}
+
+// copyInto sets dst (which must be settable) to a deep copy of src (which
+// must have been reached without going through unexported fields).
+func (c *copier) copyInto(dst, src reflect.Value) {
+	if c.rendersItselfType(src.Type()) {
+		dst.Set(src)
+		return
+	}
+	switch src.Kind() {
+	case reflect.Pointer:
+		if _, shared := sharedTypes[src.Type()]; shared || src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		key := visitKey{ptr: src.Pointer(), typ: src.Type()}
+		if cp, ok := c.copies[key]; ok {
+			dst.Set(cp)
+			return
+		}
+		cp := reflect.New(src.Type().Elem())
+		c.copies[key] = cp
+		dst.Set(cp)
+		c.copyInto(cp.Elem(), src.Elem())
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		key := visitKey{ptr: src.Pointer(), typ: src.Type()}
+		if cp, ok := c.copies[key]; ok {
+			dst.Set(cp)
+			return
+		}
+		cp := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.copies[key] = cp
+		dst.Set(cp)
+		for k, e := range src.Seq2() {
+			kc := reflect.New(k.Type()).Elem()
+			c.copyInto(kc, k)
+			ec := reflect.New(e.Type()).Elem()
+			c.copyInto(ec, e)
+			cp.SetMapIndex(kc, ec)
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		key, trackable := visitKeyOf(src)
+		if cp, ok := c.copies[key]; ok && trackable {
+			dst.Set(cp)
+			return
+		}
+		cp := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		if trackable {
+			c.copies[key] = cp
+		}
+		dst.Set(cp)
+		for i := range src.Len() {
+			c.copyInto(cp.Index(i), src.Index(i))
+		}
+	case reflect.Array:
+		for i := range src.Len() {
+			c.copyInto(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		c.copyFields(dst, src)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := src.Elem()
+		ec := reflect.New(e.Type()).Elem()
+		c.copyInto(ec, e)
+		dst.Set(ec)
+	default:
+		// scalars, strings (which are immutable), and the kinds that
+		// can't be copied
+		dst.Set(src)
+	}
+}
+
+// copyFields copies the fields of the struct src that render into dst: its
+// exported fields that aren't skipped, including those promoted from
+// embedded structs.
+func (c *copier) copyFields(dst, src reflect.Value) {
+	t := src.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if tag, tagErr := parseFieldTag(f); tagErr == nil && tag.skip {
+			continue
+		}
+		switch {
+		case f.IsExported():
+			c.copyInto(dst.Field(i), src.Field(i))
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			// an embedded struct of an unexported type, whose exported
+			// fields are promoted (and can be set)
+			c.copyFields(dst.Field(i), src.Field(i))
+		}
+	}
+}
@@ -0,0 +1,694 @@
+package statuspage
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Classes of the rows of diff tables that changed
+const (
+	diffAdded   = "sp-added"
+	diffRemoved = "sp-removed"
+	diffChanged = "sp-changed"
+)
+
+// maxLCSCells bounds the size of the table used to match up the elements of
+// two sequences being diffed. Longer sequences are compared index by index.
+const maxLCSCells = 1 << 20
+
+// GenDiffHTMLNodes renders the differences between before and after, in the
+// same layout GenHTMLNodes uses for a single value. Changed values are
+// rendered as old → new (in <del> and <ins> elements), added and removed map
+// entries, slice elements and struct fields are highlighted, and unchanged
+// sections are collapsed.
+func GenDiffHTMLNodes[T any](before, after T) ([]*html.Node, error) {
+	opts := defaultOptions()
+	return newRenderer(&opts, "").genDiffSection(reflect.ValueOf(&before).Elem(), reflect.ValueOf(&after).Elem())
+}
+
+// valuesEqual reports whether a and b are deeply equal. Iterators are equal
+// if they yield equal elements, and other funcs if they're the same func
+// (reflect.DeepEqual only considers nil funcs equal).
+func valuesEqual(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if a.Kind() == reflect.Func {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Type().CanSeq() || a.Type().CanSeq2() {
+			return seqsEqual(a, b)
+		}
+		return a.Pointer() == b.Pointer()
+	}
+	// values reached through unexported fields can't be compared, so
+	// they're taken to have changed
+	return a.CanInterface() && b.CanInterface() && reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// maxSeqCompare bounds the number of elements compared by seqsEqual.
+// Iterators yielding more are taken to have changed.
+const maxSeqCompare = 1 << 16
+
+// seqsEqual reports whether the iterators a and b (of the same type) yield
+// equal elements. Iterators reached through unexported fields can't be
+// called, so they're only equal if they're the same func.
+func seqsEqual(a, b reflect.Value) bool {
+	if !a.CanInterface() || !b.CanInterface() {
+		return a.Pointer() == b.Pointer()
+	}
+	nextA, stopA := iter.Pull2(seqPairs(a))
+	defer stopA()
+	nextB, stopB := iter.Pull2(seqPairs(b))
+	defer stopB()
+	for range maxSeqCompare {
+		ak, ae, okA := nextA()
+		bk, be, okB := nextB()
+		if okA != okB {
+			return false
+		}
+		if !okA {
+			return true
+		}
+		if !valuesEqual(ak, bk) || !valuesEqual(ae, be) {
+			return false
+		}
+	}
+	return false
+}
+
+// seqPairs iterates over the elements of the iterator v as pairs, with
+// invalid keys for iter.Seqs
+func seqPairs(v reflect.Value) iter.Seq2[reflect.Value, reflect.Value] {
+	if v.Type().CanSeq2() {
+		return v.Seq2()
+	}
+	return func(yield func(reflect.Value, reflect.Value) bool) {
+		for e := range v.Seq() {
+			if !yield(reflect.Value{}, e) {
+				return
+			}
+		}
+	}
+}
+
+// genOldSection renders v, a value from the "before" side of a diff. It's
+// rendered independently of the rest of the page, without anchors (which
+// would clash with those of the "after" side), links to sub-pages or trends
+// (which show the current value).
+func (r *renderer) genOldSection(v reflect.Value) ([]*html.Node, error) {
+	subPages, trends := r.subPages, r.trends
+	r.subPages, r.trends = false, nil
+	exitVisits := r.withFreshVisits()
+	defer func() {
+		exitVisits()
+		r.subPages, r.trends = subPages, trends
+	}()
+	ns, err := r.genValSection(v)
+	for _, n := range ns {
+		stripIDs(n)
+	}
+	return ns, err
+}
+
+// stripIDs removes the id attributes from n and its descendants
+func stripIDs(n *html.Node) {
+	n.Attr = slices.DeleteFunc(n.Attr, func(a html.Attribute) bool { return a.Key == atom.Id.String() })
+	for c := range n.ChildNodes() {
+		stripIDs(c)
+	}
+}
+
+// wrapNodes returns an element of type a holding ns
+func wrapNodes(a atom.Atom, ns []*html.Node) *html.Node {
+	n := createElemAtom(a)
+	for _, c := range ns {
+		n.AppendChild(c)
+	}
+	return n
+}
+
+// genDiffSection renders the differences between a and b (which have the same
+// type, though either may be invalid if it's missing on its side). Like
+// genValSection, failures are rendered inline.
+func (r *renderer) genDiffSection(a, b reflect.Value) ([]*html.Node, error) {
+	switch {
+	case !a.IsValid():
+		ns, err := r.genValSection(b)
+		return []*html.Node{wrapNodes(atom.Ins, ns)}, err
+	case !b.IsValid():
+		ns, err := r.genOldSection(a)
+		return []*html.Node{wrapNodes(atom.Del, ns)}, err
+	case valuesEqual(a, b):
+		return r.genValSection(b)
+	}
+	return isolate(r, func() ([]*html.Node, error) {
+		limit, leave := r.enterValue(b)
+		if limit != 0 {
+			return r.truncated(limit), nil
+		}
+		defer leave()
+		return r.genDiffNodes(a, b)
+	}, errorNodes), nil
+}
+
+// genReplaced renders a value that changed from a to b wholesale, as
+// old → new
+func (r *renderer) genReplaced(a, b reflect.Value) ([]*html.Node, error) {
+	oldNs, oldErr := r.genOldSection(a)
+	if oldErr != nil {
+		return nil, oldErr
+	}
+	newNs, newErr := r.genValSection(b)
+	if newErr != nil {
+		return nil, newErr
+	}
+	return []*html.Node{wrapNodes(atom.Del, oldNs), textNode(" → "), wrapNodes(atom.Ins, newNs)}, nil
+}
+
+// genDiffNodes does the work for genDiffSection, once we know a and b differ
+func (r *renderer) genDiffNodes(a, b reflect.Value) ([]*html.Node, error) {
+	if a.Type() != b.Type() {
+		return r.genReplaced(a, b)
+	}
+	switch b.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type() {
+			return r.genReplaced(a, b)
+		}
+		if key, trackable := visitKeyOf(b); trackable {
+			if enclosing, cycle := r.onStack[key]; cycle {
+				return r.seeAbove(enclosing), nil
+			}
+			r.onStack[key] = r.path
+			defer delete(r.onStack, key)
+		}
+		return r.genDiffNodes(a.Elem(), b.Elem())
+	}
+	// Values with renderings of their own can't be broken down any further
+	if _, ok := r.opts.valueRenderer(b); ok {
+		return r.genReplaced(a, b)
+	}
+	if _, ok := statusRendererOf(b); ok || eligibleStringer(b.Type()) {
+		return r.genReplaced(a, b)
+	}
+	switch b.Kind() {
+	case reflect.Struct:
+		return r.genDiffStructTable(a, b)
+	case reflect.Map:
+		if a.IsNil() || b.IsNil() {
+			return r.genReplaced(a, b)
+		}
+		return r.genDiffMapTable(a, b)
+	case reflect.Slice, reflect.Array:
+		if b.Kind() == reflect.Slice && (a.IsNil() || b.IsNil()) {
+			return r.genReplaced(a, b)
+		}
+		return r.genDiffSeqTable(a, b)
+	default:
+		return r.genReplaced(a, b)
+	}
+}
+
+// diffRow is a row of a diff table, which is collapsed along with its
+// neighbors if it's unchanged.
+type diffRow struct {
+	tr        *html.Node
+	unchanged bool
+}
+
+// newDiffRow returns a row with the class for change (if any)
+func newDiffRow(change string) diffRow {
+	tr := createElemAtom(atom.Tr)
+	if change != "" {
+		tr.Attr = append(tr.Attr, html.Attribute{Key: atom.Class.String(), Val: change})
+	}
+	return diffRow{tr: tr, unchanged: change == ""}
+}
+
+// addCell appends a cell holding ns to the row, wrapped in an element of
+// type wrap (if it's non-zero)
+func (dr *diffRow) addCell(ns []*html.Node, wrap atom.Atom) {
+	if wrap != 0 {
+		ns = []*html.Node{wrapNodes(wrap, ns)}
+	}
+	dr.tr.AppendChild(wrapNodes(atom.Td, ns))
+}
+
+// minCollapsedRows is the shortest run of unchanged rows that's collapsed
+// (collapsing a lone row wouldn't save any space)
+const minCollapsedRows = 2
+
+// appendDiffRows appends rows to table, collapsing each run of unchanged
+// rows into a single row (spanning nCols columns) that expands to show them.
+func appendDiffRows(table *html.Node, rows []diffRow, nCols int) {
+	for i := 0; i < len(rows); {
+		end := i
+		for end < len(rows) && rows[end].unchanged {
+			end++
+		}
+		if end-i < minCollapsedRows {
+			table.AppendChild(rows[i].tr)
+			i++
+			continue
+		}
+		inner := createElemClass(atom.Table, "sp-table sp-diff")
+		for _, dr := range rows[i:end] {
+			inner.AppendChild(dr.tr)
+		}
+		sectionTable(inner)
+		table.AppendChild(collapsedRow(strconv.Itoa(end-i)+" unchanged rows", inner, nCols))
+		i = end
+	}
+}
+
+// collapsedRow returns a row spanning nCols columns that holds n, collapsed
+// behind label
+func collapsedRow(label string, n *html.Node, nCols int) *html.Node {
+	tr := createElemAtom(atom.Tr)
+	td := createElemAtom(atom.Td)
+	setColspan(td, nCols)
+	tr.AppendChild(td)
+	td.AppendChild(collapsed(label, []*html.Node{n}))
+	return tr
+}
+
+// collapsed returns ns collapsed behind label
+func collapsed(label string, ns []*html.Node) *html.Node {
+	details := createElemAtom(atom.Details)
+	summary := createElemAtom(atom.Summary)
+	summary.AppendChild(textNode(label))
+	details.AppendChild(summary)
+	for _, n := range ns {
+		details.AppendChild(n)
+	}
+	return details
+}
+
+// genDiffStructTable mirrors genStructTable: fields that don't need tables
+// of their own go in a table at the top, followed by a section for each of
+// the others (collapsed if it's unchanged).
+func (r *renderer) genDiffStructTable(a, b reflect.Value) ([]*html.Node, error) {
+	fields, fieldsErr := structFields(b.Type())
+	if fieldsErr != nil {
+		return nil, fieldsErr
+	}
+	simpleRows := []diffRow{}
+	sections := []*html.Node{}
+	for _, f := range fields {
+		aOmitted, bOmitted := f.omitted(a), f.omitted(b)
+		if aOmitted && bOmitted {
+			continue
+		}
+		av, bv := reflect.Value{}, reflect.Value{}
+		if !aOmitted {
+			av = a.FieldByIndex(f.Index)
+		}
+		if !bOmitted {
+			bv = b.FieldByIndex(f.Index)
+		}
+		change := fieldChange(av, bv)
+		ascend := r.enterField(&f)
+		ns, diffErr := r.genDiffSection(av, bv)
+		if !r.needsTable(f.Type) {
+			ascend()
+			if diffErr != nil {
+				return nil, diffErr
+			}
+			dr := newDiffRow(change)
+			name := createElemClass(atom.Th, "sp-field-name")
+			name.AppendChild(textNode(f.displayName()))
+			setHelp(name, &f)
+			dr.tr.AppendChild(name)
+			dr.addCell(ns, 0)
+			simpleRows = append(simpleRows, dr)
+			continue
+		}
+		id := sectionID(r.path, 0)
+		ascend()
+		if diffErr != nil {
+			return nil, diffErr
+		}
+		if change == "" {
+			sections = append(sections, collapsed(f.displayName()+" (unchanged)", ns))
+			continue
+		}
+		section := createElemClass(atom.Div, "sp-section "+change)
+		section.Attr = append(section.Attr, html.Attribute{Key: atom.Id.String(), Val: id})
+		section.AppendChild(sectionHeading(&f, id))
+		for _, n := range ns {
+			section.AppendChild(n)
+		}
+		sections = append(sections, section)
+	}
+
+	out := make([]*html.Node, 0, len(sections)+1)
+	if len(simpleRows) > 0 {
+		table := createElemClass(atom.Table, "sp-table sp-diff sp-struct")
+		appendDiffRows(table, simpleRows, 2)
+		r.finishTable(table)
+		out = append(out, table)
+	}
+	return append(out, sections...), nil
+}
+
+// fieldChange returns the class for a field (or entry or element) that went
+// from a to b, where invalid values are missing
+func fieldChange(a, b reflect.Value) string {
+	switch {
+	case !a.IsValid():
+		return diffAdded
+	case !b.IsValid():
+		return diffRemoved
+	case valuesEqual(a, b):
+		return ""
+	default:
+		return diffChanged
+	}
+}
+
+// genDiffMapTable renders the entries of the maps a and b in the configured
+// order, highlighting the entries that were added, removed or changed.
+// Entries with keys that can't be looked up (those holding NaNs, which
+// aren't equal to themselves) are paired up by their position in that order.
+func (r *renderer) genDiffMapTable(a, b reflect.Value) ([]*html.Node, error) {
+	type entry struct{ k, av, bv reflect.Value }
+	entries := make([]entry, 0, b.Len())
+	// unequal holds the entries of b with keys unequal to themselves, and
+	// unequalA the values of such entries in a
+	unequal, unequalA := []entry{}, []entry{}
+	for k, bv := range r.opts.mapEntries(b) {
+		if !k.Equal(k) {
+			unequal = append(unequal, entry{k: k, bv: bv})
+			continue
+		}
+		entries = append(entries, entry{k: k, av: a.MapIndex(k), bv: bv})
+	}
+	for k, av := range r.opts.mapEntries(a) {
+		if !k.Equal(k) {
+			unequalA = append(unequalA, entry{k: k, av: av})
+			continue
+		}
+		if !b.MapIndex(k).IsValid() {
+			entries = append(entries, entry{k: k, av: av})
+		}
+	}
+	for i := range max(len(unequal), len(unequalA)) {
+		switch {
+		case i >= len(unequal):
+			entries = append(entries, unequalA[i])
+		case i < len(unequalA):
+			unequal[i].av = unequalA[i].av
+			fallthrough
+		default:
+			entries = append(entries, unequal[i])
+		}
+	}
+	if keyCmp := r.opts.keyCompare(b); keyCmp != nil {
+		slices.SortStableFunc(entries, func(x, y entry) int { return keyCmp(x.k, y.k) })
+	}
+
+	set := isSet(b.Type())
+	table := createElemClass(atom.Table, "sp-table sp-diff sp-map")
+	header := createElemAtom(atom.Tr)
+	header.AppendChild(wrapNodes(atom.Th, []*html.Node{textNode("key")}))
+	nCols := 1
+	if !set {
+		header.AppendChild(wrapNodes(atom.Th, []*html.Node{textNode("value")}))
+		nCols++
+	}
+	table.AppendChild(header)
+
+	rows := make([]diffRow, 0, len(entries))
+	for _, e := range entries {
+		if l := r.exhausted(); l != 0 {
+			rows = append(rows, diffRow{tr: r.truncatedRow(l, nCols)})
+			break
+		}
+		change := fieldChange(e.av, e.bv)
+		dr := newDiffRow(change)
+		exitKey := r.inKey()
+		var keyNs []*html.Node
+		var keyErr error
+		if change == diffRemoved {
+			keyNs, keyErr = r.genOldSection(e.k)
+		} else {
+			keyNs, keyErr = r.genValSection(e.k)
+		}
+		exitKey()
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		dr.addCell(keyNs, changeWrapper(change))
+		if !set {
+			ascend := r.descend(keyStep(e.k))
+			valNs, valErr := r.genDiffSection(e.av, e.bv)
+			ascend()
+			if valErr != nil {
+				return nil, valErr
+			}
+			dr.addCell(valNs, 0)
+		}
+		rows = append(rows, dr)
+	}
+	appendDiffRows(table, rows, nCols)
+	r.finishTable(table)
+	return []*html.Node{table}, nil
+}
+
+// changeWrapper returns the element to wrap the key or index of an entry
+// with change in
+func changeWrapper(change string) atom.Atom {
+	switch change {
+	case diffAdded:
+		return atom.Ins
+	case diffRemoved:
+		return atom.Del
+	default:
+		return 0
+	}
+}
+
+// seqOp is one step of the edit script turning one sequence into another
+type seqOp struct {
+	// ai and bi are the indexes of the elements of the old and new
+	// sequences (-1 for elements that were added or removed)
+	ai, bi int
+}
+
+// seqEditScript matches up the elements of a and b, returning the steps
+// turning a into b. Elements are matched up by the longest common
+// subsequence of equal elements; unmatched elements between matches are
+// paired up as changes where possible. Sequences too long for that are
+// compared index by index.
+func seqEditScript(a, b reflect.Value) []seqOp {
+	n, m := a.Len(), b.Len()
+	ops := make([]seqOp, 0, max(n, m))
+	if n*m > maxLCSCells {
+		for i := range max(n, m) {
+			op := seqOp{ai: -1, bi: -1}
+			if i < n {
+				op.ai = i
+			}
+			if i < m {
+				op.bi = i
+			}
+			ops = append(ops, op)
+		}
+		return ops
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if valuesEqual(a.Index(i), b.Index(j)) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	// unmatched elements are gathered up until the next match, so they
+	// can be paired up
+	removed, added := []int{}, []int{}
+	flush := func() {
+		for k := range max(len(removed), len(added)) {
+			op := seqOp{ai: -1, bi: -1}
+			if k < len(removed) {
+				op.ai = removed[k]
+			}
+			if k < len(added) {
+				op.bi = added[k]
+			}
+			ops = append(ops, op)
+		}
+		removed, added = removed[:0], added[:0]
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && valuesEqual(a.Index(i), b.Index(j)):
+			flush()
+			ops = append(ops, seqOp{ai: i, bi: j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, j)
+			j++
+		default:
+			removed = append(removed, i)
+			i++
+		}
+	}
+	flush()
+	return ops
+}
+
+// genDiffSeqTable renders the elements of the slices or arrays a and b, one
+// per row, highlighting the elements that were added, removed or changed.
+func (r *renderer) genDiffSeqTable(a, b reflect.Value) ([]*html.Node, error) {
+	table := createElemClass(atom.Table, "sp-table sp-diff sp-slice")
+	header := createElemAtom(atom.Tr)
+	header.AppendChild(wrapNodes(atom.Th, []*html.Node{textNode("index")}))
+	header.AppendChild(wrapNodes(atom.Th, []*html.Node{textNode("value")}))
+	table.AppendChild(header)
+
+	ops := seqEditScript(a, b)
+	rows := make([]diffRow, 0, len(ops))
+	for _, op := range ops {
+		if l := r.exhausted(); l != 0 {
+			rows = append(rows, diffRow{tr: r.truncatedRow(l, 2)})
+			break
+		}
+		av, bv := reflect.Value{}, reflect.Value{}
+		idx := op.bi
+		if op.ai >= 0 {
+			av = a.Index(op.ai)
+		}
+		if op.bi >= 0 {
+			bv = b.Index(op.bi)
+		} else {
+			idx = op.ai
+		}
+		change := fieldChange(av, bv)
+		dr := newDiffRow(change)
+		dr.addCell([]*html.Node{textNode(strconv.Itoa(idx))}, changeWrapper(change))
+		ascend := r.descend(indexStep(idx))
+		valNs, valErr := r.genDiffSection(av, bv)
+		ascend()
+		if valErr != nil {
+			return nil, valErr
+		}
+		dr.addCell(valNs, 0)
+		rows = append(rows, dr)
+	}
+	appendDiffRows(table, rows, 2)
+	r.finishTable(table)
+	return []*html.Node{table}, nil
+}
+
+// diffQueryParam requests a diff view of the page, against the snapshot in
+// the history at the time it holds (in RFC 3339 format), or against the one
+// the viewer last loaded if it's diffSinceSeen.
+const diffQueryParam = "diff"
+
+// diffSinceSeen is the value of diffQueryParam for diffs against the
+// snapshot the viewer last loaded.
+const diffSinceSeen = "seen"
+
+// seenCookie holds the time of the snapshot the viewer last loaded (as an
+// HTML page), for diffSinceSeen.
+const seenCookie = "statuspage-seen"
+
+// setSeenCookie records that the viewer loaded the snapshot taken at t from
+// the Status served at basePath
+func setSeenCookie(w http.ResponseWriter, basePath string, subPages bool, t time.Time) {
+	path := basePath + "/"
+	if !subPages {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     seenCookie,
+		Value:    t.UTC().Format(time.RFC3339Nano),
+		Path:     path,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// diffBase returns the snapshot from the history to diff the request
+// against, along with the status code to respond with if there isn't one.
+// Page loads aren't recorded in the history (only the sampler's snapshots
+// are), so diffs against the viewer's last visit are against the sampled
+// snapshot in effect when the one they saw was loaded.
+func (s *Status[T]) diffBase(r *http.Request, since string) (*snapshot[T], int, error) {
+	if since == diffSinceSeen {
+		c, cookieErr := r.Cookie(seenCookie)
+		if cookieErr != nil {
+			return nil, http.StatusNotFound, fmt.Errorf("no record of a previous visit to diff against")
+		}
+		since = c.Value
+	}
+	return s.historySnapshot(since)
+}
+
+// serveDiff serves the diff view of the page at target (in snap) against
+// the same value in base.
+func serveDiff(w http.ResponseWriter, rn *renderer, format outputFormat, base, target reflect.Value) {
+	if format != formatHTML {
+		http.Error(w, "diffs are only available as HTML", http.StatusNotAcceptable)
+		return
+	}
+	root, main := rn.htmlDocument()
+	since := createElemClass(atom.P, "sp-notes")
+	since.AppendChild(textNode("Changes since "))
+	t := createElemAtom(atom.Time)
+	t.Attr = append(t.Attr, html.Attribute{Key: atom.Datetime.String(), Val: rn.diffSince.Format(time.RFC3339Nano)})
+	t.AppendChild(textNode(rn.diffSince.Format(snapshotTimeLayout)))
+	since.AppendChild(t)
+	since.AppendChild(textNode(" ("))
+	q := maps.Clone(rn.query)
+	q.Del(diffQueryParam)
+	hide := createElemAtom(atom.A)
+	hide.Attr = append(hide.Attr, html.Attribute{Key: atom.Href.String(), Val: "?" + q.Encode()})
+	hide.AppendChild(textNode("hide changes"))
+	since.AppendChild(hide)
+	since.AppendChild(textNode(")"))
+	main.AppendChild(since)
+
+	diffNs, diffErr := rn.genDiffSection(base, target)
+	if diffErr != nil {
+		http.Error(w, fmt.Sprintf("failed to generate diff for struct of type %s: %s", target.Type(), diffErr), 500)
+		return
+	}
+	if errs := rn.errorCountText(); errs != "" {
+		main.AppendChild(wrapNodes(atom.P, []*html.Node{wrapNodes(atom.Strong, []*html.Node{textNode(errs)})}))
+	}
+	for _, n := range diffNs {
+		main.AppendChild(createElemAtom(atom.Hr))
+		main.AppendChild(n)
+	}
+	setTruncatedHeader(w, rn)
+	setErrorsHeader(w, rn)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if renderErr := html.Render(w, root); renderErr != nil {
+		http.Error(w, fmt.Sprintf("failed to render response for struct of type %s: %s", target.Type(), renderErr), 500)
+	}
+}
@@ -0,0 +1,264 @@
+package statuspage
+
+import (
+	"iter"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestSeqEditScript(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []int
+		want []seqOp
+	}{
+		{"equal", []int{1, 2}, []int{1, 2}, []seqOp{{0, 0}, {1, 1}}},
+		{"appended", []int{1}, []int{1, 2}, []seqOp{{0, 0}, {-1, 1}}},
+		{"removed from the front", []int{1, 2, 3}, []int{2, 3}, []seqOp{{0, -1}, {1, 0}, {2, 1}}},
+		{"changed in the middle", []int{1, 2, 3}, []int{1, 5, 3}, []seqOp{{0, 0}, {1, 1}, {2, 2}}},
+		{"inserted", []int{1, 3}, []int{1, 2, 3}, []seqOp{{0, 0}, {-1, 1}, {1, 2}}},
+		{"more removed than added", []int{1, 2, 3, 4}, []int{1, 9, 4}, []seqOp{{0, 0}, {1, 1}, {2, -1}, {3, 2}}},
+		{"empty to full", nil, []int{1, 2}, []seqOp{{-1, 0}, {-1, 1}}},
+		{"full to empty", []int{1, 2}, nil, []seqOp{{0, -1}, {1, -1}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := seqEditScript(reflect.ValueOf(tc.a), reflect.ValueOf(tc.b))
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("seqEditScript(%v, %v) = %v; want %v", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSeqEditScriptLong(t *testing.T) {
+	// too long to match up, so they're compared index by index
+	a, b := make([]int, 2000), make([]int, 1000)
+	ops := seqEditScript(reflect.ValueOf(a), reflect.ValueOf(b))
+	if len(ops) != len(a) {
+		t.Fatalf("got %d ops; want %d", len(ops), len(a))
+	}
+	if ops[999] != (seqOp{999, 999}) || ops[1000] != (seqOp{1000, -1}) {
+		t.Errorf("ops[999:1001] = %v; want [{999 999} {1000 -1}]", ops[999:1001])
+	}
+}
+
+func seqOf(vals ...int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, v := range vals {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	f := func() {}
+	for _, tc := range []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{"equal ints", 1, 1, true},
+		{"different ints", 1, 2, false},
+		{"different types", 1, int64(1), false},
+		{"equal slices", []int{1, 2}, []int{1, 2}, true},
+		{"same func", f, f, true},
+		{"iterators yielding the same", seqOf(1, 2), seqOf(1, 2), true},
+		{"iterators yielding different elements", seqOf(1, 2), seqOf(1, 3), false},
+		{"iterators yielding more", seqOf(1, 2), seqOf(1, 2, 3), false},
+		{"nil and non-nil iterators", iter.Seq[int](nil), seqOf(), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := valuesEqual(reflect.ValueOf(tc.a), reflect.ValueOf(tc.b)); got != tc.want {
+				t.Errorf("valuesEqual(%v, %v) = %t; want %t", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+type diffElem struct {
+	N    int
+	Tags []string
+}
+
+type diffVal struct {
+	Name  string
+	Count int
+	Items []diffElem
+	M     map[string]int
+	NaNs  map[float64]int
+	Ints  []int
+}
+
+// renderDiff renders the diff between a and b, parsed back into a tree
+func renderDiff(t *testing.T, a, b diffVal) *html.Node {
+	t.Helper()
+	ns, diffErr := GenDiffHTMLNodes(a, b)
+	if diffErr != nil {
+		t.Fatalf("GenDiffHTMLNodes: %s", diffErr)
+	}
+	div := createElemAtom(atom.Div)
+	for _, n := range ns {
+		div.AppendChild(n)
+	}
+	return div
+}
+
+// rowsWithClass returns the text of the rows with the class class
+func rowsWithClass(n *html.Node, class string) []string {
+	out := []string{}
+	for d := range n.Descendants() {
+		if d.Data == "tr" && hasClass(d, class) {
+			out = append(out, textContent(d))
+		}
+	}
+	return out
+}
+
+func textContent(n *html.Node) string {
+	b := strings.Builder{}
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode {
+			b.WriteString(d.Data)
+		}
+	}
+	return b.String()
+}
+
+func TestGenDiffHTMLNodes(t *testing.T) {
+	a := diffVal{
+		Name:  "a",
+		Count: 1,
+		Items: []diffElem{{N: 1, Tags: []string{"x"}}, {N: 2, Tags: []string{"y"}}},
+		M:     map[string]int{"gone": 1, "same": 2, "changed": 3},
+		NaNs:  map[float64]int{math.NaN(): 1, 1: 1},
+		Ints:  []int{1, 2, 3, 4, 5, 6},
+	}
+	b := diffVal{
+		Name:  "a",
+		Count: 2,
+		Items: []diffElem{{N: 2, Tags: []string{"y"}}},
+		M:     map[string]int{"same": 2, "changed": 4, "new": 5},
+		NaNs:  map[float64]int{math.NaN(): 2, 1: 1},
+		Ints:  []int{1, 2, 3, 4, 5, 7},
+	}
+	doc := renderDiff(t, a, b)
+
+	if got, want := rowsWithClass(doc, diffChanged), []string{
+		"Count1 (0x1) → 2 (0x2)",
+		"changed3 (0x3) → 4 (0x4)",
+		"NaN1 (0x1) → 2 (0x2)",
+		"56 (0x6) → 7 (0x7)",
+	}; !slices.Equal(got, want) {
+		t.Errorf("changed rows = %q; want %q", got, want)
+	}
+	if got := rowsWithClass(doc, diffAdded); !slices.Equal(got, []string{"new5 (0x5)"}) {
+		t.Errorf("added rows = %q; want [\"new5 (0x5)\"]", got)
+	}
+	removed := rowsWithClass(doc, diffRemoved)
+	if len(removed) != 2 || !strings.HasPrefix(removed[0], "0N1 (0x1)") || removed[1] != "gone1 (0x1)" {
+		t.Errorf("removed rows = %q; want Items[0] and gone", removed)
+	}
+	collapsedRows := []string{}
+	for d := range doc.Descendants() {
+		if d.Data == "summary" {
+			collapsedRows = append(collapsedRows, textContent(d))
+		}
+	}
+	if !slices.Contains(collapsedRows, "5 unchanged rows") {
+		t.Errorf("collapsed sections = %q; want the 5 unchanged rows of Ints", collapsedRows)
+	}
+
+	// the removed element's sections are beneath its own path, so the links
+	// to them are to the element now at its index (rather than clashing
+	// with its parent's)
+	ids := map[string]bool{}
+	links := []string{}
+	for d := range doc.Descendants() {
+		if id, ok := attr(d, "id"); ok {
+			if ids[id] {
+				t.Errorf("duplicate id %q", id)
+			}
+			ids[id] = true
+		}
+		if href, ok := attr(d, "href"); ok {
+			links = append(links, strings.TrimPrefix(href, "#"))
+		}
+	}
+	for _, l := range links {
+		if !ids[l] {
+			t.Errorf("link to #%s, which isn't in the diff", l)
+		}
+	}
+}
+
+func TestGenDiffHTMLNodesUnchanged(t *testing.T) {
+	v := diffVal{Name: "a", Items: []diffElem{{N: 1}}, M: map[string]int{"a": 1}}
+	doc := renderDiff(t, v, v)
+	for _, class := range []string{diffAdded, diffRemoved, diffChanged} {
+		if rows := rowsWithClass(doc, class); len(rows) != 0 {
+			t.Errorf("%s rows in the diff of a value with itself: %q", class, rows)
+		}
+	}
+}
+
+type historyVal struct{ N int }
+
+// waitForSnapshots waits for s's history to hold n snapshots
+func waitForSnapshots[T any](t *testing.T, s *Status[T], n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.history.times()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("history has %d snapshots; want %d", len(s.history.times()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDiffSinceSeen(t *testing.T) {
+	calls := 0
+	s := New("Test", func() historyVal { calls++; return historyVal{N: calls} }, WithHistory(time.Hour, 3))
+	defer s.Close()
+	waitForSnapshots(t, s, 1)
+	sampled := s.history.times()
+
+	var cookie *http.Cookie
+	for range 6 {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		cookie = rec.Result().Cookies()[0]
+	}
+	// page loads don't push the sampled snapshots out of the history
+	if got := s.history.times(); !slices.Equal(got, sampled) {
+		t.Errorf("history after page loads = %v; want %v", got, sampled)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?diff=seen", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /?diff=seen: status %d: %s", rec.Code, rec.Body)
+	}
+	// the diff is against the sample in effect when the last page loaded
+	if body := rec.Body.String(); !strings.Contains(body, "<del><span class=\"sp-num\">1 (0x1)</span></del>") {
+		t.Errorf("diff isn't against the sampled snapshot:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?diff=seen", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /?diff=seen without a cookie: status %d; want 404", rec.Code)
+	}
+}
@@ -0,0 +1,237 @@
+package statuspage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step from a value to one of its children: a struct field, a
+// map (or iter.Seq2) key, or an offset within an array, slice or iter.Seq.
+type pathStep struct {
+	// seg is the URL-escaped path segment for field and key steps
+	seg string
+	// label is the human-readable form of the step (for breadcrumbs)
+	label string
+	// index is the offset for index steps
+	index   int
+	isIndex bool
+}
+
+// fieldPath identifies a value nested within the value returned by a
+// Status's callback.
+//
+// Its URL form has one segment per field or key, with offsets appended to the
+// preceding segment in square brackets, e.g. /Backends/us-east/Conns[3].
+type fieldPath []pathStep
+
+func fieldStep(name string) pathStep {
+	return pathStep{seg: url.PathEscape(name), label: name}
+}
+
+func indexStep(i int) pathStep {
+	return pathStep{index: i, isIndex: true, label: "[" + strconv.Itoa(i) + "]"}
+}
+
+func keyStep(k reflect.Value) pathStep {
+	label, ok := jsonKeyString(k)
+	if !ok {
+		label = metricLabelValue(k)
+	}
+	return pathStep{seg: encodeMapKey(k), label: label}
+}
+
+// keyFieldsStep is the step from a map entry to the fields of its (struct)
+// key, for those that are rendered as tables. Its segment can't be that of a
+// field or a key, so their anchors can't clash with those of the entry's
+// value.
+var keyFieldsStep = pathStep{seg: hashedKeyPrefix + "key", label: "key"}
+
+// hashedKeyPrefix marks a map-key path segment as a hash of the key rather
+// than its string form. (a literal leading ~ in a key is escaped)
+const hashedKeyPrefix = "~"
+
+// encodeMapKey returns a stable, URL-safe path segment for the map key k.
+// Keys with a natural string form (strings, numbers, bools, Stringers) use
+// it; anything else (e.g. struct keys, or the empty string, which would
+// produce an empty segment) uses a hash of its Go-syntax representation.
+func encodeMapKey(k reflect.Value) string {
+	if ks, ok := jsonKeyString(k); ok && ks != "" {
+		esc := url.PathEscape(ks)
+		// Brackets delimit offsets, so they can't appear literally in keys
+		esc = strings.NewReplacer("[", "%5B", "]", "%5D").Replace(esc)
+		if strings.HasPrefix(esc, hashedKeyPrefix) {
+			esc = "%7E" + esc[len(hashedKeyPrefix):]
+		}
+		return esc
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%#v", k.Interface())
+	return hashedKeyPrefix + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// String returns the URL form of the path (empty for the root)
+func (p fieldPath) String() string {
+	b := strings.Builder{}
+	for _, st := range p {
+		if st.isIndex {
+			if b.Len() == 0 {
+				b.WriteByte('/')
+			}
+			b.WriteString(st.label)
+			continue
+		}
+		b.WriteByte('/')
+		b.WriteString(st.seg)
+	}
+	return b.String()
+}
+
+// label returns the human-readable form of p (without the page title)
+func (p fieldPath) label() string {
+	labels := make([]string, 0, len(p))
+	for _, st := range p {
+		labels = append(labels, st.label)
+	}
+	return strings.Join(labels, " › ")
+}
+
+// child returns a copy of p with st appended (p is never modified, so
+// siblings can't clobber each other's paths)
+func (p fieldPath) child(st pathStep) fieldPath {
+	return append(p[:len(p):len(p)], st)
+}
+
+// rawStep is a step parsed out of a URL path that hasn't been resolved
+// against a value yet.
+type rawStep struct {
+	seg     string
+	index   int
+	isIndex bool
+}
+
+var errBadPath = errors.New("malformed path")
+
+// parseFieldPath splits an (escaped) URL path into steps. Segments are
+// left escaped, so they can be compared against encodeMapKey's output.
+func parseFieldPath(escaped string) ([]rawStep, error) {
+	out := []rawStep{}
+	for seg := range strings.SplitSeq(strings.Trim(escaped, "/"), "/") {
+		name, idxs, _ := strings.Cut(seg, "[")
+		if name != "" {
+			out = append(out, rawStep{seg: name})
+		}
+		if idxs == "" {
+			continue
+		}
+		for idx := range strings.SplitSeq(strings.TrimSuffix(idxs, "]"), "][") {
+			i, convErr := strconv.Atoi(idx)
+			if convErr != nil || i < 0 {
+				return nil, fmt.Errorf("%w: bad offset %q in segment %q", errBadPath, idx, seg)
+			}
+			out = append(out, rawStep{index: i, isIndex: true})
+		}
+	}
+	return out, nil
+}
+
+var errPathNotFound = errors.New("path not found")
+
+// resolvePath follows steps from v, returning the value they lead to along
+// with the canonical fieldPath for it. Pointers and interfaces are followed
+// transparently.
+func resolvePath(v reflect.Value, steps []rawStep) (reflect.Value, fieldPath, error) {
+	path := fieldPath{}
+	for _, st := range steps {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, nil, fmt.Errorf("%w: nil %s at %s", errPathNotFound, v.Type(), path)
+			}
+			v = v.Elem()
+		}
+		next, pst, ok := resolveStep(v, st)
+		if !ok {
+			return reflect.Value{}, nil, fmt.Errorf("%w: no child %q of %s at %q", errPathNotFound, st.seg, v.Type(), path)
+		}
+		v = next
+		path = path.child(pst)
+	}
+	return v, path, nil
+}
+
+func resolveStep(v reflect.Value, st rawStep) (reflect.Value, pathStep, bool) {
+	if st.isIndex {
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+			if st.index >= v.Len() {
+				return reflect.Value{}, pathStep{}, false
+			}
+			return v.Index(st.index), indexStep(st.index), true
+		case reflect.Func:
+			if v.IsNil() || !v.Type().CanSeq() || v.Type().CanSeq2() {
+				return reflect.Value{}, pathStep{}, false
+			}
+			offset := 0
+			for ev := range v.Seq() {
+				if offset == st.index {
+					return ev, indexStep(st.index), true
+				}
+				offset++
+			}
+		}
+		return reflect.Value{}, pathStep{}, false
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		name, unescErr := url.PathUnescape(st.seg)
+		if unescErr != nil {
+			return reflect.Value{}, pathStep{}, false
+		}
+		fields, fieldsErr := visibleFields(v)
+		if fieldsErr != nil {
+			return reflect.Value{}, pathStep{}, false
+		}
+		for _, f := range fields {
+			if f.Name == name {
+				return v.FieldByIndex(f.Index), f.step(), true
+			}
+		}
+	case reflect.Map:
+		for ik, iv := range v.Seq2() {
+			if mapKeyMatches(ik, st.seg) {
+				return iv, keyStep(ik), true
+			}
+		}
+	case reflect.Func:
+		if v.IsNil() || !v.Type().CanSeq2() {
+			return reflect.Value{}, pathStep{}, false
+		}
+		for ik, iv := range v.Seq2() {
+			if mapKeyMatches(ik, st.seg) {
+				return iv, keyStep(ik), true
+			}
+		}
+	}
+	return reflect.Value{}, pathStep{}, false
+}
+
+// mapKeyMatches reports whether the escaped path segment seg identifies the
+// map key k. Clients may escape segments differently than encodeMapKey does,
+// so keys with a natural string form are compared unescaped.
+func mapKeyMatches(k reflect.Value, seg string) bool {
+	if strings.HasPrefix(seg, hashedKeyPrefix) {
+		return encodeMapKey(k) == seg
+	}
+	ks, ok := jsonKeyString(k)
+	if !ok || ks == "" {
+		return false
+	}
+	want, unescErr := url.PathUnescape(seg)
+	return unescErr == nil && ks == want
+}
@@ -0,0 +1,184 @@
+package statuspage
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pathKey struct {
+	Region string
+	Zone   int
+}
+
+func TestEncodeMapKey(t *testing.T) {
+	keys := []any{
+		"us-east", "us east/1", "a[1]", "~tilde", "%41", "", "ünïcode",
+		42, -1, uint8(7), true, 1.5, time.Second,
+		pathKey{"us", 1}, pathKey{"us", 2}, [2]int{1, 2},
+	}
+	segs := map[string]any{}
+	for _, k := range keys {
+		kv := reflect.ValueOf(k)
+		seg := encodeMapKey(kv)
+		if prev, dup := segs[seg]; dup {
+			t.Errorf("%#v and %#v are both encoded as %q", prev, k, seg)
+		}
+		segs[seg] = k
+		if seg == "" || strings.ContainsAny(seg, "/[]") {
+			t.Errorf("encodeMapKey(%#v) = %q, which isn't a single path segment", k, seg)
+		}
+		if again := encodeMapKey(kv); again != seg {
+			t.Errorf("encodeMapKey(%#v) isn't stable: %q then %q", k, seg, again)
+		}
+
+		// the segment survives a trip through a URL path, and picks out
+		// its key (and only its key)
+		steps, parseErr := parseFieldPath(fieldPath{keyStep(kv)}.String())
+		if parseErr != nil || len(steps) != 1 || steps[0].seg != seg {
+			t.Errorf("parseFieldPath of the path for %#v = %+v, %v; want [{seg: %q}]", k, steps, parseErr, seg)
+		}
+		for _, other := range keys {
+			ov := reflect.ValueOf(other)
+			if ov.Type() != kv.Type() {
+				continue
+			}
+			if got, want := mapKeyMatches(ov, seg), ov.Equal(kv); got != want {
+				t.Errorf("mapKeyMatches(%#v, %q) = %t; want %t", other, seg, got, want)
+			}
+		}
+	}
+
+	// clients may escape segments differently
+	if !mapKeyMatches(reflect.ValueOf("us east/1"), "us%20east%2f1") {
+		t.Error("a differently-escaped segment doesn't match its key")
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	for _, tc := range []struct {
+		path    string
+		want    []rawStep
+		wantErr bool
+	}{
+		{path: "", want: []rawStep{}},
+		{path: "/", want: []rawStep{}},
+		{path: "/Backends/us-east/Conns[3]", want: []rawStep{{seg: "Backends"}, {seg: "us-east"}, {seg: "Conns"}, {index: 3, isIndex: true}}},
+		{path: "/Grid[1][2]/", want: []rawStep{{seg: "Grid"}, {index: 1, isIndex: true}, {index: 2, isIndex: true}}},
+		{path: "/[0]", want: []rawStep{{index: 0, isIndex: true}}},
+		{path: "/A%2FB", want: []rawStep{{seg: "A%2FB"}}},
+		{path: "/Conns[x]", wantErr: true},
+		{path: "/Conns[-1]", wantErr: true},
+		{path: "/Conns[]", wantErr: true},
+	} {
+		got, err := parseFieldPath(tc.path)
+		if tc.wantErr {
+			if !errors.Is(err, errBadPath) {
+				t.Errorf("parseFieldPath(%q) = %+v, %v; want errBadPath", tc.path, got, err)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("parseFieldPath(%q) = %+v, %v; want %+v", tc.path, got, err, tc.want)
+		}
+	}
+}
+
+type pathBackend struct {
+	Conns []int
+	Peers map[pathKey]string
+}
+
+type pathVal struct {
+	Backends map[string]*pathBackend
+	Grid     [][]int
+	Skipped  int `statuspage:"-"`
+}
+
+func TestResolvePath(t *testing.T) {
+	v := reflect.ValueOf(pathVal{
+		Backends: map[string]*pathBackend{"us east": {Conns: []int{5, 6}, Peers: map[pathKey]string{{"eu", 1}: "x"}}},
+		Grid:     [][]int{{1}, {2, 3}},
+	})
+	peerSeg := encodeMapKey(reflect.ValueOf(pathKey{"eu", 1}))
+	for _, tc := range []struct {
+		path     string
+		want     any
+		wantPath string
+	}{
+		{"/Backends/us%20east/Conns[1]", 6, "/Backends/us%20east/Conns[1]"},
+		{"/Backends/us east/Conns[0]", 5, "/Backends/us%20east/Conns[0]"},
+		{"/Backends/us%20east/Peers/" + peerSeg, "x", "/Backends/us%20east/Peers/" + peerSeg},
+		{"/Grid[1][1]", 3, "/Grid[1][1]"},
+	} {
+		steps, parseErr := parseFieldPath(tc.path)
+		if parseErr != nil {
+			t.Fatalf("parseFieldPath(%q): %s", tc.path, parseErr)
+		}
+		got, p, err := resolvePath(v, steps)
+		if err != nil {
+			t.Errorf("resolvePath(%q): %s", tc.path, err)
+			continue
+		}
+		if got.Interface() != tc.want || p.String() != tc.wantPath {
+			t.Errorf("resolvePath(%q) = %v at %q; want %v at %q", tc.path, got, p, tc.want, tc.wantPath)
+		}
+	}
+
+	for _, path := range []string{"/Nope", "/Skipped", "/Backends/us-west", "/Grid[2]", "/Grid[0][1]", "/Backends/us%20east/Peers/~nope"} {
+		steps, parseErr := parseFieldPath(path)
+		if parseErr != nil {
+			t.Fatalf("parseFieldPath(%q): %s", path, parseErr)
+		}
+		if _, _, err := resolvePath(v, steps); !errors.Is(err, errPathNotFound) {
+			t.Errorf("resolvePath(%q): %v; want errPathNotFound", path, err)
+		}
+	}
+}
+
+func TestSubPages(t *testing.T) {
+	v := pathVal{Backends: map[string]*pathBackend{"us east/1": {Conns: []int{5, 6, 7, 8}}}}
+	mux := http.NewServeMux()
+	mux.Handle("/status/", New("Test", func() pathVal { return v }, WithMaxInlineDepth(1)))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/"+path, nil))
+		return rec
+	}
+
+	// tables nested too deeply are links to their sub-pages
+	rec := get("")
+	if !strings.Contains(rec.Body.String(), `<a class="sp-more" href="/status/Backends" id="sp/Backends">`) {
+		t.Errorf("no link to the Backends sub-page:\n%s", rec.Body)
+	}
+
+	rec = get("Backends/us%20east%2F1/Conns[3]")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<nav class="sp-breadcrumbs" aria-label="Breadcrumbs"><a href="/status/">Test</a> › <a href="/status/Backends">Backends</a> › ` +
+			`<a href="/status/Backends/us%20east%2F1">us east/1</a> › <a href="/status/Backends/us%20east%2F1/Conns">Conns</a> › ` +
+			`<span aria-current="page">[3]</span></nav>`,
+		`<span class="sp-num">8 (0x8)</span>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("sub-page doesn't contain %q:\n%s", want, body)
+		}
+	}
+
+	for _, path := range []string{"Nope", "Backends/us-west", "Backends/us%20east%2F1/Conns[4]"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET /status/%s: status %d; want 404", path, rec.Code)
+		}
+	}
+	if rec := get("Backends/us%20east%2F1/Conns[x]"); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET of a malformed path: status %d; want 400", rec.Code)
+	}
+}
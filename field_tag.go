@@ -0,0 +1,414 @@
+package statuspage
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// statusPageTagKey is the struct tag controlling how a field is rendered, e.g.
+//
+//	Conns uint64 `statuspage:"name=Open Conns,format=bytes,omitempty,help=Bytes in flight"`
+//
+// Recognized directives:
+//   - name=<text>: the name displayed for the field (defaults to the field's name)
+//   - format=bytes|percent|hex|dec: how numbers within the field are displayed
+//   - omitempty: hide the field when it holds its zero value
+//   - help=<text>: tooltip text for the field's name (may not contain commas)
+//   - trend: keep a history of the field's value, and render it with a
+//     sparkline (see WithTrends). The field must be a number or a map of
+//     numbers, in which case each entry gets its own trend.
+//   - health: the field is a health check (see HealthChecker), which fails
+//     if it's false (for bools) or non-nil (for errors). Maps of bools or
+//     errors check each entry.
+//   - unhealthy<op><value>: the field (a number, or a map of numbers) fails
+//     a health check when it compares to value with op (one of >=, >, <=
+//     or <), e.g. unhealthy>=0.95. Values for time.Durations are durations
+//     (e.g. unhealthy>1.5s).
+//   - warn<op><value>, crit<op><value>: highlight the field's value (or,
+//     for maps, each value) when it compares to value with op, as with
+//     unhealthy, e.g. warn>=0.8,crit>=0.95. Tables flag the rows holding
+//     values past their crit thresholds in their captions.
+//   - summary: show the field's value in the summary cards at the top of the
+//     page (and the _summary sub-path; see Status), wherever it's nested.
+//
+// A value of "-" hides the field entirely. Unknown directives are an error.
+//
+// The bytes format renders sizes with binary (KiB, MiB, ...) units, percent
+// renders fractions held by floats (0.25 is 25.0%) and whole percentages
+// held by integers (50 is 50%), hex renders integers in hexadecimal only and
+// dec renders integers in decimal only (rather than both). Formats only apply
+// to numbers; other values are rendered as usual.
+const statusPageTagKey = "statuspage"
+
+// valueFormat is the number format selected with a format= directive
+type valueFormat int
+
+const (
+	valueFormatDefault valueFormat = iota
+	valueFormatBytes
+	valueFormatPercent
+	valueFormatHex
+	valueFormatDec
+)
+
+var valueFormatNames = map[string]valueFormat{
+	"bytes":   valueFormatBytes,
+	"percent": valueFormatPercent,
+	"hex":     valueFormatHex,
+	"dec":     valueFormatDec,
+}
+
+// fieldTag holds the parsed directives from a statuspage struct tag
+type fieldTag struct {
+	skip      bool
+	name      string
+	format    valueFormat
+	omitEmpty bool
+	help      string
+	trend     bool
+	// health marks a bool or error as a health check
+	health bool
+	// unhealthy is the threshold past which a number fails its health
+	// check (nil if it isn't one)
+	unhealthy *threshold
+	// thresholds are the warn and crit thresholds numbers within the
+	// field are highlighted past
+	thresholds levelThresholds
+	// summary lists the field in the summary at the top of the page
+	summary bool
+}
+
+// healthChecked reports whether the field holds health checks
+func (ft *fieldTag) healthChecked() bool { return ft.health || ft.unhealthy != nil }
+
+func parseFieldTag(f reflect.StructField) (fieldTag, error) {
+	raw, ok := f.Tag.Lookup(statusPageTagKey)
+	if !ok || raw == "" {
+		return fieldTag{}, nil
+	}
+	if raw == "-" {
+		return fieldTag{skip: true}, nil
+	}
+	out := fieldTag{}
+	for directive := range strings.SplitSeq(raw, ",") {
+		if name, cmp, isCmp := cutThreshold(directive); isCmp {
+			dst := map[string]**threshold{
+				"unhealthy": &out.unhealthy,
+				"warn":      &out.thresholds.warn,
+				"crit":      &out.thresholds.crit,
+			}[name]
+			if dst == nil {
+				return fieldTag{}, fmt.Errorf("field %q: unknown directive %q in %s tag", f.Name, directive, statusPageTagKey)
+			}
+			th, thErr := parseThreshold(cmp, f.Type)
+			if thErr != nil {
+				return fieldTag{}, fmt.Errorf("field %q: %s: %w", f.Name, directive, thErr)
+			}
+			*dst = &th
+			continue
+		}
+		k, v, hasVal := strings.Cut(directive, "=")
+		v = strings.TrimSpace(v)
+		switch strings.TrimSpace(k) {
+		case "name":
+			if v == "" {
+				return fieldTag{}, fmt.Errorf("field %q: empty name in %s tag", f.Name, statusPageTagKey)
+			}
+			out.name = v
+		case "format":
+			vf, known := valueFormatNames[v]
+			if !known {
+				return fieldTag{}, fmt.Errorf("field %q: unknown format %q in %s tag", f.Name, v, statusPageTagKey)
+			}
+			out.format = vf
+		case "omitempty":
+			if hasVal {
+				return fieldTag{}, fmt.Errorf("field %q: omitempty takes no value in %s tag (got %q)", f.Name, statusPageTagKey, directive)
+			}
+			out.omitEmpty = true
+		case "help":
+			if v == "" {
+				return fieldTag{}, fmt.Errorf("field %q: empty help in %s tag", f.Name, statusPageTagKey)
+			}
+			out.help = v
+		case "trend":
+			if hasVal {
+				return fieldTag{}, fmt.Errorf("field %q: trend takes no value in %s tag (got %q)", f.Name, statusPageTagKey, directive)
+			}
+			if !trendable(f.Type) {
+				return fieldTag{}, fmt.Errorf("field %q: trend requires a number or a map of numbers, not %s", f.Name, f.Type)
+			}
+			out.trend = true
+		case "health":
+			if hasVal {
+				return fieldTag{}, fmt.Errorf("field %q: health takes no value in %s tag (got %q)", f.Name, statusPageTagKey, directive)
+			}
+			if !healthFlagType(f.Type) {
+				return fieldTag{}, fmt.Errorf("field %q: health requires a bool or an error (or a map of them), not %s", f.Name, f.Type)
+			}
+			out.health = true
+		case "summary":
+			if hasVal {
+				return fieldTag{}, fmt.Errorf("field %q: summary takes no value in %s tag (got %q)", f.Name, statusPageTagKey, directive)
+			}
+			out.summary = true
+		default:
+			return fieldTag{}, fmt.Errorf("field %q: unknown directive %q in %s tag", f.Name, directive, statusPageTagKey)
+		}
+	}
+	return out, nil
+}
+
+// structField is a struct field to be rendered, along with its parsed
+// statuspage tag.
+type structField struct {
+	reflect.StructField
+	tag fieldTag
+}
+
+// displayName returns the name the field is displayed with
+func (f *structField) displayName() string {
+	if f.tag.name != "" {
+		return f.tag.name
+	}
+	return f.Name
+}
+
+// step returns the path step from the struct to this field
+func (f *structField) step() pathStep {
+	st := fieldStep(f.Name)
+	st.label = f.displayName()
+	return st
+}
+
+// structFieldsResult is a cached result of structFields
+type structFieldsResult struct {
+	fields []structField
+	err    error
+}
+
+// structFieldsCache maps struct types to their structFieldsResult, so tags
+// are only parsed once per field.
+var structFieldsCache sync.Map
+
+// structFields returns the visible, exported fields of the struct type t that
+// aren't tagged with `statuspage:"-"`, in declaration order.
+func structFields(t reflect.Type) ([]structField, error) {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		res := cached.(structFieldsResult)
+		return res.fields, res.err
+	}
+	res := structFieldsResult{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() {
+			// skip the unexported fields for now
+			continue
+		}
+		tag, tagErr := parseFieldTag(f)
+		if tagErr != nil {
+			res = structFieldsResult{err: fmt.Errorf("struct %s: %w", t, tagErr)}
+			break
+		}
+		if tag.skip {
+			// The caller asked us to skip this
+			continue
+		}
+		res.fields = append(res.fields, structField{StructField: f, tag: tag})
+	}
+	structFieldsCache.Store(t, res)
+	return res.fields, res.err
+}
+
+// visibleFields returns the fields of the struct value v to walk, in
+// declaration order. Unexported fields, fields tagged with
+// `statuspage:"-"` and fields promoted through a nil embedded pointer are
+// omitted.
+func visibleFields(v reflect.Value) ([]structField, error) {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]structField, 0, len(fields))
+	for _, f := range fields {
+		fv, fErr := v.FieldByIndexErr(f.Index)
+		if fErr != nil {
+			// it's embedded, but has a nil parent
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Pointer && fv.IsNil() {
+			// its promoted fields are skipped above, so skip it too
+			continue
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// renderableFields is visibleFields with omitempty fields holding their zero
+// value removed as well.
+func renderableFields(v reflect.Value) ([]structField, error) {
+	fields, err := visibleFields(v)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(fields, func(f structField) bool {
+		return f.tag.omitEmpty && v.FieldByIndex(f.Index).IsZero()
+	}), nil
+}
+
+// omitted reports whether the field f of struct value v shouldn't be
+// rendered, either because it's hidden by omitempty or because it's promoted
+// through a nil embedded pointer. It's used by tables with a column per
+// field, where omitted fields get an empty cell.
+func (f *structField) omitted(v reflect.Value) bool {
+	fv, fErr := v.FieldByIndexErr(f.Index)
+	return fErr != nil || (f.tag.omitEmpty && fv.IsZero()) ||
+		(f.Anonymous && f.Type.Kind() == reflect.Pointer && fv.IsNil())
+}
+
+// setHelp gives n (the element naming the field f) a tooltip with the field's
+// help text, if it has any.
+func setHelp(n *html.Node, f *structField) {
+	if f.tag.help != "" {
+		setAttr(n, atom.Title.String(), f.tag.help)
+	}
+}
+
+// withFormat sets the number format for the values about to be rendered,
+// returning a func that restores the previous one.
+func (r *renderer) withFormat(f valueFormat) func() {
+	format := r.format
+	r.format = f
+	return func() { r.format = format }
+}
+
+// enterField moves the renderer to the field f of the current value,
+// returning a func that moves it back. Numbers within the field are formatted
+// (and checked against thresholds) according to its tag.
+func (r *renderer) enterField(f *structField) func() {
+	ascend := r.descend(f.step())
+	exitFormat := r.withFormat(f.tag.format)
+	exitThresholds := r.withThresholds(f.tag.thresholds)
+	// summary cards link to the field's value
+	r.anchorValue = f.tag.summary
+	return func() {
+		exitThresholds()
+		exitFormat()
+		ascend()
+	}
+}
+
+// byteUnits are the binary unit suffixes for valueFormatBytes
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// formatBytes renders a size in bytes with a binary unit, e.g. 1.5 MiB
+func formatBytes(n float64) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	unit := 0
+	for n >= 1024 && unit < len(byteUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return sign + strconv.FormatFloat(n, 'f', -1, 64) + " " + byteUnits[unit]
+	}
+	return sign + strconv.FormatFloat(n, 'f', 1, 64) + " " + byteUnits[unit]
+}
+
+// formattedText returns the textual form of the number v according to the
+// current field's format, and false if there's no format or it doesn't apply
+// to v.
+func (r *renderer) formattedText(v reflect.Value) (string, bool) {
+	if r.format == valueFormatDefault || eligibleStringer(v.Type()) {
+		return "", false
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch r.format {
+		case valueFormatBytes:
+			return formatBytes(float64(v.Int())), true
+		case valueFormatPercent:
+			return strconv.FormatInt(v.Int(), 10) + "%", true
+		case valueFormatHex:
+			if v.Int() < 0 {
+				return "-0x" + strconv.FormatInt(-v.Int(), 16), true
+			}
+			return "0x" + strconv.FormatInt(v.Int(), 16), true
+		case valueFormatDec:
+			return strconv.FormatInt(v.Int(), 10), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch r.format {
+		case valueFormatBytes:
+			return formatBytes(float64(v.Uint())), true
+		case valueFormatPercent:
+			return strconv.FormatUint(v.Uint(), 10) + "%", true
+		case valueFormatHex:
+			return "0x" + strconv.FormatUint(v.Uint(), 16), true
+		case valueFormatDec:
+			return strconv.FormatUint(v.Uint(), 10), true
+		}
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", false
+		}
+		switch r.format {
+		case valueFormatBytes:
+			return formatBytes(f), true
+		case valueFormatPercent:
+			return strconv.FormatFloat(f*100, 'f', 1, 64) + "%", true
+		}
+	}
+	return "", false
+}
+
+// mayHaveTagged reports whether values of type t may hold fields whose tags
+// satisfy tagged (interfaces may hold anything), caching the result by type
+// in cache.
+func mayHaveTagged(t reflect.Type, cache *sync.Map, tagged func(*fieldTag) bool) bool {
+	if cached, ok := cache.Load(t); ok {
+		return cached.(bool)
+	}
+	res := typeHasTagged(t, tagged, map[reflect.Type]struct{}{})
+	cache.Store(t, res)
+	return res
+}
+
+// typeHasTagged does the work for mayHaveTagged, with visiting holding the
+// types enclosing t (so recursive types terminate)
+func typeHasTagged(t reflect.Type, tagged func(*fieldTag) bool, visiting map[reflect.Type]struct{}) bool {
+	if _, ok := visiting[t]; ok {
+		return false
+	}
+	visiting[t] = struct{}{}
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasTagged(t.Elem(), tagged, visiting)
+	case reflect.Struct:
+		fields, fieldsErr := structFields(t)
+		if fieldsErr != nil {
+			return false
+		}
+		for _, f := range fields {
+			if tagged(&f.tag) || typeHasTagged(f.Type, tagged, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
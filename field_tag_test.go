@@ -0,0 +1,145 @@
+package statuspage
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	intType, durType := reflect.TypeFor[int](), reflect.TypeFor[time.Duration]()
+	for _, tc := range []struct {
+		name    string
+		typ     reflect.Type
+		tag     string
+		want    fieldTag
+		wantErr string
+	}{
+		{name: "no tag", typ: intType, tag: ``, want: fieldTag{}},
+		{name: "skip", typ: intType, tag: `statuspage:"-"`, want: fieldTag{skip: true}},
+		{name: "name and help", typ: intType, tag: `statuspage:"name=Open Conns,help=Connections in use"`,
+			want: fieldTag{name: "Open Conns", help: "Connections in use"}},
+		{name: "formats", typ: intType, tag: `statuspage:"format=hex"`, want: fieldTag{format: valueFormatHex}},
+		{name: "spaces around directives and values", typ: intType, tag: `statuspage:" format= bytes , omitempty ,summary"`,
+			want: fieldTag{format: valueFormatBytes, omitEmpty: true, summary: true}},
+		{name: "trend", typ: reflect.TypeFor[map[string]float64](), tag: `statuspage:"trend"`, want: fieldTag{trend: true}},
+		{name: "health", typ: reflect.TypeFor[error](), tag: `statuspage:"health"`, want: fieldTag{health: true}},
+		{name: "thresholds", typ: intType, tag: `statuspage:"warn>=8,crit> 10,unhealthy<0"`,
+			want: fieldTag{
+				thresholds: levelThresholds{
+					warn: &threshold{op: ">=", text: "8", val: 8},
+					crit: &threshold{op: ">", text: "10", val: 10},
+				},
+				unhealthy: &threshold{op: "<", text: "0", val: 0},
+			}},
+		{name: "duration thresholds", typ: durType, tag: `statuspage:"crit>1.5s"`,
+			want: fieldTag{thresholds: levelThresholds{crit: &threshold{op: ">", text: "1.5s", val: float64(1500 * time.Millisecond)}}}},
+
+		{name: "unknown directive", typ: intType, tag: `statuspage:"bold"`, wantErr: `unknown directive "bold"`},
+		{name: "unknown format", typ: intType, tag: `statuspage:"format=roman"`, wantErr: `unknown format "roman"`},
+		{name: "empty name", typ: intType, tag: `statuspage:"name=,help=x"`, wantErr: "empty name"},
+		{name: "blank name", typ: intType, tag: `statuspage:"name= "`, wantErr: "empty name"},
+		{name: "empty help", typ: intType, tag: `statuspage:"help="`, wantErr: "empty help"},
+		{name: "omitempty with a value", typ: intType, tag: `statuspage:"omitempty=true"`, wantErr: "omitempty takes no value"},
+		{name: "trend on a string", typ: reflect.TypeFor[string](), tag: `statuspage:"trend"`, wantErr: "trend requires a number"},
+		{name: "health on an int", typ: intType, tag: `statuspage:"health"`, wantErr: "health requires a bool or an error"},
+		{name: "threshold on a string", typ: reflect.TypeFor[string](), tag: `statuspage:"warn>1"`, wantErr: "thresholds require a number"},
+		{name: "unknown threshold", typ: intType, tag: `statuspage:"alert>1"`, wantErr: `unknown directive "alert>1"`},
+		{name: "invalid threshold value", typ: intType, tag: `statuspage:"warn>lots"`, wantErr: `invalid threshold "lots"`},
+		{name: "invalid duration threshold", typ: durType, tag: `statuspage:"warn>5"`, wantErr: "missing unit"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := reflect.StructField{Name: "F", Type: tc.typ, Tag: reflect.StructTag(tc.tag)}
+			got, err := parseFieldTag(f)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parseFieldTag(%s) = %+v, %v; want an error containing %q", tc.tag, got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFieldTag(%s): %s", tc.tag, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseFieldTag(%s) = %+v; want %+v", tc.tag, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	type embedded struct{ E int }
+	type s struct {
+		embedded
+		A      int `statuspage:"name=Aye"`
+		hidden int
+		B      int `statuspage:"-"`
+		C      string
+	}
+	fields, err := structFields(reflect.TypeFor[s]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range fields {
+		names = append(names, f.displayName())
+	}
+	if got, want := strings.Join(names, ","), "E,Aye,C"; got != want {
+		t.Errorf("structFields names = %s; want %s", got, want)
+	}
+
+	type bad struct {
+		X int `statuspage:"format=roman"`
+	}
+	if _, err := structFields(reflect.TypeFor[bad]()); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("structFields with a bad tag: %v; want an error naming the struct", err)
+	}
+}
+
+func TestFormattedText(t *testing.T) {
+	for _, tc := range []struct {
+		format valueFormat
+		v      any
+		want   string
+	}{
+		{valueFormatPercent, 0.25, "25.0%"},
+		{valueFormatPercent, float32(0.5), "50.0%"},
+		{valueFormatPercent, 50, "50%"},
+		{valueFormatPercent, uint8(5), "5%"},
+		{valueFormatBytes, 512, "512 B"},
+		{valueFormatBytes, 1536, "1.5 KiB"},
+		{valueFormatBytes, -2 << 20, "-2.0 MiB"},
+		{valueFormatBytes, 1.5 * (1 << 30), "1.5 GiB"},
+		{valueFormatHex, 255, "0xff"},
+		{valueFormatHex, -255, "-0xff"},
+		{valueFormatHex, uint(16), "0x10"},
+		{valueFormatDec, 255, "255"},
+	} {
+		r := newRenderer(&options{}, "")
+		r.format = tc.format
+		got, ok := r.formattedText(reflect.ValueOf(tc.v))
+		if !ok || got != tc.want {
+			t.Errorf("format %d of %T %v = %q, %t; want %q", tc.format, tc.v, tc.v, got, ok, tc.want)
+		}
+	}
+
+	// formats that don't apply leave the value to its usual rendering
+	for _, tc := range []struct {
+		format valueFormat
+		v      any
+	}{
+		{valueFormatDefault, 1},
+		{valueFormatHex, 1.5},
+		{valueFormatPercent, math.NaN()},
+		{valueFormatBytes, "1024"},
+		{valueFormatPercent, time.Second},
+	} {
+		r := newRenderer(&options{}, "")
+		r.format = tc.format
+		if got, ok := r.formattedText(reflect.ValueOf(tc.v)); ok {
+			t.Errorf("format %d of %T %v = %q; want no formatting", tc.format, tc.v, tc.v, got)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package statuspage
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// outputFormat identifies one of the representations ServeHTTP can produce.
+type outputFormat int
+
+const (
+	formatHTML outputFormat = iota
+	formatJSON
+	formatText
+	formatOpenMetrics
+)
+
+// formatQueryParam is the query parameter that overrides content negotiation
+// (e.g. ?format=json)
+const formatQueryParam = "format"
+
+// formatNames maps the values accepted by the format query parameter to
+// their outputFormat.
+var formatNames = map[string]outputFormat{
+	"html":        formatHTML,
+	"json":        formatJSON,
+	"text":        formatText,
+	"openmetrics": formatOpenMetrics,
+}
+
+// formatMediaTypes maps the media types we recognize in an Accept header to
+// their outputFormat.
+var formatMediaTypes = map[string]outputFormat{
+	"text/html":                    formatHTML,
+	"application/xhtml+xml":        formatHTML,
+	"application/json":             formatJSON,
+	"text/plain":                   formatText,
+	"application/openmetrics-text": formatOpenMetrics,
+}
+
+// terminalUserAgents are User-Agent product prefixes of command-line HTTP
+// clients that get plain-text output unless they ask for something else.
+var terminalUserAgents = []string{"curl/", "Wget/", "HTTPie/", "xh/"}
+
+// negotiateFormat picks the output format for the request r. An explicit
+// format query parameter wins; otherwise the Accept header is consulted.
+// If the Accept header doesn't name anything we produce (curl sends */*),
+// command-line clients get plain text and everyone else gets HTML.
+func negotiateFormat(r *http.Request) outputFormat {
+	if f, ok := formatNames[strings.ToLower(r.URL.Query().Get(formatQueryParam))]; ok {
+		return f
+	}
+
+	best, bestQ := formatHTML, 0.0
+	for _, accepted := range r.Header.Values("Accept") {
+		for mediaRange := range strings.SplitSeq(accepted, ",") {
+			mt, params, parseErr := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if parseErr != nil {
+				continue
+			}
+			f, ok := formatMediaTypes[mt]
+			if !ok {
+				continue
+			}
+			q := 1.0
+			if qs, hasQ := params["q"]; hasQ {
+				pq, qErr := strconv.ParseFloat(qs, 64)
+				if qErr != nil {
+					continue
+				}
+				q = pq
+			}
+			// Ties go to the earlier entry, which is the order
+			// clients list their preferences in.
+			if q > bestQ {
+				best, bestQ = f, q
+			}
+		}
+	}
+	if bestQ > 0 {
+		return best
+	}
+	ua := r.UserAgent()
+	for _, prefix := range terminalUserAgents {
+		if strings.HasPrefix(ua, prefix) {
+			return formatText
+		}
+	}
+	return formatHTML
+}
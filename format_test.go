@@ -0,0 +1,47 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		accept []string
+		ua     string
+		want   outputFormat
+	}{
+		{name: "nothing", want: formatHTML},
+		{name: "browser", accept: []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, ua: "Mozilla/5.0", want: formatHTML},
+		{name: "json", accept: []string{"application/json"}, want: formatJSON},
+		{name: "json with parameters", accept: []string{"application/json; charset=utf-8"}, want: formatJSON},
+		{name: "text", accept: []string{"text/plain"}, want: formatText},
+		{name: "openmetrics", accept: []string{"application/openmetrics-text; version=1.0.0"}, want: formatOpenMetrics},
+		{name: "highest q wins", accept: []string{"text/html;q=0.5, application/json;q=0.9"}, want: formatJSON},
+		{name: "ties go to the first", accept: []string{"text/plain, application/json"}, want: formatText},
+		{name: "across headers", accept: []string{"text/html;q=0.1", "application/json"}, want: formatJSON},
+		{name: "q=0 refuses", accept: []string{"application/json;q=0"}, want: formatHTML},
+		{name: "bad q is skipped", accept: []string{"application/json;q=lots, text/plain;q=0.1"}, want: formatText},
+		{name: "unparseable ranges are skipped", accept: []string{"/, application/json"}, want: formatJSON},
+		{name: "curl", accept: []string{"*/*"}, ua: "curl/8.5.0", want: formatText},
+		{name: "curl asking for json", accept: []string{"application/json"}, ua: "curl/8.5.0", want: formatJSON},
+		{name: "wget", ua: "Wget/1.21", want: formatText},
+		{name: "query overrides accept", query: "?format=json", accept: []string{"text/html"}, want: formatJSON},
+		{name: "query is case-insensitive", query: "?format=TEXT", want: formatText},
+		{name: "unknown query falls back", query: "?format=xml", accept: []string{"application/json"}, want: formatJSON},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.query, nil)
+			for _, a := range tc.accept {
+				req.Header.Add("Accept", a)
+			}
+			req.Header.Set("User-Agent", tc.ua)
+			if got := negotiateFormat(req); got != tc.want {
+				t.Errorf("negotiateFormat = %d; want %d", got, tc.want)
+			}
+		})
+	}
+}
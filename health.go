@@ -0,0 +1,293 @@
+package statuspage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HealthChecker is implemented by status types that can judge their own
+// health. Healthy returns the verdict, along with descriptions of the checks
+// that failed. It's combined with the fields tagged with health or
+// unhealthy directives (see statusPageTagKey), if any.
+type HealthChecker interface {
+	Healthy() (bool, []string)
+}
+
+// healthzPath is the sub-path serving just the health verdict (see Status)
+const healthzPath = "/_healthz"
+
+var errorType = reflect.TypeFor[error]()
+
+// healthVerdict is the outcome of a snapshot's health checks
+type healthVerdict struct {
+	// checks counts the checks made (zero if T has no health checks, in
+	// which case the verdict is left off the page)
+	checks int
+	// failing describes the checks that failed
+	failing []string
+}
+
+func (hv *healthVerdict) healthy() bool { return len(hv.failing) == 0 }
+
+// healthFlagType reports whether a field of type t can be tagged with
+// health: it must be a bool or an error, or a map of them.
+func healthFlagType(t reflect.Type) bool {
+	if !t.Implements(errorType) {
+		t = derefType(t)
+	}
+	if t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t.Implements(errorType) || derefType(t).Kind() == reflect.Bool
+}
+
+// healthTypesCache caches mayHaveHealthChecks by type
+var healthTypesCache sync.Map
+
+// mayHaveHealthChecks reports whether values of type t may hold fields
+// tagged with health checks
+func mayHaveHealthChecks(t reflect.Type) bool {
+	return mayHaveTagged(t, &healthTypesCache, func(ft *fieldTag) bool { return ft.healthChecked() })
+}
+
+// healthCollector holds the state for a single checkHealth
+type healthCollector struct {
+	opts    *options
+	verdict *healthVerdict
+	// seen holds the pointers already walked, to break cycles
+	seen map[visitKey]struct{}
+}
+
+// checkHealth runs the health checks on v (the value returned by a Status's
+// callback): those in tagged fields, followed by v's Healthy method.
+func checkHealth(opts *options, v reflect.Value) healthVerdict {
+	hv := healthVerdict{}
+	c := healthCollector{opts: opts, verdict: &hv, seen: map[visitKey]struct{}{}}
+	if mayHaveHealthChecks(v.Type()) {
+		c.collect(v, nil, nil, false)
+	}
+	if hc, ok := healthCheckerOf(v); ok {
+		hv.checks++
+		healthy, failing := callHealthy(hc)
+		if !healthy && len(failing) == 0 {
+			failing = []string{"Healthy reported a failure"}
+		}
+		hv.failing = append(hv.failing, failing...)
+	}
+	return hv
+}
+
+// healthCheckerOf returns v (or a pointer to it) as a HealthChecker, if it
+// implements it
+func healthCheckerOf(v reflect.Value) (HealthChecker, bool) {
+	if v.Kind() == reflect.Interface && v.IsNil() {
+		return nil, false
+	}
+	if hc, ok := v.Interface().(HealthChecker); ok {
+		return hc, true
+	}
+	if v.CanAddr() {
+		hc, ok := v.Addr().Interface().(HealthChecker)
+		return hc, ok
+	}
+	return nil, false
+}
+
+// callHealthy calls hc.Healthy, treating panics as failures
+func callHealthy(hc HealthChecker) (healthy bool, failing []string) {
+	defer func() {
+		if p := recover(); p != nil {
+			healthy, failing = false, []string{fmt.Sprintf("Healthy panicked: %v", p)}
+		}
+	}()
+	return hc.Healthy()
+}
+
+// collect runs the checks within v (at path p). tag holds the directives of
+// the field v is (or, if elem is set, is a map value of), or nil outside
+// tagged fields.
+func (c *healthCollector) collect(v reflect.Value, p fieldPath, tag *fieldTag, elem bool) {
+	if tag != nil && tag.health && v.Type().Implements(errorType) {
+		c.verdict.checks++
+		if !(isNilableType(v.Kind()) && v.IsNil()) {
+			c.fail(p, ": "+v.Interface().(error).Error())
+		}
+		return
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Pointer {
+			key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+			if _, seen := c.seen[key]; seen {
+				return
+			}
+			c.seen[key] = struct{}{}
+		}
+		v = v.Elem()
+	}
+	if tag != nil {
+		if tag.health && v.Kind() == reflect.Bool {
+			c.verdict.checks++
+			if !v.Bool() {
+				c.fail(p, " is false")
+			}
+			return
+		}
+		if f, ok := numericValue(v); ok && tag.unhealthy != nil {
+			c.verdict.checks++
+			if tag.unhealthy.crossed(f) {
+				c.fail(p, " is "+thresholdValText(v)+" (unhealthy when "+tag.unhealthy.String()+")")
+			}
+			return
+		}
+		if v.Kind() == reflect.Map && !elem {
+			for k, e := range c.opts.mapEntries(v) {
+				c.collect(e, p.child(keyStep(k)), tag, true)
+			}
+			return
+		}
+	}
+	if !mayHaveHealthChecks(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, fieldsErr := visibleFields(v)
+		if fieldsErr != nil {
+			return
+		}
+		for _, f := range fields {
+			var ft *fieldTag
+			if f.tag.healthChecked() {
+				ft = &f.tag
+			}
+			c.collect(v.FieldByIndex(f.Index), p.child(f.step()), ft, false)
+		}
+	case reflect.Map:
+		for k, e := range c.opts.mapEntries(v) {
+			c.collect(e, p.child(keyStep(k)), nil, false)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			c.collect(v.Index(i), p.child(indexStep(i)), nil, false)
+		}
+	}
+}
+
+// fail records a failed check of the value at p, described by what
+func (c *healthCollector) fail(p fieldPath, what string) {
+	c.verdict.failing = append(c.verdict.failing, p.label()+what)
+}
+
+// healthVerdict returns the verdict of snap's health checks, and whether T
+// has any
+func (s *Status[T]) healthVerdict(snap *snapshot[T]) (*healthVerdict, bool) {
+	snap.healthOnce.Do(func() {
+		snap.health = checkHealth(&s.opts, reflect.ValueOf(&snap.v).Elem())
+	})
+	return &snap.health, snap.health.checks > 0
+}
+
+// statusCodeWriter responds with code (rather than 200) unless the handler
+// it's passed to sets a status code of its own.
+type statusCodeWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusCodeWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusCodeWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(w.code)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// healthBanner returns the banner at the top of pages announcing the health
+// verdict and listing any failing checks.
+func (r *renderer) healthBanner() *html.Node {
+	div := createElemClass(atom.Div, "sp-health")
+	setAttr(div, "role", "status")
+	strong := createElemAtom(atom.Strong)
+	div.AppendChild(strong)
+	if r.health.healthy() {
+		addClass(div, "sp-healthy")
+		strong.AppendChild(textNode("✔ Healthy"))
+		return div
+	}
+	addClass(div, "sp-unhealthy")
+	strong.AppendChild(textNode("✘ Unhealthy: " + failingChecksText(len(r.health.failing))))
+	ul := createElemAtom(atom.Ul)
+	for _, f := range r.health.failing {
+		li := createElemAtom(atom.Li)
+		li.AppendChild(textNode(f))
+		ul.AppendChild(li)
+	}
+	div.AppendChild(ul)
+	return div
+}
+
+// healthBannerText is the plain-text counterpart to healthBanner
+func (r *renderer) healthBannerText() string {
+	if r.health.healthy() {
+		return "✔ Healthy\n"
+	}
+	b := strings.Builder{}
+	b.WriteString("✘ Unhealthy: " + failingChecksText(len(r.health.failing)) + "\n")
+	for _, f := range r.health.failing {
+		b.WriteString("  - " + f + "\n")
+	}
+	return b.String()
+}
+
+func failingChecksText(n int) string {
+	if n == 1 {
+		return "1 check failing"
+	}
+	return strconv.Itoa(n) + " checks failing"
+}
+
+// serveHealthz responds with just the health verdict: "ok" with a 200 if
+// the checks pass, or the failing checks with a 503 if they don't (or if
+// the callback fails). JSON clients get {"healthy": ..., "failing": [...]}.
+func (s *Status[T]) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	code, failing := http.StatusOK, []string{}
+	if snap, loadErr := s.snapshot(r.Context()); loadErr != nil {
+		code, failing = http.StatusServiceUnavailable, []string{errorMessage + ": " + loadErr.Error()}
+	} else if hv, _ := s.healthVerdict(snap); !hv.healthy() {
+		code, failing = http.StatusServiceUnavailable, hv.failing
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if negotiateFormat(r) == formatJSON {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(jsonObject{{Key: "healthy", Val: code == http.StatusOK}, {Key: "failing", Val: failing}})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	if code == http.StatusOK {
+		io.WriteString(w, "ok\n")
+		return
+	}
+	io.WriteString(w, "unhealthy\n")
+	for _, f := range failing {
+		io.WriteString(w, f+"\n")
+	}
+}
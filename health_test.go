@@ -0,0 +1,176 @@
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type healthElem struct {
+	Up bool `statuspage:"health"`
+}
+
+type healthVal struct {
+	DB     error           `statuspage:"health"`
+	OK     bool            `statuspage:"health"`
+	Lag    int             `statuspage:"unhealthy>10"`
+	Deps   map[string]bool `statuspage:"health"`
+	Elems  []healthElem
+	Ptr    *healthElem
+	Plain  bool
+	Nested *healthVal
+}
+
+// checkedVal reports its own health, failing if it has reasons or Fail is
+// set
+type checkedVal struct {
+	Reasons     []string
+	Fail, Panic bool
+}
+
+func (c *checkedVal) Healthy() (bool, []string) {
+	if c.Panic {
+		panic("lost track")
+	}
+	return len(c.Reasons) == 0 && !c.Fail, c.Reasons
+}
+
+func TestCheckHealth(t *testing.T) {
+	cycle := &healthVal{OK: true}
+	cycle.Nested = cycle
+	for _, tc := range []struct {
+		name        string
+		v           any
+		wantChecks  int
+		wantFailing []string
+	}{
+		{"no checks", struct{ A bool }{}, 0, nil},
+		{"passing", healthVal{OK: true, Lag: 10, Deps: map[string]bool{"a": true}, Elems: []healthElem{{Up: true}}}, 5, nil},
+		{"failing", healthVal{
+			DB:    errors.New("refused"),
+			Lag:   11,
+			Deps:  map[string]bool{"a": true, "b c": false},
+			Elems: []healthElem{{Up: true}, {}},
+			Ptr:   &healthElem{},
+		}, 8, []string{
+			"DB: refused",
+			"OK is false",
+			"Lag is 11 (unhealthy when >10)",
+			"Deps › b c is false",
+			"Elems › [1] › Up is false",
+			"Ptr › Up is false",
+		}},
+		{"cycle", cycle, 3, nil},
+		{"Healthy", checkedVal{}, 1, nil},
+		{"Healthy failing", checkedVal{Reasons: []string{"queue stuck"}}, 1, []string{"queue stuck"}},
+		{"Healthy failing without reasons", checkedVal{Fail: true}, 1, []string{"Healthy reported a failure"}},
+		{"Healthy panicking", checkedVal{Panic: true}, 1, []string{"Healthy panicked: lost track"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// (addressable, as the snapshots are)
+			v := reflect.New(reflect.TypeOf(tc.v)).Elem()
+			v.Set(reflect.ValueOf(tc.v))
+			hv := checkHealth(&options{}, v)
+			if hv.checks != tc.wantChecks || !slices.Equal(hv.failing, tc.wantFailing) {
+				t.Errorf("checkHealth = %d checks, failing %q; want %d, %q", hv.checks, hv.failing, tc.wantChecks, tc.wantFailing)
+			}
+		})
+	}
+}
+
+func TestHealthStatusCodes(t *testing.T) {
+	healthy := true
+	mux := http.NewServeMux()
+	mux.Handle("/status/", New("Test", func() healthVal {
+		return healthVal{OK: healthy, Deps: map[string]bool{"cache": healthy}}
+	}))
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/status/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<strong>✔ Healthy</strong>`) {
+		t.Errorf("healthy page: status %d; want 200 with a healthy banner:\n%s", rec.Code, rec.Body)
+	}
+	if rec := get("/status/_healthz"); rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("healthy /_healthz: %d %q; want 200 \"ok\\n\"", rec.Code, rec.Body)
+	}
+
+	healthy = false
+	rec := get("/status/")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("unhealthy page: status %d; want 503", rec.Code)
+	}
+	for _, want := range []string{
+		`<div class="sp-health sp-unhealthy" role="status"><strong>✘ Unhealthy: 2 checks failing</strong>`,
+		"<li>OK is false</li><li>Deps › cache is false</li>",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("unhealthy page doesn't contain %q:\n%s", want, rec.Body)
+		}
+	}
+	if rec := get("/status/?format=text"); rec.Code != http.StatusServiceUnavailable ||
+		!strings.Contains(rec.Body.String(), "✘ Unhealthy: 2 checks failing\n  - OK is false\n  - Deps › cache is false\n") {
+		t.Errorf("unhealthy text page: status %d; want 503 listing the failing checks:\n%s", rec.Code, rec.Body)
+	}
+	// metrics scrapes aren't health checks
+	if rec := get("/status/?format=openmetrics"); rec.Code != http.StatusOK {
+		t.Errorf("unhealthy metrics: status %d; want 200", rec.Code)
+	}
+	// sub-pages take the verdict of the whole value
+	if rec := get("/status/Deps"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("unhealthy sub-page: status %d; want 503", rec.Code)
+	}
+
+	rec = get("/status/_healthz")
+	if want := "unhealthy\nOK is false\nDeps › cache is false\n"; rec.Code != http.StatusServiceUnavailable || rec.Body.String() != want {
+		t.Errorf("unhealthy /_healthz: %d %q; want 503 %q", rec.Code, rec.Body, want)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("/_healthz Cache-Control = %q; want no-store", cc)
+	}
+	rec = get("/status/_healthz", "Accept", "application/json")
+	var verdict struct {
+		Healthy bool
+		Failing []string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &verdict); err != nil {
+		t.Fatalf("decoding /_healthz JSON: %s:\n%s", err, rec.Body)
+	}
+	if rec.Code != http.StatusServiceUnavailable || verdict.Healthy || len(verdict.Failing) != 2 {
+		t.Errorf("unhealthy /_healthz JSON: %d %+v; want 503 with 2 failing checks", rec.Code, verdict)
+	}
+}
+
+func TestHealthzWithoutChecks(t *testing.T) {
+	s := New("Test", func() struct{ A int } { return struct{ A int }{} })
+	// mounted at the health check's own path
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("/_healthz without checks: %d %q; want 200 \"ok\\n\"", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Contains(rec.Body.String(), `class="sp-health`) {
+		t.Errorf("page without checks has a health banner:\n%s", rec.Body)
+	}
+
+	failing := NewCtx("Test", func(context.Context) (int, error) { return 0, errors.New("no backend") })
+	rec = httptest.NewRecorder()
+	failing.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_healthz", nil))
+	if want := "unhealthy\nFailed to load status: no backend\n"; rec.Code != http.StatusServiceUnavailable || rec.Body.String() != want {
+		t.Errorf("/_healthz with a failing callback: %d %q; want 503 %q", rec.Code, rec.Body, want)
+	}
+}
@@ -0,0 +1,218 @@
+package statuspage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// historyQueryParam selects a snapshot from the history to render (see
+// WithHistory), by its time in RFC 3339 format.
+const historyQueryParam = "at"
+
+// history holds the most recent snapshots taken by a Status's sampler,
+// oldest first.
+type history[T any] struct {
+	mu    sync.Mutex
+	snaps []*snapshot[T]
+	size  int
+	// opts are the Status's options, for copying snapshots
+	opts *options
+}
+
+func newHistory[T any](size int, o *options) *history[T] {
+	return &history[T]{snaps: make([]*snapshot[T], 0, size), size: size, opts: o}
+}
+
+// add records snap (unless there's already a snapshot loaded at the same
+// time), evicting the oldest snapshot if the history is full
+func (h *history[T]) add(snap *snapshot[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i, found := slices.BinarySearchFunc(h.snaps, snap.loadedAt, compareLoadedAt[T])
+	if found {
+		return
+	}
+	h.snaps = slices.Insert(h.snaps, i, snap)
+	if len(h.snaps) > h.size {
+		h.snaps = slices.Delete(h.snaps, 0, len(h.snaps)-h.size)
+	}
+}
+
+func compareLoadedAt[T any](snap *snapshot[T], t time.Time) int {
+	return snap.loadedAt.Compare(t)
+}
+
+// all returns the snapshots in the history, oldest first
+func (h *history[T]) all() []*snapshot[T] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.snaps)
+}
+
+// times returns the times of the snapshots in the history, oldest first
+func (h *history[T]) times() []time.Time {
+	snaps := h.all()
+	out := make([]time.Time, len(snaps))
+	for i, snap := range snaps {
+		out[i] = snap.loadedAt
+	}
+	return out
+}
+
+// at returns the latest snapshot taken at or before t
+func (h *history[T]) at(t time.Time) (*snapshot[T], bool) {
+	snaps := h.all()
+	i, found := slices.BinarySearchFunc(snaps, t, compareLoadedAt[T])
+	if found {
+		return snaps[i], true
+	}
+	if i == 0 {
+		return nil, false
+	}
+	return snaps[i-1], true
+}
+
+// loaded returns the snapshot loaded at exactly t
+func (h *history[T]) loaded(t time.Time) (*snapshot[T], bool) {
+	snaps := h.all()
+	i, found := slices.BinarySearchFunc(snaps, t, compareLoadedAt[T])
+	if !found {
+		return nil, false
+	}
+	return snaps[i], true
+}
+
+// record adds a copy of snap to the history (if it isn't there already)
+func (h *history[T]) record(snap *snapshot[T]) {
+	if _, ok := h.loaded(snap.loadedAt); ok {
+		return
+	}
+	h.add(&snapshot[T]{v: deepCopy(snap.v, h.opts), loadedAt: snap.loadedAt})
+}
+
+// sample calls the Status's callback every interval until ctx is done,
+// passing each new snapshot to onSnap.
+func (s *Status[T]) sample(ctx context.Context, interval time.Duration, onSnap func(*snapshot[T])) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Time{}
+	for {
+		// Failed calls are skipped, and snapshots cached for longer than
+		// the interval are only passed along once
+		if snap, err := s.snapshot(ctx); err == nil && !snap.loadedAt.Equal(last) {
+			onSnap(snap)
+			last = snap.loadedAt
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startSampler runs sample in the background until ctx is done
+func (s *Status[T]) startSampler(ctx context.Context, interval time.Duration, onSnap func(*snapshot[T])) {
+	s.samplers.Add(1)
+	go func() {
+		defer s.samplers.Done()
+		s.sample(ctx, interval, onSnap)
+	}()
+}
+
+// Close stops the samplers started by WithHistory and WithTrends (if any),
+// waiting for them to exit. The Status continues to serve requests
+// (including for the snapshots already in its history).
+func (s *Status[T]) Close() error {
+	s.stopSamplers()
+	s.samplers.Wait()
+	return nil
+}
+
+// historySnapshot returns the snapshot from the history requested by the
+// value of the at query parameter, along with the status code to respond
+// with if there isn't one.
+func (s *Status[T]) historySnapshot(at string) (*snapshot[T], int, error) {
+	if s.history == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no snapshot history is kept (see WithHistory)")
+	}
+	t, parseErr := time.Parse(time.RFC3339Nano, at)
+	if parseErr != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid %s parameter: %w", historyQueryParam, parseErr)
+	}
+	snap, ok := s.history.at(t)
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("no snapshot at or before %s", t.Format(snapshotTimeLayout))
+	}
+	return snap, 0, nil
+}
+
+// timelineNav returns the selector for the snapshots in the history, which
+// goes across the top of HTML pages. It's a form so it works without
+// scripts; the current page's other query parameters (other than pagination
+// offsets, which needn't apply to other snapshots) are carried over.
+func (r *renderer) timelineNav() *html.Node {
+	nav := createElemClass(atom.Nav, "sp-timeline")
+	setAttr(nav, "aria-label", "Snapshots")
+	form := createElemAtom(atom.Form)
+	form.Attr = append(form.Attr, html.Attribute{Key: atom.Method.String(), Val: http.MethodGet})
+	nav.AppendChild(form)
+
+	label := createElemAtom(atom.Label)
+	label.AppendChild(textNode("Snapshot: "))
+	form.AppendChild(label)
+	sel := createElemAtom(atom.Select)
+	sel.Attr = append(sel.Attr,
+		html.Attribute{Key: atom.Name.String(), Val: historyQueryParam},
+		html.Attribute{Key: atom.Onchange.String(), Val: "this.form.submit()"})
+	label.AppendChild(sel)
+
+	option := func(val, text string, selected bool) {
+		o := createElemAtom(atom.Option)
+		o.Attr = append(o.Attr, html.Attribute{Key: atom.Value.String(), Val: val})
+		if selected {
+			o.Attr = append(o.Attr, html.Attribute{Key: atom.Selected.String()})
+		}
+		o.AppendChild(textNode(text))
+		sel.AppendChild(o)
+	}
+	option("", "latest", !r.historical)
+	// newest first, to match the latest option
+	for _, t := range slices.Backward(r.timeline) {
+		option(t.UTC().Format(time.RFC3339Nano), t.Format(snapshotTimeLayout), r.historical && t.Equal(r.snapshotAt))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(r.query)) {
+		if k == historyQueryParam || isPageOffsetParam(k) {
+			continue
+		}
+		for _, v := range r.query[k] {
+			hidden := createElemAtom(atom.Input)
+			hidden.Attr = append(hidden.Attr,
+				html.Attribute{Key: atom.Type.String(), Val: "hidden"},
+				html.Attribute{Key: atom.Name.String(), Val: k},
+				html.Attribute{Key: atom.Value.String(), Val: v})
+			form.AppendChild(hidden)
+		}
+	}
+	// for browsers without scripts
+	noscript := createElemAtom(atom.Noscript)
+	submit := createElemAtom(atom.Button)
+	submit.AppendChild(textNode("View"))
+	noscript.AppendChild(submit)
+	form.AppendChild(noscript)
+
+	diff := createElemAtom(atom.A)
+	diff.Attr = append(diff.Attr, html.Attribute{Key: atom.Href.String(), Val: "?" + diffQueryParam + "=" + diffSinceSeen})
+	diff.AppendChild(textNode("Changes since your last visit"))
+	nav.AppendChild(diff)
+	return nav
+}
@@ -0,0 +1,221 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type copyInner struct {
+	N    int
+	Tags []string
+}
+
+type copyNode struct{ Next *copyNode }
+
+// gauge has a renderer set with WithRenderer
+type gauge struct{ V int }
+
+func renderGauge(g gauge) ([]*html.Node, error) {
+	return []*html.Node{textNode("gauge")}, nil
+}
+
+type copyVal struct {
+	Inner      copyInner
+	Ptr, Alias *copyInner
+	M          map[string]*copyInner
+	S, SSame   []int
+	SPrefix    []int
+	Arr        [2][]int
+	Any        any
+	Gauge      *gauge
+	Cycle      *copyNode
+	Loc        *time.Location
+	Ch         chan int
+	Skipped    []int `statuspage:"-"`
+	hidden     []int
+}
+
+func TestDeepCopy(t *testing.T) {
+	ptr := &copyInner{N: 1}
+	s := []int{1, 2}
+	cycle := &copyNode{}
+	cycle.Next = &copyNode{Next: cycle}
+	orig := copyVal{
+		Inner:   copyInner{N: 1, Tags: []string{"a"}},
+		Ptr:     ptr,
+		Alias:   ptr,
+		M:       map[string]*copyInner{"a": ptr},
+		S:       s,
+		SSame:   s,
+		SPrefix: s[:1],
+		Arr:     [2][]int{{1}, {2}},
+		Any:     &copyInner{N: 1},
+		Gauge:   &gauge{V: 1},
+		Cycle:   cycle,
+		Loc:     time.UTC,
+		Ch:      make(chan int),
+		Skipped: []int{1},
+		hidden:  []int{1},
+	}
+	o := &options{}
+	WithRenderer(renderGauge)(o)
+	cp := deepCopy(orig, o)
+
+	// aliasing in the original is kept in the copy, but nothing's shared
+	// with the original
+	if cp.Ptr != cp.Alias || cp.M["a"] != cp.Ptr {
+		t.Error("copies of the same pointer differ")
+	}
+	if cp.Ptr == orig.Ptr {
+		t.Error("pointer shared with the original")
+	}
+	if &cp.SSame[0] != &cp.S[0] {
+		t.Error("copies of the same slice differ")
+	}
+	if &cp.SPrefix[0] == &cp.S[0] {
+		t.Error("slices of different lengths share a copy")
+	}
+	if cp.Cycle.Next.Next != cp.Cycle || cp.Cycle == orig.Cycle {
+		t.Error("cycle isn't copied as a cycle")
+	}
+	// shared types, and those that can't be copied, are assigned
+	if cp.Loc != time.UTC || cp.Ch != orig.Ch {
+		t.Error("location or channel copied")
+	}
+	// the parts that don't render are left out
+	if cp.Skipped != nil || cp.hidden != nil {
+		t.Errorf("copied fields that don't render: %v, %v", cp.Skipped, cp.hidden)
+	}
+
+	orig.Inner.Tags[0] = "changed"
+	orig.Ptr.N = 2
+	orig.M["b"] = &copyInner{}
+	orig.S[0] = 9
+	orig.Arr[0][0] = 9
+	orig.Any.(*copyInner).N = 2
+	// the pointer's followed to the rendered value, so that's copied too
+	orig.Gauge.V = 2
+	want := copyVal{
+		Inner:   copyInner{N: 1, Tags: []string{"a"}},
+		Ptr:     &copyInner{N: 1},
+		M:       map[string]*copyInner{"a": {N: 1}},
+		S:       []int{1, 2},
+		SPrefix: []int{1},
+		Arr:     [2][]int{{1}, {2}},
+		Any:     &copyInner{N: 1},
+		Gauge:   &gauge{V: 1},
+	}
+	if cp.Inner.Tags[0] != want.Inner.Tags[0] || cp.Ptr.N != want.Ptr.N || len(cp.M) != len(want.M) ||
+		!slices.Equal(cp.S, want.S) || cp.Arr[0][0] != want.Arr[0][0] ||
+		cp.Any.(*copyInner).N != want.Any.(*copyInner).N || cp.Gauge.V != want.Gauge.V {
+		t.Errorf("copy changed with the original: %+v", cp)
+	}
+}
+
+// selfRendered renders itself, so deepCopy assigns it, sharing what it
+// points to
+type selfRendered struct{ P *int }
+
+func (selfRendered) String() string { return "self" }
+
+func TestDeepCopyRendersItself(t *testing.T) {
+	n := 1
+	orig := struct{ S selfRendered }{S: selfRendered{P: &n}}
+	if cp := deepCopy(orig, &options{}); cp.S.P != &n {
+		t.Error("value with a String method was copied, rather than assigned")
+	}
+}
+
+func TestHistoryRing(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory[int](3, &options{})
+	for _, i := range []int{2, 0, 1, 3, 3, 4} {
+		h.record(&snapshot[int]{v: i, loadedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+	got := []int{}
+	for _, snap := range h.all() {
+		got = append(got, snap.v)
+	}
+	if want := []int{2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("history = %v; want the latest 3, %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		at     time.Duration
+		want   int
+		wantOK bool
+	}{
+		{2 * time.Minute, 2, true},
+		{150 * time.Second, 2, true},
+		{time.Hour, 4, true},
+		{time.Minute, 0, false},
+	} {
+		snap, ok := h.at(base.Add(tc.at))
+		if ok != tc.wantOK || (ok && snap.v != tc.want) {
+			t.Errorf("at(+%s) = %v, %t; want %d, %t", tc.at, snap, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestHistoryAt(t *testing.T) {
+	live := &copyInner{N: 1, Tags: []string{"sampled"}}
+	s := New("Test", func() *copyInner { return live }, WithHistory(time.Hour, 3))
+	defer s.Close()
+	waitForSnapshots(t, s, 1)
+	sampled := s.history.times()[0]
+	// later mutations don't bleed into the history
+	live.N, live.Tags[0] = 2, "mutated"
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	at := sampled.UTC().Format(time.RFC3339Nano)
+	rec := get("/?at=" + url.QueryEscape(at) + "&format=json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("?at=%s: status %d: %s", at, rec.Code, rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"sampled"`) || strings.Contains(body, "mutated") {
+		t.Errorf("historical snapshot isn't as sampled:\n%s", body)
+	}
+
+	page := get("/?at=" + url.QueryEscape(at)).Body.String()
+	for _, want := range []string{
+		"(from the history)",
+		`<option value="">latest</option>`,
+		`<option value="` + at + `" selected="">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("historical page doesn't contain %q:\n%s", want, page)
+		}
+	}
+	if latest := get("/").Body.String(); !strings.Contains(latest, `<option value="" selected="">latest</option>`) {
+		t.Errorf("latest page doesn't select the latest option:\n%s", latest)
+	}
+
+	for _, tc := range []struct {
+		at   string
+		code int
+	}{
+		{"yesterday", http.StatusBadRequest},
+		{sampled.Add(-time.Hour).UTC().Format(time.RFC3339Nano), http.StatusNotFound},
+	} {
+		if rec := get("/?at=" + url.QueryEscape(tc.at)); rec.Code != tc.code {
+			t.Errorf("?at=%s: status %d; want %d", tc.at, rec.Code, tc.code)
+		}
+	}
+	noHistory := New("Test", func() int { return 1 })
+	rec = httptest.NewRecorder()
+	noHistory.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?at="+url.QueryEscape(at), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("?at= without a history: status %d; want 404", rec.Code)
+	}
+}
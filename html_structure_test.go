@@ -0,0 +1,436 @@
+package statuspage_test
+
+import (
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	statuspage "github.com/vimeo/go-status-page"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type elem struct {
+	A int    `statuspage:"help=the a,warn>1,crit>2"`
+	B string `statuspage:"name=Bee"`
+}
+
+type key struct {
+	Region string
+	Zone   int
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type nested struct {
+	Count    uint64        `statuspage:"format=bytes,summary"`
+	Latency  time.Duration `statuspage:"crit>1s"`
+	Ratio    float64       `statuspage:"format=percent"`
+	Up       bool
+	Err      error
+	Nil      *elem
+	Inner    elem
+	Backends map[string]elem
+	Tags     []string
+}
+
+// serveStatus serves the page for v at path (beneath /status/), failing t
+// unless it's served with a 200.
+func serveStatus[T any](t *testing.T, v T, path string, opts ...statuspage.Option) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/status/", statuspage.New("Test", func() T { return v }, opts...))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/"+path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /status/%s: status %d: %s", path, rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func TestHTMLStructure(t *testing.T) {
+	shared := &elem{A: 1, B: "shared"}
+	cycle := &node{Name: "a"}
+	cycle.Next = &node{Name: "b", Next: cycle}
+	bigMap := map[string]elem{}
+	for i := range 50 {
+		bigMap["k"+strconv.Itoa(i)] = elem{A: i}
+	}
+
+	for _, tc := range []struct {
+		name string
+		page func(t *testing.T) string
+	}{
+		{"scalars", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				I   int
+				U   uint8
+				F   float32
+				S   string
+				B   bool
+				D   time.Duration
+				T   time.Time
+				P   *int
+				Any any
+			}{I: -3, U: 7, F: 1.5, S: "<b>", B: true, D: time.Second}, "")
+		}},
+		{"nested struct", func(t *testing.T) string {
+			return serveStatus(t, nested{
+				Count: 1 << 20, Latency: 2 * time.Second, Ratio: 0.25, Err: errors.New("oops"),
+				Inner:    elem{A: 3},
+				Backends: map[string]elem{"us-east": {A: 1, B: "x"}, "eu-west": {A: 5}},
+				Tags:     []string{"a", "b"},
+			}, "")
+		}},
+		{"maps of scalars", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				M     map[string]int
+				Empty map[string]int
+				Nil   map[int]string
+				Set   map[string]struct{}
+			}{M: map[string]int{"a": 1, "b": 2}, Empty: map[string]int{}, Set: map[string]struct{}{"x": {}, "y": {}}}, "")
+		}},
+		{"maps of structs", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				Vals    map[string]elem
+				Keys    map[key]int
+				Both    map[key]elem
+				Ptrs    map[string]*elem
+				NilPtrs map[string]*elem
+			}{
+				Vals:    map[string]elem{"a": {A: 1}, "b": {A: 3}},
+				Keys:    map[key]int{{"us", 1}: 1, {"eu", 2}: 2},
+				Both:    map[key]elem{{"us", 1}: {A: 1}},
+				Ptrs:    map[string]*elem{"a": shared, "b": shared},
+				NilPtrs: map[string]*elem{"n": nil},
+			}, "")
+		}},
+		{"maps of collections", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				Maps    map[string]map[string]int
+				Slices  map[string][]int
+				Long    map[string][]int
+				Nil     map[string][]int
+				Structs map[string][]elem
+			}{
+				Maps:    map[string]map[string]int{"x": {"a": 1}, "y": {"b": 2}},
+				Slices:  map[string][]int{"s": {1, 2, 3}},
+				Long:    map[string][]int{"l": make([]int, 20)},
+				Nil:     map[string][]int{"n": nil},
+				Structs: map[string][]elem{"e": {{A: 1}, {A: 2}}},
+			}, "")
+		}},
+		{"slices and arrays", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				Ints    []int
+				Structs []elem
+				Ptrs    []*elem
+				Grid    [][]int
+				Ragged  [][]int
+				Array   [3]int
+				Mixed   []any
+				Uniform []any
+				Nil     []int
+			}{
+				Ints:    []int{1, 2, 3},
+				Structs: []elem{{A: 1}, {A: 3, B: "c"}},
+				Ptrs:    []*elem{shared, nil, shared},
+				Grid:    [][]int{{1, 2}, {3, 4}},
+				Ragged:  [][]int{{1}, nil, {2, 3, 4}},
+				Array:   [3]int{1, 2, 3},
+				Mixed:   []any{1, "two", elem{A: 3}},
+				Uniform: []any{elem{A: 1}, elem{A: 2}},
+			}, "")
+		}},
+		{"iterators", func(t *testing.T) string {
+			return serveStatus(t, struct {
+				Seq  iter.Seq[int]
+				Seq2 iter.Seq2[string, elem]
+			}{
+				Seq: func(yield func(int) bool) {
+					for i := range 3 {
+						if !yield(i) {
+							return
+						}
+					}
+				},
+				Seq2: func(yield func(string, elem) bool) {
+					yield("a", elem{A: 1})
+				},
+			}, "")
+		}},
+		{"cycles", func(t *testing.T) string {
+			return serveStatus(t, struct{ Head *node }{Head: cycle}, "")
+		}},
+		{"truncated", func(t *testing.T) string {
+			return serveStatus(t, struct{ Big map[string]elem }{Big: bigMap},
+				"", statuspage.WithRenderLimits(statuspage.RenderLimits{MaxNodes: 40}))
+		}},
+		{"paginated", func(t *testing.T) string {
+			return serveStatus(t, struct{ Ints []int }{Ints: make([]int, 30)}, "", statuspage.WithPageSize(10))
+		}},
+		{"sub-page", func(t *testing.T) string {
+			return serveStatus(t, struct{ Backends map[string]elem }{Backends: map[string]elem{"a": {A: 1}}}, "Backends")
+		}},
+		{"live", func(t *testing.T) string {
+			return serveStatus(t, nested{Inner: elem{A: 1}}, "", statuspage.WithLiveUpdates(time.Second))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			page := tc.page(t)
+			checkTokens(t, page)
+			doc, parseErr := html.Parse(strings.NewReader(page))
+			if parseErr != nil {
+				t.Fatalf("failed to parse page: %s", parseErr)
+			}
+			checkDocument(t, doc)
+			if t.Failed() {
+				t.Logf("page:\n%s", page)
+			}
+		})
+	}
+}
+
+type point struct{ X, Y int }
+
+func TestMapKeyColumns(t *testing.T) {
+	type shapedKey struct {
+		Name  string
+		Shape [2]int
+		Where key
+		At    point
+	}
+	// points render as two nodes, each getting a cell of its own
+	renderPoint := statuspage.WithRenderer(func(p point) ([]*html.Node, error) {
+		return []*html.Node{
+			{Type: html.TextNode, Data: strconv.Itoa(p.X)},
+			{Type: html.TextNode, Data: strconv.Itoa(p.Y)},
+		}, nil
+	}, statuspage.NonScalar())
+	page := serveStatus(t, struct{ M map[shapedKey]elem }{M: map[shapedKey]elem{
+		{"a", [2]int{1, 2}, key{"us", 1}, point{1, 2}}: {A: 1},
+		{Name: "b"}: {A: 2},
+	}}, "", renderPoint)
+	checkTokens(t, page)
+	doc, parseErr := html.Parse(strings.NewReader(page))
+	if parseErr != nil {
+		t.Fatalf("failed to parse page: %s", parseErr)
+	}
+	checkDocument(t, doc)
+
+	// the key spans its fields' columns, including those holding tables
+	var tbl *html.Node
+	for n := range elems(doc, atom.Table) {
+		if attrVal(n, "id") == "sp/M" {
+			tbl = n
+		}
+	}
+	if tbl == nil {
+		t.Fatalf("no table for M:\n%s", page)
+	}
+	head := firstElem(tbl, atom.Thead)
+	if got := attrVal(firstElem(head, atom.Th), "colspan"); got != "5" {
+		t.Errorf("key header spans %q columns; want 5", got)
+	}
+	for row := range elems(tbl, atom.Tr) {
+		if row.Parent.Parent != tbl {
+			continue
+		}
+		if w := rowColumns(row); w != 7 {
+			t.Errorf("row %q is %d columns wide; want 7", attrVal(row, "id"), w)
+		}
+	}
+}
+
+// rowColumns returns the number of columns row's cells span
+func rowColumns(row *html.Node) int {
+	w := 0
+	for cell := firstChildElem(row); cell != nil; cell = nextElem(cell) {
+		span := 1
+		if s := attrVal(cell, "colspan"); s != "" {
+			span, _ = strconv.Atoi(s)
+		}
+		w += span
+	}
+	return w
+}
+
+// checkTokens checks the page's tags: no attribute appears twice on an
+// element, ids are unique, fragment links resolve, and colspans are valid.
+func checkTokens(t *testing.T, page string) {
+	t.Helper()
+	ids := map[string]bool{}
+	fragments := []string{}
+	z := html.NewTokenizer(strings.NewReader(page))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		seen := map[string]bool{}
+		for _, a := range tok.Attr {
+			if seen[a.Key] {
+				t.Errorf("<%s> has more than one %s attribute", tok.Data, a.Key)
+			}
+			seen[a.Key] = true
+			switch a.Key {
+			case "id":
+				if ids[a.Val] {
+					t.Errorf("duplicate id %q", a.Val)
+				}
+				ids[a.Val] = true
+			case "href":
+				if frag, ok := strings.CutPrefix(a.Val, "#"); ok {
+					fragments = append(fragments, frag)
+				}
+			case "colspan":
+				if n, convErr := strconv.Atoi(a.Val); convErr != nil || n < 1 {
+					t.Errorf("<%s> has invalid colspan %q", tok.Data, a.Val)
+				}
+			case "alt":
+				if tok.DataAtom != atom.Img && tok.DataAtom != atom.Input && tok.DataAtom != atom.Area {
+					t.Errorf("<%s> has an alt attribute", tok.Data)
+				}
+			}
+		}
+	}
+	for _, frag := range fragments {
+		if !ids[frag] {
+			t.Errorf("link to #%s, which isn't on the page", frag)
+		}
+	}
+}
+
+// checkDocument checks the structure of the parsed page: the document's
+// language, charset and landmarks, and the sections and header cells of its
+// tables.
+func checkDocument(t *testing.T, doc *html.Node) {
+	t.Helper()
+	root := firstElem(doc, atom.Html)
+	if root == nil {
+		t.Fatal("no <html> element")
+	}
+	if lang := attrVal(root, "lang"); lang == "" {
+		t.Error("<html> has no lang")
+	}
+	head, body := firstElem(root, atom.Head), firstElem(root, atom.Body)
+	if head == nil || body == nil {
+		t.Fatal("missing <head> or <body>")
+	}
+	if meta := firstChildElem(head); meta == nil || meta.DataAtom != atom.Meta || attrVal(meta, "charset") == "" {
+		t.Error("<head> doesn't open with <meta charset>")
+	}
+	if firstElem(head, atom.H1) != nil {
+		t.Error("<h1> in <head>")
+	}
+	if firstElem(body, atom.H1) == nil {
+		t.Error("no <h1> in <body>")
+	}
+	if firstElem(body, atom.Main) == nil {
+		t.Error("no <main> landmark")
+	}
+	for tbl := range elems(body, atom.Table) {
+		checkTable(t, tbl)
+	}
+}
+
+func checkTable(t *testing.T, tbl *html.Node) {
+	t.Helper()
+	id := attrVal(tbl, "id")
+	sawBody := false
+	for i, c := 0, firstChildElem(tbl); c != nil; i, c = i+1, nextElem(c) {
+		switch c.DataAtom {
+		case atom.Caption:
+			if i != 0 {
+				t.Errorf("table %q: <caption> isn't its first child", id)
+			}
+		case atom.Thead:
+			if sawBody {
+				t.Errorf("table %q: <thead> after <tbody>", id)
+			}
+			for row := firstChildElem(c); row != nil; row = nextElem(row) {
+				for cell := firstChildElem(row); cell != nil; cell = nextElem(cell) {
+					if cell.DataAtom != atom.Th {
+						t.Errorf("table %q: <%s> in <thead>", id, cell.Data)
+					} else if attrVal(cell, "scope") != "col" {
+						t.Errorf("table %q: column header without scope=col", id)
+					}
+				}
+			}
+		case atom.Tbody:
+			sawBody = true
+			for row := firstChildElem(c); row != nil; row = nextElem(row) {
+				for cell := firstChildElem(row); cell != nil; cell = nextElem(cell) {
+					if cell.DataAtom == atom.Th && attrVal(cell, "scope") == "" {
+						t.Errorf("table %q: header cell without scope", id)
+					}
+				}
+			}
+		default:
+			t.Errorf("table %q: <%s> directly within <table>", id, c.Data)
+		}
+	}
+	if id == "" {
+		t.Error("table without an id")
+	}
+	if attrVal(tbl, "aria-label") == "" {
+		t.Errorf("table %q has no aria-label", id)
+	}
+}
+
+func attrVal(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// elems yields the elements of type a within n, in document order
+func elems(n *html.Node, a atom.Atom) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for d := range n.Descendants() {
+			if d.Type == html.ElementNode && d.DataAtom == a && !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+func firstElem(n *html.Node, a atom.Atom) *html.Node {
+	for e := range elems(n, a) {
+		return e
+	}
+	return nil
+}
+
+func firstChildElem(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+func nextElem(n *html.Node) *html.Node {
+	for c := n.NextSibling; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package statuspage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strconv"
+)
+
+// jsonMember is a single key/value pair within a jsonObject
+type jsonMember struct {
+	Key string
+	Val any
+}
+
+// jsonObject is a JSON object that preserves the order of its members (unlike
+// a map[string]any, which encoding/json sorts), so struct fields come out in
+// declaration order.
+type jsonObject []jsonMember
+
+// MarshalJSON implements encoding/json.Marshaler
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	b := bytes.Buffer{}
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, kErr := json.Marshal(m.Key)
+		if kErr != nil {
+			return nil, fmt.Errorf("failed to marshal key %q: %w", m.Key, kErr)
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, vErr := json.Marshal(m.Val)
+		if vErr != nil {
+			return nil, fmt.Errorf("failed to marshal value for key %q: %w", m.Key, vErr)
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// jsonTruncatedKey is the key of the member marking where rendering stopped
+// due to a RenderLimits limit
+const jsonTruncatedKey = "$truncated"
+
+// jsonTruncated returns the marker rendered in place of a value (or after
+// the last element rendered) when limit l is reached.
+func jsonTruncated(l renderLimit) jsonObject {
+	return jsonObject{{Key: jsonTruncatedKey, Val: l.String()}}
+}
+
+// jsonKeyString returns the string used as an object key for the map key k,
+// and false if k has no natural string form (e.g. struct keys).
+func jsonKeyString(k reflect.Value) (string, bool) {
+	if eligibleStringer(k.Type()) && !(isNilableType(k.Kind()) && k.IsNil()) {
+		return k.Interface().(fmt.Stringer).String(), true
+	}
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(k.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(k.Float(), 'g', -1, k.Type().Bits()), true
+	case reflect.Interface:
+		if k.IsNil() {
+			return "", false
+		}
+		return jsonKeyString(k.Elem())
+	default:
+		return "", false
+	}
+}
+
+// jsonFloat returns a JSON-encodable representation of f. NaN and the
+// infinities have no JSON number form, so they're emitted as strings.
+func jsonFloat(f float64, bits int) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bits))
+}
+
+// jsonErrorKey holds the error for a value that failed to render
+const jsonErrorKey = "$error"
+
+// genJSONVal is the JSON counterpart to genValSection: it walks v following
+// the same rules and returns a value that encoding/json can marshal.
+// Failures are rendered inline, as {"$error": "..."} objects.
+//
+// Unlike the HTML and text renderers, aliased values are rendered in full
+// each time they appear; only cycles are broken, with a {"$ref": path}
+// object pointing at the enclosing occurrence.
+func (r *renderer) genJSONVal(v reflect.Value) (any, error) {
+	return isolate(r, func() (any, error) { return r.genJSONValue(v) },
+		func(msg string) any { return jsonObject{{Key: jsonErrorKey, Val: msg}} }), nil
+}
+
+// genJSONValue does the work for genJSONVal
+func (r *renderer) genJSONValue(v reflect.Value) (any, error) {
+	limit, leave := r.enterValue(v)
+	if limit != 0 {
+		return jsonTruncated(limit), nil
+	}
+	defer leave()
+	if key, trackable := visitKeyOf(v); trackable {
+		if enclosing, cycle := r.onStack[key]; cycle {
+			ref := enclosing.String()
+			if ref == "" {
+				ref = "/"
+			}
+			return jsonObject{{Key: "$ref", Val: ref}}, nil
+		}
+		r.onStack[key] = r.path
+		defer delete(r.onStack, key)
+	}
+	k := v.Kind()
+
+	// Nil values of any nilable kind are rendered as null (the HTML
+	// equivalent is the "(nil)" marker)
+	if isNilableType(k) && v.IsNil() {
+		return nil, nil
+	}
+	// If this type implements fmt.Stringer, delegate to that
+	// implementation.
+	if eligibleStringer(v.Type()) {
+		return v.Interface().(fmt.Stringer).String(), nil
+	}
+	switch k {
+	case reflect.Struct:
+		return r.genJSONStruct(v)
+	case reflect.Map:
+		if isSet(v.Type()) {
+			// sets are rendered as a list of their members, as they are in
+			// map values in the HTML tables
+			return r.genJSONSet(v)
+		}
+		return r.genJSONMapOrSeq2(v)
+	case reflect.Array, reflect.Slice:
+		return r.genJSONSeq(v)
+	case reflect.Pointer, reflect.Interface:
+		// Delegate after following the bouncing ball
+		return r.genJSONVal(v.Elem())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.UnsafePointer:
+		return json.Number(strconv.FormatUint(uint64(uintptr(v.UnsafePointer())), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return jsonFloat(v.Float(), v.Type().Bits()), nil
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), nil
+	case reflect.String:
+		r.spendBytes(len(v.String()))
+		return v.String(), nil
+	case reflect.Chan:
+		return jsonObject{{Key: "len", Val: v.Len()}, {Key: "cap", Val: v.Cap()}}, nil
+	case reflect.Func:
+		if v.Type().CanSeq2() {
+			return r.genJSONMapOrSeq2(v)
+		} else if v.Type().CanSeq() {
+			return r.genJSONSeq(v)
+		}
+		fnPtr := uintptr(v.UnsafePointer())
+		return v.Type().String() + "(0x" + strconv.FormatUint(uint64(fnPtr), 16) + "): " + runtime.FuncForPC(fnPtr).Name(), nil
+	default:
+		return nil, fmt.Errorf("unhandled kind %s (type %s)", k, v.Type())
+	}
+}
+
+func (r *renderer) genJSONStruct(v reflect.Value) (jsonObject, error) {
+	fields, fieldsErr := renderableFields(v)
+	if fieldsErr != nil {
+		return nil, fieldsErr
+	}
+	out := make(jsonObject, 0, len(fields))
+	for _, f := range fields {
+		ascend := r.enterField(&f)
+		fv, fErr := r.genJSONVal(v.FieldByIndex(f.Index))
+		ascend()
+		if fErr != nil {
+			return nil, fmt.Errorf("failed to render field %q: %w", f.Name, fErr)
+		}
+		out = append(out, jsonMember{Key: f.Name, Val: fv})
+	}
+	return out, nil
+}
+
+// genJSONMapOrSeq2 renders maps and iter.Seq2 values. If every key has a
+// distinct natural string form the result is an object; otherwise it's an
+// array of {"key": ..., "value": ...} objects so no entries are lost.
+func (r *renderer) genJSONMapOrSeq2(v reflect.Value) (any, error) {
+	type entry struct {
+		key, val any
+		keyStr   string
+	}
+	entries := []entry{}
+	seenKeys := map[string]struct{}{}
+	asObject := true
+	truncated := renderLimit(0)
+	for ik, iv := range r.opts.mapEntries(v) {
+		if truncated = r.exhausted(); truncated != 0 {
+			break
+		}
+		kv, kErr := r.genJSONVal(ik)
+		if kErr != nil {
+			return nil, fmt.Errorf("failed to render key of type %s: %w", ik.Type(), kErr)
+		}
+		ascend := r.descend(keyStep(ik))
+		vv, vErr := r.genJSONVal(iv)
+		ascend()
+		if vErr != nil {
+			return nil, fmt.Errorf("failed to render value for key %v: %w", kv, vErr)
+		}
+		e := entry{key: kv, val: vv}
+		if asObject {
+			ks, ok := jsonKeyString(ik)
+			if _, dup := seenKeys[ks]; !ok || dup {
+				asObject = false
+			}
+			seenKeys[ks] = struct{}{}
+			e.keyStr = ks
+		}
+		entries = append(entries, e)
+	}
+	if asObject {
+		out := make(jsonObject, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, jsonMember{Key: e.keyStr, Val: e.val})
+		}
+		if truncated != 0 {
+			out = append(out, jsonMember{Key: jsonTruncatedKey, Val: truncated.String()})
+		}
+		return out, nil
+	}
+	out := make([]any, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, jsonObject{{Key: "key", Val: e.key}, {Key: "value", Val: e.val}})
+	}
+	if truncated != 0 {
+		out = append(out, jsonTruncated(truncated))
+	}
+	return out, nil
+}
+
+// genJSONSeq renders arrays, slices and iter.Seq values as JSON arrays
+func (r *renderer) genJSONSeq(v reflect.Value) ([]any, error) {
+	out := []any{}
+	for ev := range seqElems(v) {
+		if l := r.exhausted(); l != 0 {
+			out = append(out, jsonTruncated(l))
+			break
+		}
+		ascend := r.descend(indexStep(len(out)))
+		jv, jErr := r.genJSONVal(ev)
+		ascend()
+		if jErr != nil {
+			return nil, fmt.Errorf("failed to render element %d of %s: %w", len(out), v.Type(), jErr)
+		}
+		out = append(out, jv)
+	}
+	return out, nil
+}
+
+// genJSONSet renders a map[K]struct{} as an array of its keys
+func (r *renderer) genJSONSet(v reflect.Value) ([]any, error) {
+	out := make([]any, 0, v.Len())
+	for ik := range r.opts.mapKeys(v) {
+		if l := r.exhausted(); l != 0 {
+			out = append(out, jsonTruncated(l))
+			break
+		}
+		jv, jErr := r.genJSONVal(ik)
+		if jErr != nil {
+			return nil, fmt.Errorf("failed to render member of %s: %w", v.Type(), jErr)
+		}
+		out = append(out, jv)
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package statuspage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"iter"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	statuspage "github.com/vimeo/go-status-page"
+)
+
+type jsonVal struct {
+	I       int
+	F       float64
+	NaN     float64
+	S       string `statuspage:"name=Ess"`
+	Skipped int    `statuspage:"-"`
+	hidden  int
+	IP      net.IP
+	Nil     *int
+	NilMap  map[string]int
+	M       map[string]int
+	Set     map[string]struct{}
+	Keys    map[key]int
+	Seq     iter.Seq[int]
+	Seq2    iter.Seq2[string, int]
+	D       time.Duration
+	Inner   elem
+	Shared  []*elem
+	Cycle   *node
+}
+
+func TestJSON(t *testing.T) {
+	shared := &elem{A: 1}
+	cycle := &node{Name: "a"}
+	cycle.Next = cycle
+	v := jsonVal{
+		I: 1, F: 1.5, NaN: math.NaN(), S: "s", Skipped: 1, hidden: 1,
+		IP:     net.IPv4(1, 2, 3, 4),
+		M:      map[string]int{"b": 2, "a": 1},
+		Set:    map[string]struct{}{"y": {}, "x": {}},
+		Keys:   map[key]int{{"us", 1}: 3},
+		Seq:    func(yield func(int) bool) { _ = yield(1) && yield(2) },
+		Seq2:   func(yield func(string, int) bool) { yield("a", 1) },
+		D:      time.Second,
+		Inner:  elem{B: "b"},
+		Shared: []*elem{shared, shared, nil},
+		Cycle:  cycle,
+	}
+	got := serveStatus(t, v, "?format=json")
+	want := `{
+		"I": 1, "F": 1.5, "NaN": "NaN", "S": "s", "IP": "1.2.3.4", "Nil": null, "NilMap": null,
+		"M": {"a": 1, "b": 2},
+		"Set": ["x", "y"],
+		"Keys": [{"key": {"Region": "us", "Zone": 1}, "value": 3}],
+		"Seq": [1, 2],
+		"Seq2": {"a": 1},
+		"D": "1s",
+		"Inner": {"A": 0, "B": "b"},
+		"Shared": [{"A": 1, "B": ""}, {"A": 1, "B": ""}, null],
+		"Cycle": {"Name": "a", "Next": {"$ref": "/Cycle"}}
+	}`
+	wantBuf := bytes.Buffer{}
+	if err := json.Compact(&wantBuf, []byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	gotBuf := bytes.Buffer{}
+	if err := json.Compact(&gotBuf, []byte(got)); err != nil {
+		t.Fatalf("invalid JSON: %s\n%s", err, got)
+	}
+	if gotBuf.String() != wantBuf.String() {
+		t.Errorf("JSON:\n%s\nwant:\n%s", gotBuf.String(), wantBuf.String())
+	}
+}
+
+func TestJSONAccept(t *testing.T) {
+	s := statuspage.New("Test", func() elem { return elem{A: 1} })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q; want JSON", ct)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %s\n%s", err, rec.Body)
+	}
+	if got["A"] != 1.0 || got["B"] != "" {
+		t.Errorf("JSON = %v; want A 1 and B empty", got)
+	}
+}
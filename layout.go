@@ -0,0 +1,294 @@
+package statuspage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LayoutData is what layouts (see WithLayout) are executed with
+type LayoutData struct {
+	// Title is the page's title (including the path of sub-pages)
+	Title string
+	// Head holds the elements the content needs in the page's <head>:
+	// the stylesheets and scripts
+	Head template.HTML
+	// Nav holds the page's navigation: the snapshot timeline (with
+	// WithHistory) and the breadcrumbs (on sub-pages)
+	Nav template.HTML
+	// Contents is the table of contents linking to the page's sections
+	// (empty if there are none)
+	Contents template.HTML
+	// Body is the rendered content of the page. Layouts placing some of
+	// its Sections elsewhere should use BodyWithout instead.
+	Body template.HTML
+	// Sections holds the sections of the body rendering the fields that
+	// get sections of their own (those needing tables), by field name, so
+	// they can be placed individually
+	Sections map[string]template.HTML
+	// Value is the value rendered (T, or the value at the sub-page's
+	// path), for rendering its fields individually with statusTable (see
+	// FuncMap). It's nil on error pages.
+	Value any
+	// Meta describes the render
+	Meta LayoutMeta
+
+	// body holds the parts Body is made of, for BodyWithout
+	body layoutBody
+}
+
+// layoutBody is the Body of a LayoutData, in parts
+type layoutBody struct {
+	// open and close are the tags of the element holding the body
+	open, close template.HTML
+	parts       []layoutPart
+}
+
+// layoutPart is a part of a layout's Body: a section (named for its field,
+// if it's in Sections), or the notes or footer around them (unnamed).
+type layoutPart struct {
+	name string
+	html template.HTML
+}
+
+func (b *layoutBody) render(without ...string) template.HTML {
+	s := strings.Builder{}
+	s.WriteString(string(b.open))
+	for _, p := range b.parts {
+		if p.name != "" && slices.Contains(without, p.name) {
+			continue
+		}
+		s.WriteString(string(p.html))
+	}
+	s.WriteString(string(b.close))
+	return template.HTML(s.String())
+}
+
+// BodyWithout returns the Body without the named Sections, for layouts
+// placing them elsewhere (e.g. {{.BodyWithout "Backends"}}), so they aren't
+// on the page twice.
+func (d *LayoutData) BodyWithout(names ...string) template.HTML {
+	return d.body.render(names...)
+}
+
+// LayoutMeta describes the render of a page passed to a layout
+type LayoutMeta struct {
+	// Timestamp is when the value rendered was loaded
+	Timestamp time.Time
+	// Duration is how long the value took to render
+	Duration time.Duration
+	// Errors holds the messages for the values that failed to render (or
+	// for the failure to load the value, on error pages)
+	Errors []string
+}
+
+// renderHTML renders ns as HTML for a template
+func renderHTML(ns ...*html.Node) (template.HTML, error) {
+	b := strings.Builder{}
+	for _, n := range ns {
+		if renderErr := html.Render(&b, n); renderErr != nil {
+			return "", renderErr
+		}
+	}
+	return template.HTML(b.String()), nil
+}
+
+// layoutData returns the data for a layout rendering the content held by
+// page (which the head elements are set up for), apart from the Body and
+// Sections.
+func (r *renderer) layoutData(page *html.Node) (*LayoutData, error) {
+	head := createElemAtom(atom.Head)
+	r.addHeadElems(head, page)
+	d := &LayoutData{Title: r.pageTitle()}
+	headElems := []*html.Node{}
+	for n := range head.ChildNodes() {
+		headElems = append(headElems, n)
+	}
+	var renderErr error
+	if d.Head, renderErr = renderHTML(headElems...); renderErr != nil {
+		return nil, renderErr
+	}
+	if d.Nav, renderErr = renderHTML(r.pageNavs()...); renderErr != nil {
+		return nil, renderErr
+	}
+	return d, nil
+}
+
+// genLayoutData renders v into the data for a layout
+func (r *renderer) genLayoutData(v reflect.Value) (*LayoutData, error) {
+	start := time.Now()
+	ps, genErr := r.genPageSections(v)
+	if genErr != nil {
+		return nil, genErr
+	}
+	page := createElemClass(atom.Div, "sp-page")
+	d, dataErr := r.layoutData(page)
+	if dataErr != nil {
+		return nil, dataErr
+	}
+	if r.opts.liveInterval > 0 {
+		// stamp the sections before they're rendered
+		upd, stampErr := ps.stamp()
+		if stampErr != nil {
+			return nil, stampErr
+		}
+		setAttr(page, liveHashAttr, upd.Hash)
+	}
+	// the element holding the body is rendered without its children, to
+	// split it into its tags
+	tags, renderErr := renderHTML(page)
+	if renderErr != nil {
+		return nil, renderErr
+	}
+	d.body.close = template.HTML("</" + page.Data + ">")
+	d.body.open = template.HTML(strings.TrimSuffix(string(tags), string(d.body.close)))
+	addPart := func(name string, ns ...*html.Node) error {
+		h, renderErr := renderHTML(ns...)
+		d.body.parts = append(d.body.parts, layoutPart{name: name, html: h})
+		return renderErr
+	}
+	d.Sections = map[string]template.HTML{}
+	for _, n := range []*html.Node{ps.notes, ps.summary} {
+		if n == nil {
+			continue
+		}
+		if partErr := addPart("", n); partErr != nil {
+			return nil, partErr
+		}
+	}
+	for _, sec := range ps.values {
+		name := ""
+		if e, ok := tocEntryOf(sec); ok {
+			name = e.label
+		}
+		if partErr := addPart(name, createElemAtom(atom.Hr), sec); partErr != nil {
+			return nil, partErr
+		}
+		if name != "" {
+			sh, _ := renderHTML(sec)
+			d.Sections[name] = sh
+		}
+	}
+	if ps.footer != nil {
+		if partErr := addPart("", ps.footer); partErr != nil {
+			return nil, partErr
+		}
+	}
+	if toc := genTOC(ps.values); toc != nil {
+		h, renderErr := renderHTML(toc)
+		if renderErr != nil {
+			return nil, renderErr
+		}
+		d.Contents = h
+	}
+	d.Body = d.body.render()
+	if v.CanInterface() {
+		d.Value = v.Interface()
+	}
+	d.Meta = LayoutMeta{Timestamp: r.snapshotAt, Duration: time.Since(start), Errors: r.errors}
+	if d.Meta.Timestamp.IsZero() {
+		// the value was loaded for this request
+		d.Meta.Timestamp = start
+	}
+	return d, nil
+}
+
+// serveLayout renders v through the layout
+func serveLayout(w http.ResponseWriter, rn *renderer, v reflect.Value) {
+	d, genErr := rn.genLayoutData(v)
+	if genErr != nil {
+		http.Error(w, fmt.Sprintf("failed to generate HTML for struct of type %s: %s", v.Type(), genErr), 500)
+		return
+	}
+	b := bytes.Buffer{}
+	if execErr := rn.opts.layout.Execute(&b, d); execErr != nil {
+		http.Error(w, fmt.Sprintf("failed to execute layout for struct of type %s: %s", v.Type(), execErr), 500)
+		return
+	}
+	setTruncatedHeader(w, rn)
+	setErrorsHeader(w, rn)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// serveLayoutError responds with err (under the heading heading) and the
+// status code, rendered through the layout.
+func serveLayoutError(w http.ResponseWriter, rn *renderer, code int, heading string, err error) {
+	page := createElemClass(atom.Div, "sp-page")
+	h := createElemClass(atom.H2, "sp-error")
+	h.AppendChild(textNode(heading))
+	page.AppendChild(h)
+	msg := createElemClass(atom.Pre, "sp-error")
+	msg.AppendChild(textNode(err.Error()))
+	page.AppendChild(msg)
+
+	d, dataErr := rn.layoutData(page)
+	if dataErr == nil {
+		d.Body, dataErr = renderHTML(page)
+	}
+	if dataErr != nil {
+		http.Error(w, fmt.Sprintf("%s: %s", heading, err), code)
+		return
+	}
+	d.body.parts = []layoutPart{{html: d.Body}}
+	d.Meta = LayoutMeta{Timestamp: time.Now(), Errors: []string{heading + ": " + err.Error()}}
+	b := bytes.Buffer{}
+	if execErr := rn.opts.layout.Execute(&b, d); execErr != nil {
+		http.Error(w, fmt.Sprintf("%s: %s (and failed to execute layout: %s)", heading, err, execErr), code)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(b.Bytes())
+}
+
+// FuncMap returns functions for rendering values within html/template
+// templates, be they layouts (see WithLayout) or existing pages:
+//
+//   - statusTable renders a value as it's rendered on status pages (e.g.
+//     {{statusTable .Backends}})
+//   - statusStylesheet returns the default stylesheet, for pages that don't
+//     include LayoutData.Head (e.g. <style>{{statusStylesheet}}</style>)
+//
+// The options configure rendering as they do for New (those that only
+// apply to serving pages are ignored). Tables rendered by statusTable don't
+// carry ids, since they may be placed alongside others, and can't link to
+// sub-pages, since they aren't served by a Status.
+func FuncMap(opts ...Option) template.FuncMap {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return template.FuncMap{
+		"statusTable": func(v any) (template.HTML, error) {
+			rv := reflect.ValueOf(v)
+			if !rv.IsValid() {
+				return "", nil
+			}
+			// render an addressable copy, so methods with pointer
+			// receivers are found as they are on pages
+			addr := reflect.New(rv.Type()).Elem()
+			addr.Set(rv)
+			rn := newRenderer(&o, "")
+			ns, genErr := rn.genValSection(addr)
+			if genErr != nil {
+				return "", genErr
+			}
+			for _, n := range ns {
+				stripIDs(n)
+			}
+			return renderHTML(ns...)
+		},
+		"statusStylesheet": func() template.CSS {
+			return template.CSS(staticAssets[defaultStylesheetName].content)
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package statuspage_test
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	statuspage "github.com/vimeo/go-status-page"
+)
+
+type layoutVal struct {
+	Name     string
+	Backends map[string]elem
+	Tags     []string
+	Panics   panicky
+}
+
+const layoutTmpl = `<!DOCTYPE html><html><head><title>{{.Title}} · Acme</title>{{.Head}}</head>
+<body><nav class="acme">runbooks</nav>{{.Nav}}{{.Contents}}
+<main>{{.BodyWithout "Backends"}}</main>
+<aside>{{.Sections.Backends}}</aside>
+{{with .Value}}<div class="extra">{{statusTable .Tags}}</div>{{end}}
+<footer>{{len .Meta.Errors}} errors, at {{not .Meta.Timestamp.IsZero}}</footer></body></html>`
+
+func layoutStatus(t *testing.T, tmpl string) *statuspage.Status[layoutVal] {
+	t.Helper()
+	layout := template.Must(template.New("layout").Funcs(statuspage.FuncMap()).Parse(tmpl))
+	return statuspage.New("Test", func() layoutVal {
+		return layoutVal{Name: "x", Backends: map[string]elem{"a": {A: 1}}, Tags: []string{"t"}}
+	}, statuspage.WithLayout(layout), statuspage.WithBasePath("/status"))
+}
+
+func TestLayout(t *testing.T) {
+	rec := httptest.NewRecorder()
+	layoutStatus(t, layoutTmpl).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+	checkTokens(t, page)
+	for _, want := range []string{
+		"<title>Test · Acme</title>",
+		`<link rel="stylesheet" href="/status/_static/statuspage.css?v=`,
+		`<nav class="acme">runbooks</nav>`,
+		`<nav class="sp-toc" aria-label="Contents">`,
+		`<main><div class="sp-page">`,
+		`<aside><div class="sp-section" id="sec/Backends">`,
+		`<div class="extra"><table class="sp-table sp-slice"`,
+		// Panics fails to render
+		"<footer>1 errors, at true</footer>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, page)
+		}
+	}
+	// the section placed on its own isn't in the body too
+	if n := strings.Count(page, `id="sec/Backends"`); n != 1 {
+		t.Errorf("Backends is on the page %d times; want 1:\n%s", n, page)
+	}
+	if !strings.Contains(page, `id="sec/Tags"`) {
+		t.Errorf("the sections left in the body aren't rendered:\n%s", page)
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	layout := template.Must(template.New("layout").Parse(
+		`<html><head><title>{{.Title}}</title></head><body>{{.Body}}<footer>{{index .Meta.Errors 0}}</footer></body></html>`))
+	s := statuspage.NewCtx("Test", func(context.Context) (layoutVal, error) {
+		return layoutVal{}, errors.New("no backends")
+	}, statuspage.WithLayout(layout))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d; want 503", rec.Code)
+	}
+	for _, want := range []string{
+		`<h2 class="sp-error">Failed to load status</h2><pre class="sp-error">no backends</pre>`,
+		"<footer>Failed to load status: no backends</footer>",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("error page doesn't contain %q:\n%s", want, rec.Body)
+		}
+	}
+
+	// layouts that fail to execute fail the request
+	rec = httptest.NewRecorder()
+	layoutStatus(t, `{{.NoSuchField}}`).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "failed to execute layout") {
+		t.Errorf("broken layout: %d: %s; want a 500", rec.Code, rec.Body)
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(statuspage.FuncMap()).Parse(
+		`<style>{{statusStylesheet}}</style>{{statusTable .Elem}}|{{statusTable .Num}}|{{statusTable .Nil}}|`))
+	b := strings.Builder{}
+	err := tmpl.Execute(&b, map[string]any{"Elem": &elem{A: 1, B: "b"}, "Num": 2, "Nil": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"<style>/* Default stylesheet",
+		`<table class="sp-table sp-struct"`,
+		`<span class="sp-str">b</span>`,
+		`|<span class="sp-num">2 (0x2)</span>||`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	// the tables may be placed alongside others, so they don't carry ids
+	if strings.Contains(out, " id=") {
+		t.Errorf("statusTable output has ids:\n%s", out)
+	}
+}
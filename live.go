@@ -0,0 +1,345 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// liveQueryParam selects one of the live update endpoints (see
+// WithLiveUpdates): "sse" for a Server-Sent Events stream, or "poll" for a
+// long poll.
+const liveQueryParam = "live"
+
+// liveHashQueryParam holds the hash of the page the live update endpoints
+// are updating, so they only respond once it's changed.
+const liveHashQueryParam = "hash"
+
+// Attributes holding the hashes of live pages and their sections
+const (
+	liveHashAttr    = "data-live-hash"
+	sectionHashAttr = "data-hash"
+)
+
+// livePollTimeout is how long a long poll waits for the page to change
+// before responding with a 204 (No Content), so intermediaries don't time it
+// out.
+const livePollTimeout = 30 * time.Second
+
+// liveSection is the rendering of one of a page's sections sent to live
+// pages. HTML is omitted for sections the page already has.
+type liveSection struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
+	HTML string `json:"html,omitempty"`
+}
+
+// liveUpdate is the payload of a live update event (or long poll response).
+// Sections lists every section on the page, in order, so the page knows to
+// reload if sections have come or gone.
+type liveUpdate struct {
+	Hash     string        `json:"hash"`
+	Sections []liveSection `json:"sections"`
+}
+
+// sectionHash returns the hash identifying the content of the section n
+func sectionHash(n *html.Node) (string, error) {
+	h := fnv.New64a()
+	if err := html.Render(h, n); err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(h.Sum64(), 36), nil
+}
+
+// pageHash combines the hashes of a page's sections
+func pageHash(secs []liveSection) string {
+	h := fnv.New64a()
+	for _, sec := range secs {
+		io.WriteString(h, sec.ID+"\x00"+sec.Hash+"\x00")
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// stamp sets the hash attribute on each of ps's sections, and returns their
+// renderings along with the page's hash.
+func (ps *pageSections) stamp() (*liveUpdate, error) {
+	upd := &liveUpdate{}
+	for _, n := range ps.all() {
+		// sections are hashed before they're stamped, so the hash
+		// doesn't depend on itself
+		h, hashErr := sectionHash(n)
+		if hashErr != nil {
+			return nil, hashErr
+		}
+		setAttr(n, sectionHashAttr, h)
+		id, _ := attr(n, atom.Id.String())
+		b := bytes.Buffer{}
+		if renderErr := html.Render(&b, n); renderErr != nil {
+			return nil, renderErr
+		}
+		upd.Sections = append(upd.Sections, liveSection{ID: id, Hash: h, HTML: b.String()})
+	}
+	upd.Hash = pageHash(upd.Sections)
+	return upd, nil
+}
+
+// liveScript swaps in the sections of live updates. It streams them over
+// Server-Sent Events, falling back to long polling if nothing arrives on the
+// stream in time (e.g. because a proxy buffers it), and reloads the page if
+// its sections come or go. Its settings are read from the element holding
+// the page's content (the body, unless a layout is used). Layouts may place
+// sections on their own, so sections are matched by id, wherever they are.
+const liveScript = `document.addEventListener("DOMContentLoaded", function () {
+	var body = document.querySelector("[data-live-interval]");
+	if (!body) {
+		return;
+	}
+	var interval = +body.dataset.liveInterval;
+	function idSet(ids) {
+		return ids.filter(function (id, i) { return ids.indexOf(id) === i; }).sort().join(" ");
+	}
+	function sectionIDs() {
+		return idSet(Array.prototype.map.call(document.querySelectorAll("[data-hash]"), function (el) { return el.id; }));
+	}
+	function apply(update) {
+		if (idSet(update.sections.map(function (s) { return s.id; })) !== sectionIDs()) {
+			location.reload();
+			return;
+		}
+		update.sections.forEach(function (s) {
+			var el = document.getElementById(s.id);
+			if (!s.html || el.dataset.hash === s.hash) {
+				return;
+			}
+			var tmpl = document.createElement("template");
+			tmpl.innerHTML = s.html;
+			el.replaceWith(tmpl.content);
+		});
+		body.dataset.liveHash = update.hash;
+	}
+	function liveURL(mode) {
+		var u = new URL(location.href);
+		u.hash = "";
+		u.searchParams.set("live", mode);
+		u.searchParams.set("hash", body.dataset.liveHash || "");
+		return u;
+	}
+	function poll() {
+		fetch(liveURL("poll")).then(function (resp) {
+			if (resp.status === 200) {
+				return resp.json().then(apply);
+			}
+			if (resp.status !== 204) {
+				throw new Error(resp.statusText);
+			}
+		}).then(poll, function () { setTimeout(poll, Math.max(interval, 5000)); });
+	}
+	if (!window.EventSource) {
+		poll();
+		return;
+	}
+	var es = new EventSource(liveURL("sse"));
+	var fallback = setTimeout(function () { es.close(); poll(); }, 2 * interval + 5000);
+	es.addEventListener("update", function (e) {
+		clearTimeout(fallback);
+		apply(JSON.parse(e.data));
+	});
+});
+`
+
+// addLiveScript sets up the live update script on a page, if live updates
+// are enabled. body is the element holding the page's content.
+func (r *renderer) addLiveScript(head, body *html.Node) {
+	if r.opts.liveInterval <= 0 {
+		return
+	}
+	setAttr(body, "data-live-interval", strconv.FormatInt(r.opts.liveInterval.Milliseconds(), 10))
+	script := createElemAtom(atom.Script)
+	script.AppendChild(textNode(liveScript))
+	head.AppendChild(script)
+}
+
+// errBusy is returned by liveRender when all render slots are in use
+var errBusy = errors.New("too many status page renders in progress")
+
+// liveRender renders the page at steps (as requested by r) for a live update.
+func (s *Status[T]) liveRender(r *http.Request, steps []rawStep) (*liveUpdate, error) {
+	basePath, _, subPages := s.requestPaths(r)
+	snap, loadErr := s.snapshot(r.Context())
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	release, acquired := s.acquireRender()
+	if !acquired {
+		return nil, errBusy
+	}
+	defer release()
+	target, path, resolveErr := resolvePath(reflect.ValueOf(&snap.v).Elem(), steps)
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	rn := s.newRenderer(basePath, subPages, path)
+	rn.query = pageQueryValues(r.URL.Query())
+	if hv, checksHealth := s.healthVerdict(snap); checksHealth {
+		rn.health = hv
+	}
+	if s.opts.cacheTTL > 0 {
+		rn.snapshotAt = snap.loadedAt
+	}
+	ps, genErr := rn.genPageSections(target)
+	if genErr != nil {
+		return nil, genErr
+	}
+	return ps.stamp()
+}
+
+// pageQueryValues returns the query parameters of a live update request
+// that belong to the page being updated.
+func pageQueryValues(q url.Values) url.Values {
+	q = maps.Clone(q)
+	q.Del(liveQueryParam)
+	q.Del(liveHashQueryParam)
+	return q
+}
+
+// failedUpdate returns last with its notes replaced by a note that loading
+// the status failed with err, so live pages keep their last good rendering
+// but flag that it's stale.
+func failedUpdate(last *liveUpdate, err error) (*liveUpdate, error) {
+	notes := createElemClass(atom.Div, "sp-notes")
+	setAttr(notes, atom.Id.String(), notesSectionID)
+	p := createElemAtom(atom.P)
+	strong := createElemAtom(atom.Strong)
+	strong.AppendChild(textNode(errorMarker + errorMessage + ": " + err.Error()))
+	p.AppendChild(strong)
+	notes.AppendChild(p)
+	ps := pageSections{notes: notes}
+	upd, stampErr := ps.stamp()
+	if stampErr != nil {
+		return nil, stampErr
+	}
+	secs := slices.Clone(last.Sections)
+	for i, sec := range secs {
+		if sec.ID == notesSectionID {
+			secs[i] = upd.Sections[0]
+		}
+	}
+	return &liveUpdate{Hash: pageHash(secs), Sections: secs}, nil
+}
+
+// serveLive serves the live update endpoints for the page at steps.
+func (s *Status[T]) serveLive(w http.ResponseWriter, r *http.Request, mode string, steps []rawStep) {
+	switch mode {
+	case "sse":
+		s.serveLiveSSE(w, r, steps)
+	case "poll":
+		s.serveLivePoll(w, r, steps)
+	default:
+		http.Error(w, fmt.Sprintf("unknown %s mode %q (expected sse or poll)", liveQueryParam, mode), http.StatusBadRequest)
+	}
+}
+
+// serveLiveSSE streams updates to the page at steps as Server-Sent Events,
+// re-rendering it every live interval. Each "update" event holds a
+// liveUpdate in which only the sections that changed since the previous
+// event carry their HTML.
+func (s *Status[T]) serveLiveSSE(w http.ResponseWriter, r *http.Request, steps []rawStep) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// ask nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if flushErr := rc.Flush(); flushErr != nil {
+		// The page will fall back to long polling
+		return
+	}
+
+	hash := r.URL.Query().Get(liveHashQueryParam)
+	sent := map[string]string{}
+	var last *liveUpdate
+	ticker := time.NewTicker(s.opts.liveInterval)
+	defer ticker.Stop()
+	for {
+		upd, renderErr := s.liveRender(r, steps)
+		switch {
+		case errors.Is(renderErr, errBusy) || errors.Is(renderErr, context.Canceled):
+			// try again next time
+		case renderErr != nil && last != nil:
+			upd, renderErr = failedUpdate(last, renderErr)
+		}
+		if renderErr == nil && upd.Hash != hash {
+			last, hash = upd, upd.Hash
+			out := liveUpdate{Hash: upd.Hash, Sections: make([]liveSection, 0, len(upd.Sections))}
+			for _, sec := range upd.Sections {
+				if sent[sec.ID] == sec.Hash {
+					sec.HTML = ""
+				}
+				sent[sec.ID] = sec.Hash
+				out.Sections = append(out.Sections, sec)
+			}
+			b, _ := json.Marshal(out)
+			if _, wErr := fmt.Fprintf(w, "event: update\ndata: %s\n\n", b); wErr != nil {
+				return
+			}
+			if flushErr := rc.Flush(); flushErr != nil {
+				return
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveLivePoll responds with a liveUpdate once the page at steps no longer
+// matches the hash in the request, re-rendering it every live interval. If
+// it hasn't changed within livePollTimeout, the response is a 204.
+func (s *Status[T]) serveLivePoll(w http.ResponseWriter, r *http.Request, steps []rawStep) {
+	hash := r.URL.Query().Get(liveHashQueryParam)
+	timeout := time.NewTimer(livePollTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(s.opts.liveInterval)
+	defer ticker.Stop()
+	for {
+		upd, renderErr := s.liveRender(r, steps)
+		if r.Context().Err() != nil {
+			// the page stopped waiting
+			return
+		}
+		if renderErr != nil && !errors.Is(renderErr, errBusy) {
+			serveError(w, s.newRenderer("", false, nil), formatJSON, http.StatusServiceUnavailable, renderErr)
+			return
+		}
+		if renderErr == nil && upd.Hash != hash {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-cache")
+			json.NewEncoder(w).Encode(upd)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package statuspage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type liveVal struct {
+	A int
+	B []int
+}
+
+// liveStatus returns a Status whose value has an A of a's value, failing
+// while a is negative
+func liveStatus(a *atomic.Int64) *Status[liveVal] {
+	return NewCtx("Test", func(context.Context) (liveVal, error) {
+		n := a.Load()
+		if n < 0 {
+			return liveVal{}, errors.New("backend down")
+		}
+		return liveVal{A: int(n), B: []int{2}}, nil
+	}, WithLiveUpdates(5*time.Millisecond))
+}
+
+func getLive(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+// decodeUpdate decodes a long poll's liveUpdate
+func decodeUpdate(t *testing.T, rec *httptest.ResponseRecorder) liveUpdate {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	upd := liveUpdate{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &upd); err != nil {
+		t.Fatalf("decoding update: %s:\n%s", err, rec.Body)
+	}
+	return upd
+}
+
+// sectionHashes maps the ids of upd's sections to their hashes
+func sectionHashes(upd liveUpdate) map[string]string {
+	out := map[string]string{}
+	for _, sec := range upd.Sections {
+		out[sec.ID] = sec.Hash
+	}
+	return out
+}
+
+func TestLivePage(t *testing.T) {
+	a := &atomic.Int64{}
+	a.Store(1)
+	s := liveStatus(a)
+	page := getLive(t, s, "/")
+	doc, parseErr := html.Parse(page.Body)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	body := findElem(doc, "body")
+	if got, _ := attr(body, "data-live-interval"); got != "5" {
+		t.Errorf("data-live-interval = %q; want 5", got)
+	}
+	pageHashAttr, _ := attr(body, liveHashAttr)
+	onPage := map[string]string{}
+	for d := range doc.Descendants() {
+		if h, ok := attr(d, sectionHashAttr); ok {
+			id, _ := attr(d, "id")
+			onPage[id] = h
+		}
+	}
+
+	// the page's hashes match those of the live updates, so the updates
+	// only carry what's changed since it loaded
+	upd := decodeUpdate(t, getLive(t, s, "/?live=poll"))
+	if upd.Hash != pageHashAttr {
+		t.Errorf("poll hash %q; want the page's %q", upd.Hash, pageHashAttr)
+	}
+	if got := sectionHashes(upd); len(got) != 3 || !maps.Equal(got, onPage) {
+		t.Errorf("update's sections = %v; want the page's %v", got, onPage)
+	}
+
+	// without live updates, the parameter's ignored
+	plain := New("Test", func() liveVal { return liveVal{} })
+	if rec := getLive(t, plain, "/?live=poll"); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("?live=poll without live updates: status %d, Content-Type %q; want the page", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := getLive(t, s, "/?live=ws"); rec.Code != http.StatusBadRequest {
+		t.Errorf("?live=ws: status %d; want 400", rec.Code)
+	}
+}
+
+func findElem(n *html.Node, tag string) *html.Node {
+	for d := range n.Descendants() {
+		if d.Type == html.ElementNode && d.Data == tag {
+			return d
+		}
+	}
+	return nil
+}
+
+func TestLivePoll(t *testing.T) {
+	a := &atomic.Int64{}
+	a.Store(1)
+	s := liveStatus(a)
+	first := decodeUpdate(t, getLive(t, s, "/?live=poll"))
+
+	// an unchanged page holds the poll until it's given up on
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?live=poll&hash="+first.Hash, nil).WithContext(ctx))
+	if rec.Body.Len() != 0 {
+		t.Errorf("poll of an unchanged page responded with %s", rec.Body)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- getLive(t, s, "/?live=poll&hash="+first.Hash) }()
+	time.Sleep(10 * time.Millisecond)
+	a.Store(2)
+	upd := decodeUpdate(t, <-done)
+	if upd.Hash == first.Hash {
+		t.Fatalf("poll responded with the hash it was given")
+	}
+	before, after := sectionHashes(first), sectionHashes(upd)
+	if before["sec"] == after["sec"] {
+		t.Errorf("the changed section's hash didn't change")
+	}
+	if before["sec/B"] != after["sec/B"] || before[notesSectionID] != after[notesSectionID] {
+		t.Errorf("unchanged sections' hashes changed: %v -> %v", before, after)
+	}
+
+	a.Store(-1)
+	rec = getLive(t, s, "/?live=poll&hash="+upd.Hash)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "backend down") {
+		t.Errorf("poll of a failing status: %d: %s; want a 503 with the error", rec.Code, rec.Body)
+	}
+}
+
+// sseEvents returns the updates sent on the stream from the server at url
+func sseEvents(t *testing.T, ctx context.Context, url string) <-chan liveUpdate {
+	t.Helper()
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if reqErr != nil {
+		t.Fatal(reqErr)
+	}
+	resp, getErr := http.DefaultClient.Do(req)
+	if getErr != nil {
+		t.Fatal(getErr)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q; want text/event-stream", ct)
+	}
+	out := make(chan liveUpdate)
+	go func() {
+		defer resp.Body.Close()
+		defer close(out)
+		event := ""
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			line := sc.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok || event != "update" {
+				continue
+			}
+			upd := liveUpdate{}
+			if err := json.Unmarshal([]byte(data), &upd); err != nil {
+				t.Errorf("decoding event: %s: %s", err, data)
+				return
+			}
+			select {
+			case out <- upd:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func nextEvent(t *testing.T, events <-chan liveUpdate) liveUpdate {
+	t.Helper()
+	select {
+	case upd, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return upd
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event on the stream")
+	}
+	return liveUpdate{}
+}
+
+// withHTML returns the ids of the sections of upd that carry their HTML
+func withHTML(upd liveUpdate) []string {
+	ids := []string{}
+	for _, sec := range upd.Sections {
+		if sec.HTML != "" {
+			ids = append(ids, sec.ID)
+		}
+	}
+	return ids
+}
+
+func TestLiveSSE(t *testing.T) {
+	a := &atomic.Int64{}
+	a.Store(1)
+	srv := httptest.NewServer(liveStatus(a))
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := sseEvents(t, ctx, srv.URL+"/?live=sse")
+
+	first := nextEvent(t, events)
+	if got := strings.Join(withHTML(first), ","); got != "sp-notes,sec,sec/B" {
+		t.Errorf("first event carries %s; want every section", got)
+	}
+
+	a.Store(2)
+	second := nextEvent(t, events)
+	if got := strings.Join(withHTML(second), ","); got != "sec" {
+		t.Errorf("second event carries %s; want just the changed section", got)
+	}
+	if len(second.Sections) != 3 {
+		t.Errorf("second event lists %d sections; want 3", len(second.Sections))
+	}
+
+	// failures keep the last rendering, flagged in the notes
+	a.Store(-1)
+	third := nextEvent(t, events)
+	if got := strings.Join(withHTML(third), ","); got != notesSectionID {
+		t.Fatalf("failure event carries %s; want just the notes", got)
+	}
+	if notes := third.Sections[0].HTML; !strings.Contains(notes, "Failed to load status: backend down") {
+		t.Errorf("notes don't give the error: %s", notes)
+	}
+	if third.Sections[1].Hash != second.Sections[1].Hash {
+		t.Errorf("failure event changed the value's section")
+	}
+}
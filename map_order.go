@@ -0,0 +1,211 @@
+package statuspage
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MapOrder controls the order in which map entries are rendered
+type MapOrder int
+
+const (
+	// MapOrderSorted renders map entries sorted by key (the default), so the
+	// same map always renders the same way. See WithMapOrder for how keys
+	// are ordered.
+	MapOrderSorted MapOrder = iota
+	// MapOrderIteration renders map entries in Go's map iteration order,
+	// which is randomized and changes from one render to the next, but
+	// avoids the cost of sorting large maps.
+	MapOrderIteration
+)
+
+// WithMapOrder sets the order map entries are rendered in.
+//
+// With MapOrderSorted, keys with a Compare method (like time.Time) are
+// ordered by it, Stringers are ordered by their String() values, and
+// strings, numbers and bools are ordered naturally. Other keys (e.g. structs
+// and arrays) are ordered field by field (or element by element), so their
+// order is still stable.
+//
+// Go maps don't record the order entries were inserted in. Values that need
+// it should be exposed as an iter.Seq2, whose entries are always rendered in
+// the order they're yielded.
+func WithMapOrder(order MapOrder) Option {
+	return func(o *options) {
+		o.mapOrder = order
+	}
+}
+
+// WithMapCompare sets the comparison function used to order the keys of maps
+// of type M, overriding the MapOrder for that type. cmp should return a
+// negative number when a < b, a positive number when a > b and zero when
+// a == b, like the functions in the cmp package.
+//
+// e.g. WithMapCompare[map[string]int](func(a, b string) int { return cmp.Compare(len(a), len(b)) })
+func WithMapCompare[M ~map[K]V, K comparable, V any](cmp func(a, b K) int) Option {
+	return func(o *options) {
+		if o.mapCompare == nil {
+			o.mapCompare = map[reflect.Type]func(a, b reflect.Value) int{}
+		}
+		o.mapCompare[reflect.TypeFor[M]()] = func(a, b reflect.Value) int {
+			return cmp(a.Interface().(K), b.Interface().(K))
+		}
+	}
+}
+
+// keyCompare returns the function to order the keys of the map v by, or nil
+// if they should be left in iteration order.
+func (o *options) keyCompare(v reflect.Value) func(a, b reflect.Value) int {
+	if c, ok := o.mapCompare[v.Type()]; ok {
+		return c
+	}
+	if o.mapOrder == MapOrderIteration {
+		return nil
+	}
+	return func(a, b reflect.Value) int {
+		return compareKeys(a, b, maxKeyCompareDepth)
+	}
+}
+
+// mapEntries iterates over the entries of the map or iter.Seq2 v in the
+// configured order. (iter.Seq2 values are always iterated in their own order)
+func (o *options) mapEntries(v reflect.Value) iter.Seq2[reflect.Value, reflect.Value] {
+	if v.Kind() != reflect.Map {
+		return v.Seq2()
+	}
+	keyCmp := o.keyCompare(v)
+	if keyCmp == nil {
+		return v.Seq2()
+	}
+	return func(yield func(reflect.Value, reflect.Value) bool) {
+		type entry struct{ k, v reflect.Value }
+		// collect the values along with the keys, since NaN keys can't
+		// be looked up afterwards
+		entries := make([]entry, 0, v.Len())
+		it := v.MapRange()
+		for it.Next() {
+			entries = append(entries, entry{k: it.Key(), v: it.Value()})
+		}
+		slices.SortStableFunc(entries, func(a, b entry) int { return keyCmp(a.k, b.k) })
+		for _, e := range entries {
+			if !yield(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+// mapKeys iterates over the keys of the map v (usually a set) in the
+// configured order.
+func (o *options) mapKeys(v reflect.Value) iter.Seq[reflect.Value] {
+	return func(yield func(reflect.Value) bool) {
+		for k := range o.mapEntries(v) {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// maxKeyCompareDepth bounds how many pointers compareKeys follows, since
+// pointer keys may lead to cycles.
+const maxKeyCompareDepth = 8
+
+// comparerMethod returns the Compare method of values of type t, if it has one
+// of the form func (T) Compare(T) int (e.g. time.Time).
+func comparerMethod(t reflect.Type) (reflect.Method, bool) {
+	m, ok := t.MethodByName("Compare")
+	if !ok || m.Type.NumIn() != 2 || m.Type.In(1) != t || m.Type.NumOut() != 1 || m.Type.Out(0).Kind() != reflect.Int {
+		return reflect.Method{}, false
+	}
+	return m, true
+}
+
+// compareKeys orders two map keys of the same type. It's a total order over
+// comparable values, except that pointers nested more than depth levels deep
+// are ordered by address.
+func compareKeys(a, b reflect.Value, depth int) int {
+	t := a.Type()
+	if a.CanInterface() && b.CanInterface() && !isNilRef(a) && !isNilRef(b) {
+		if m, ok := comparerMethod(t); ok {
+			return int(m.Func.Call([]reflect.Value{a, b})[0].Int())
+		}
+		if eligibleStringer(t) {
+			if c := strings.Compare(a.Interface().(fmt.Stringer).String(), b.Interface().(fmt.Stringer).String()); c != 0 {
+				return c
+			}
+		}
+	}
+	switch a.Kind() {
+	case reflect.Bool:
+		return cmp.Compare(boolOrder(a.Bool()), boolOrder(b.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		ac, bc := a.Complex(), b.Complex()
+		return cmp.Or(cmp.Compare(real(ac), real(bc)), cmp.Compare(imag(ac), imag(bc)))
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Array:
+		for i := range a.Len() {
+			if c := compareKeys(a.Index(i), b.Index(i), depth); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if c := compareKeys(a.Field(i), b.Field(i), depth); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case reflect.Interface:
+		switch {
+		case a.IsNil() || b.IsNil():
+			// nils first
+			return cmp.Compare(boolOrder(!a.IsNil()), boolOrder(!b.IsNil()))
+		case a.Elem().Type() != b.Elem().Type():
+			return strings.Compare(a.Elem().Type().String(), b.Elem().Type().String())
+		default:
+			return compareKeys(a.Elem(), b.Elem(), depth)
+		}
+	case reflect.Pointer:
+		// Pointers are usually freshly allocated on every call to the
+		// callback, so their targets are a more stable order than their
+		// addresses.
+		if depth > 0 && !a.IsNil() && !b.IsNil() {
+			if c := compareKeys(a.Elem(), b.Elem(), depth-1); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Pointer(), b.Pointer())
+	case reflect.Chan:
+		return cmp.Compare(a.Pointer(), b.Pointer())
+	case reflect.UnsafePointer:
+		return cmp.Compare(uintptr(a.UnsafePointer()), uintptr(b.UnsafePointer()))
+	default:
+		// other kinds aren't comparable, so they can't be map keys
+		return 0
+	}
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// isNilRef reports whether v is a nil pointer or interface
+func isNilRef(v reflect.Value) bool {
+	return (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil()
+}
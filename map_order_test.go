@@ -0,0 +1,134 @@
+package statuspage
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type orderKey struct {
+	Region string
+	Zone   int
+}
+
+type byName struct{ name string }
+
+func (b byName) String() string { return b.name }
+
+// sortedByCompareKeys returns a shuffled copy of sorted, sorted with
+// compareKeys
+func sortedByCompareKeys[T any](sorted []T) []T {
+	out := slices.Clone(sorted)
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	slices.SortStableFunc(out, func(a, b T) int {
+		return compareKeys(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(), maxKeyCompareDepth)
+	})
+	return out
+}
+
+func checkOrder[T any](t *testing.T, name string, sorted []T) {
+	t.Helper()
+	for range 10 {
+		if got := sortedByCompareKeys(sorted); !reflect.DeepEqual(got, sorted) {
+			t.Errorf("%s sorted to %v; want %v", name, got, sorted)
+			return
+		}
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	checkOrder(t, "ints", []int{-10, -1, 0, 2, 10, 100})
+	checkOrder(t, "uints", []uint64{0, 1, 1 << 40, math.MaxUint64})
+	checkOrder(t, "floats", []float64{math.Inf(-1), -1.5, 0, 0.25, 3, math.Inf(1)})
+	checkOrder(t, "strings", []string{"", "A", "a", "ab", "b", "é"})
+	checkOrder(t, "bools", []bool{false, true})
+	checkOrder(t, "complex", []complex128{-1 + 5i, 1 - 1i, 1 + 0i, 1 + 2i})
+	// time.Time has a Compare method
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	checkOrder(t, "times", []time.Time{base, base.Add(time.Second).In(time.FixedZone("x", 3600)), base.Add(time.Hour)})
+	// Stringers are ordered by their String()s (so 10s comes before 1m0s
+	// and 5s)
+	checkOrder(t, "stringers", []byName{{"alpha"}, {"beta"}, {"gamma"}})
+	checkOrder(t, "durations", []time.Duration{10 * time.Second, time.Minute, 5 * time.Second})
+	checkOrder(t, "structs", []orderKey{{"eu", 2}, {"us", 1}, {"us", 3}})
+	checkOrder(t, "arrays", [][2]int{{0, 5}, {1, 0}, {1, 2}})
+	checkOrder(t, "interfaces", []any{nil, 1, 2, "a", "b"})
+	checkOrder(t, "pointers", []*orderKey{nil, {"eu", 1}, {"us", 1}})
+
+	// a pointer cycle doesn't recurse forever
+	type loop struct{ Next *loop }
+	a, b := &loop{}, &loop{}
+	a.Next, b.Next = a, b
+	if c := compareKeys(reflect.ValueOf(a), reflect.ValueOf(b), maxKeyCompareDepth); c == 0 {
+		t.Error("distinct pointers to cycles compare equal")
+	}
+}
+
+func entryKeys(o *options, v any) []string {
+	out := []string{}
+	for k := range o.mapEntries(reflect.ValueOf(v)) {
+		out = append(out, fmt.Sprint(k.Interface()))
+	}
+	return out
+}
+
+func TestMapEntries(t *testing.T) {
+	m := map[string]int{"bb": 1, "a": 2, "ccc": 3}
+	o := &options{}
+	if got, want := entryKeys(o, m), []string{"a", "bb", "ccc"}; !slices.Equal(got, want) {
+		t.Errorf("sorted keys = %q; want %q", got, want)
+	}
+
+	// NaN keys can't be looked up, but their entries still come out
+	nans := map[float64]int{math.NaN(): 1, math.NaN(): 2, 1: 3}
+	if got, want := entryKeys(o, nans), []string{"NaN", "NaN", "1"}; !slices.Equal(got, want) {
+		t.Errorf("keys with NaNs = %q; want %q", got, want)
+	}
+
+	byLen := &options{}
+	WithMapCompare[map[string]int](func(a, b string) int { return cmp.Compare(len(b), len(a)) })(byLen)
+	if got, want := entryKeys(byLen, m), []string{"ccc", "bb", "a"}; !slices.Equal(got, want) {
+		t.Errorf("keys by length = %q; want %q", got, want)
+	}
+	// the comparison only applies to its map type
+	if got, want := entryKeys(byLen, map[string]bool{"bb": true, "a": true}), []string{"a", "bb"}; !slices.Equal(got, want) {
+		t.Errorf("keys of another map type = %q; want %q", got, want)
+	}
+
+	iterOrder := &options{mapOrder: MapOrderIteration}
+	if got := entryKeys(iterOrder, m); len(got) != 3 {
+		t.Errorf("iteration order keys = %q; want all three", got)
+	}
+
+	// iter.Seq2s are rendered in the order they yield
+	seq := func(yield func(string, int) bool) { _ = yield("z", 1) && yield("a", 2) }
+	if got, want := entryKeys(o, seq), []string{"z", "a"}; !slices.Equal(got, want) {
+		t.Errorf("iter.Seq2 keys = %q; want %q", got, want)
+	}
+}
+
+func TestMapRenderingIsDeterministic(t *testing.T) {
+	m := map[orderKey]int{}
+	for i := range 50 {
+		m[orderKey{Region: fmt.Sprint("r", i%7), Zone: i}] = i
+	}
+	s := New("Test", func() map[orderKey]int { return m })
+	render := func() string {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Body.String()
+	}
+	first := render()
+	for range 5 {
+		if render() != first {
+			t.Fatal("the same map rendered differently")
+		}
+	}
+}
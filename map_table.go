@@ -21,34 +21,47 @@ func isSet(t reflect.Type) bool {
 	return t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0
 }
 
-// only works for v where the type is simple (doesn't need a table)
-func (s *Status[T]) simpleTableCell(v reflect.Value) (*html.Node, error) {
-	cell := createElemAtom(atom.Td)
-	ns, genErr := s.genValSection(v)
+// renders v into a single table cell (values that need a table get one nested within the cell)
+func (r *renderer) simpleTableCell(v reflect.Value) (*html.Node, error) {
+	cell := createElemClass(atom.Td, "sp-value")
+	ns, genErr := r.genValSection(v)
 	if genErr != nil {
 		return nil, genErr
 	}
 	for _, n := range ns {
 		cell.AppendChild(n)
 	}
+	setCellLevel(cell)
 	return cell, nil
 }
 
-func (s *Status[T]) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
-	if v.Kind() != reflect.Map && (v.Kind() != reflect.Func || v.Type().CanSeq2()) {
-		panic(fmt.Errorf("non-map/seq2 kind: %s type %s", v.Kind(), v.Type()))
+// fieldHeaderCell returns the header cell naming the field f
+func fieldHeaderCell(f *structField) *html.Node {
+	th := createElemClass(atom.Th, "sp-field-name")
+	th.AppendChild(textNode(f.displayName()))
+	setHelp(th, f)
+	return th
+}
+
+func (r *renderer) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
+	if v.Kind() != reflect.Map && (v.Kind() != reflect.Func || !v.Type().CanSeq2()) {
+		return nil, fmt.Errorf("non-map/seq2 kind: %s type %s", v.Kind(), v.Type())
 	}
 
-	baseTable := createElemAtom(atom.Table)
+	baseTable := createElemClass(atom.Table, "sp-table sp-map")
+	if v.Kind() == reflect.Func {
+		baseTable = createElemClass(atom.Table, "sp-table sp-seq2")
+	}
 	headerRow := createElemAtom(atom.Tr)
 	baseTable.AppendChild(headerRow)
 
-	keyHeader := createElemAtom(atom.Th)
+	keyHeader := createElemClass(atom.Th, "sp-header")
 	headerRow.AppendChild(keyHeader)
 	keyHeader.AppendChild(textNode(mapKeyHeader))
 
-	valHeader := createElemAtom(atom.Th)
-	valHeader.Attr = append(valHeader.Attr, html.Attribute{Key: "colspan", Val: "100%"})
+	// the value header spans the value columns, which are counted once
+	// the rows are in
+	valHeader := createElemClass(atom.Th, "sp-header")
 	headerRow.AppendChild(valHeader)
 	valHeader.AppendChild(textNode(mapValueHeader))
 
@@ -60,7 +73,7 @@ func (s *Status[T]) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
 		// this has to be an iter.Seq2
 		valType = v.Type().In(0).In(1)
 	default:
-		panic(fmt.Errorf("non-map/func kind: %s type %s", v.Kind(), v.Type()))
+		return nil, fmt.Errorf("non-map/func kind: %s type %s", v.Kind(), v.Type())
 	}
 
 	valSet := isSet(valType)
@@ -71,15 +84,26 @@ func (s *Status[T]) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
 
 	// header row for the map key if applicable
 	var hRowKey *html.Node
-	for ikey, ival := range v.Seq2() {
+	// valFieldsHeaded is set once the fields of struct values have a
+	// header row (when the keys don't have one to share)
+	valFieldsHeaded := false
+	// truncRow marks where rendering stopped (spanning the whole table)
+	var truncRow *html.Node
+	pw := r.pageWindow(v)
+	for ikey, ival := range pw.pairs(r.opts.mapEntries(v)) {
+		if l := r.exhausted(); l != 0 {
+			truncRow = r.truncatedRow(l, 0)
+			baseTable.AppendChild(truncRow)
+			break
+		}
 		if valSet {
 			// ival is actually a slice of valType where the values are ival's map keys, make it so!
 			// ex. map[string]struct{}{"dog":struct{}, "cat":struct{}} => ival should be []string{"dog", "cat"}
-			keys := ival.MapKeys()
-			ival = reflect.MakeSlice(valType, ival.Len(), ival.Len())
-			for i, key := range keys {
-				ival.Index(i).Set(key)
+			members := reflect.MakeSlice(valType, 0, ival.Len())
+			for key := range r.opts.mapKeys(ival) {
+				members = reflect.Append(members, key)
 			}
+			ival = members
 		}
 
 		row := createElemAtom(atom.Tr)
@@ -90,133 +114,198 @@ func (s *Status[T]) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
 
 		// cells for keys
 		// TODO: pull this out into helper
-		if !needsTable(ikey.Type()) {
-			cell, cellErr := s.simpleTableCell(ikey)
+		exitKey := r.inKey()
+		if r.ownCell(ikey.Type()) {
+			cell, cellErr := r.simpleTableCell(ikey)
 			if cellErr != nil {
 				return nil, cellErr
 			}
+			setAttr(cell, atom.Class.String(), "sp-key")
 			row.AppendChild(cell)
 		} else if ikey.Kind() == reflect.Struct {
-			fields := reflect.VisibleFields(ikey.Type())
+			fields, fieldsErr := structFields(ikey.Type())
+			if fieldsErr != nil {
+				return nil, fieldsErr
+			}
 			hRowKey = createElemAtom(atom.Tr)
-			keyHeader.Attr = append(keyHeader.Attr, html.Attribute{Key: atom.Colspan.String(), Val: strconv.Itoa(len(fields))})
+			// keyCells counts the cells the key takes up (a field that
+			// needs a table takes one per node), for the key header to
+			// span
+			keyCells := 0
 
 			for _, field := range fields {
-				if !field.IsExported() {
-					// skip the unexported fields for now
+				if field.omitted(ikey) {
+					hRowKey.AppendChild(fieldHeaderCell(&field))
+					row.AppendChild(createElemClass(atom.Td, "sp-omitted"))
+					keyCells++
 					continue
 				}
-				// TODO: pull out into a helper
-				if v, ok := field.Tag.Lookup("statuspage"); ok && v == "-" {
-					// The caller asked us to skip this
-					continue
-				}
-				if needsTable(field.Type) {
+				if r.needsTable(field.Type) {
 					// TODO: add in recursion with depth for header row levels, but for now, just stick in a table within this table
-					ns, genErr := s.genValSection(ikey.FieldByIndex(field.Index))
+					ascend := r.descend(keyStep(ikey))
+					ascendKey := r.descend(keyFieldsStep)
+					ascendField := r.descend(field.step())
+					ns, genErr := r.genValSection(ikey.FieldByIndex(field.Index))
+					ascendField()
+					ascendKey()
+					ascend()
 					if genErr != nil {
 						return nil, genErr
 					}
 					for _, n := range ns {
-						cell := createElemAtom(atom.Td)
+						cell := createElemClass(atom.Td, "sp-value")
 						row.AppendChild(cell)
 						cell.AppendChild(n)
 					}
+					keyCells += len(ns)
+					th := fieldHeaderCell(&field)
+					setColspan(th, len(ns))
+					hRowKey.AppendChild(th)
+					continue
 				}
-				th := createElemAtom(atom.Th)
-				hRowKey.AppendChild(th)
-				th.AppendChild(textNode(field.Name))
+				hRowKey.AppendChild(fieldHeaderCell(&field))
 
 				// add the field values from this struct to the values row
-				if needsTable(field.Type) {
-					continue
-				}
-				fieldValCell, cellErr := s.simpleTableCell(ikey.FieldByName(field.Name))
+				exitFormat := r.withFormat(field.tag.format)
+				fieldValCell, cellErr := r.simpleTableCell(ikey.FieldByIndex(field.Index))
+				exitFormat()
 				if cellErr != nil {
 					return nil, cellErr
 				}
 				row.AppendChild(fieldValCell)
+				keyCells++
 			}
+			setColspan(keyHeader, keyCells)
 		}
+		exitKey()
 		if hRowKey != nil {
 			headerRows = append(headerRows, hRowKey)
 		}
 
 		// cells for values
-		if !needsTable(ival.Type()) {
-			cell, cellErr := s.simpleTableCell(ival)
+		ascend := r.descend(keyStep(ikey))
+		setAttr(row, atom.Id.String(), rowID(r.path))
+		// Values we've already rendered link back to their first
+		// occurrence.
+		key, trackable := visitKeyOf(ival)
+		firstSeen, repeat := r.visited[key]
+		repeat = repeat && trackable
+		// follow pointers to the underlying value (nil pointers get a nil
+		// marker in a simple cell)
+		for ival.Kind() == reflect.Pointer && !ival.IsNil() {
+			ival = ival.Elem()
+		}
+		// Structs, slices and arrays are spread across the row's cells, so
+		// the row becomes their anchor. Everything else goes through
+		// genValSection (which records and anchors it) in a cell of its own.
+		anchored := false
+		if trackable && !repeat && !r.ownCell(ival.Type()) {
+			switch ival.Kind() {
+			case reflect.Struct, reflect.Slice, reflect.Array:
+				r.markVisited(key)
+				anchored = true
+			}
+		}
+		if repeat {
+			cell := createElemClass(atom.Td, "sp-value")
+			for _, n := range r.seeAbove(firstSeen) {
+				cell.AppendChild(n)
+			}
+			row.AppendChild(cell)
+		} else if r.ownCell(ival.Type()) || ival.Kind() == reflect.Pointer {
+			cell, cellErr := r.simpleTableCell(ival)
 			if cellErr != nil {
 				return nil, cellErr
 			}
 			row.AppendChild(cell)
 		} else if ival.Kind() == reflect.Struct {
-			fields := reflect.VisibleFields(ival.Type())
+			fields, fieldsErr := structFields(ival.Type())
+			if fieldsErr != nil {
+				return nil, fieldsErr
+			}
 			var hRowVal *html.Node
 			if len(headerRows) > 0 {
 				hRowVal = headerRows[0]
 			} else {
 				hRowVal = createElemAtom(atom.Tr)
+				// the key column is headed by the row above
+				hRowVal.AppendChild(createElemClass(atom.Th, "sp-header"))
+				if !valFieldsHeaded {
+					headerRows = append(headerRows, hRowVal)
+					valFieldsHeaded = true
+				}
 			}
 			for _, field := range fields {
-				if !field.IsExported() {
-					// skip the unexported fields for now
-					continue
-				}
-				// TODO: pull out into a helper
-				if v, ok := field.Tag.Lookup("statuspage"); ok && v == "-" {
-					// The caller asked us to skip this
+				if field.omitted(ival) {
+					hRowVal.AppendChild(fieldHeaderCell(&field))
+					row.AppendChild(createElemClass(atom.Td, "sp-omitted"))
 					continue
 				}
-				if needsTable(field.Type) {
-					ns, genErr := s.genValSection(ival.FieldByIndex(field.Index))
+				ascendField := r.enterField(&field)
+				if r.needsTable(field.Type) {
+					ns, genErr := r.genValSection(ival.FieldByIndex(field.Index))
 					if genErr != nil {
 						return nil, genErr
 					}
 					for _, n := range ns {
-						cell := createElemAtom(atom.Td)
+						cell := createElemClass(atom.Td, "sp-value")
 						row.AppendChild(cell)
 						cell.AppendChild(n)
 					}
 				}
-				th := createElemAtom(atom.Th)
-				hRowVal.AppendChild(th)
-				th.AppendChild(textNode(field.Name))
+				hRowVal.AppendChild(fieldHeaderCell(&field))
 
-				if needsTable(field.Type) {
+				if r.needsTable(field.Type) {
+					ascendField()
 					continue
 				}
 
 				// add the field values from this struct to the values row
-				fieldValCell, cellErr := s.simpleTableCell(ival.FieldByName(field.Name))
+				fieldValCell, cellErr := r.simpleTableCell(ival.FieldByIndex(field.Index))
+				ascendField()
 				if cellErr != nil {
 					return nil, cellErr
 				}
 				row.AppendChild(fieldValCell)
 			}
-		} else if ival.Kind() == reflect.Slice || ival.Kind() == reflect.Array {
-			if ival.Kind() != reflect.Array && ival.IsNil() {
-				nilCell, cErr := s.simpleTableCell(ival)
-				if cErr != nil {
-					return nil, cErr
-				}
-				row.AppendChild(nilCell)
-				continue
+		} else if ival.Kind() == reflect.Slice && ival.IsNil() {
+			nilCell, cErr := r.simpleTableCell(ival)
+			if cErr != nil {
+				return nil, cErr
 			}
+			row.AppendChild(nilCell)
+		} else if ival.Kind() == reflect.Slice || ival.Kind() == reflect.Array {
+			// the first few elements get a cell each (with a table
+			// nested within it if they need one)
 			size := min(ival.Len(), maxSliceLen)
 			for i := range size {
 				sliceVal := ival.Index(i)
-				if needsTable(sliceVal.Type()) {
-					// TODO
-					continue
-				}
-				c, cellErr := s.simpleTableCell(sliceVal)
+				ascendElem := r.descend(indexStep(i))
+				c, cellErr := r.simpleTableCell(sliceVal)
+				ascendElem()
 				if cellErr != nil {
 					return nil, cellErr
 				}
 				row.AppendChild(c)
 			}
+			if ival.Len() > size {
+				// the rest of the values are on the value's own page
+				row.AppendChild(r.moreCell(ival.Len() - size))
+			}
+		} else {
+			// Anything else (maps, iterators) gets a table within the cell
+			cell, cellErr := r.simpleTableCell(ival)
+			if cellErr != nil {
+				return nil, cellErr
+			}
+			row.AppendChild(cell)
 		}
 
+		if anchored {
+			r.setAnchor([]*html.Node{row})
+		}
+		ascend()
+
 		for _, hRow := range headerRows {
 			baseTable.AppendChild(hRow)
 		}
@@ -224,5 +313,26 @@ func (s *Status[T]) genMapOrSeq2Table(v reflect.Value) ([]*html.Node, error) {
 		baseTable.AppendChild(row)
 	}
 
+	keyCols, width := 1, 0
+	if span, ok := attr(keyHeader, atom.Colspan.String()); ok {
+		keyCols, _ = strconv.Atoi(span)
+	}
+	for row := range baseTable.ChildNodes() {
+		if row != headerRow && row != truncRow {
+			width = max(width, rowWidth(row))
+		}
+	}
+	setColspan(valHeader, width-keyCols)
+	if truncRow != nil {
+		setColspan(truncRow.FirstChild, width)
+	}
+
+	if pw.truncated() {
+		capNode := createElemAtom(atom.Caption)
+		r.pageCaption(capNode, pw)
+		baseTable.InsertBefore(capNode, baseTable.FirstChild)
+	}
+	r.finishTable(baseTable)
+
 	return []*html.Node{baseTable}, nil
 }
@@ -1,21 +1,56 @@
 package statuspage
 
 import (
+	"slices"
+	"strings"
+
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
 func createElemAtom(d atom.Atom) *html.Node {
-	n := &html.Node{Type: html.ElementNode, DataAtom: d, Data: d.String()}
-	if n.DataAtom == atom.Table {
-		n.Attr = append(n.Attr, html.Attribute{
-			Key: "style",
-			Val: "border: 1px solid; min-width: 100px",
-		})
-	}
-	return n
+	return &html.Node{Type: html.ElementNode, DataAtom: d, Data: d.String()}
 }
 
 func textNode(d string) *html.Node {
 	return &html.Node{Type: html.TextNode, Data: d}
 }
+
+// attr returns the value of n's attribute key, and whether it has one
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// setAttr sets n's attribute key to val, replacing any existing value
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// hasClass reports whether n has the class c
+func hasClass(n *html.Node, c string) bool {
+	classes, _ := attr(n, atom.Class.String())
+	return slices.Contains(strings.Fields(classes), c)
+}
+
+// addClass adds the class c to n's classes
+func addClass(n *html.Node, c string) {
+	classes, ok := attr(n, atom.Class.String())
+	if !ok || classes == "" {
+		setAttr(n, atom.Class.String(), c)
+		return
+	}
+	if !hasClass(n, c) {
+		setAttr(n, atom.Class.String(), classes+" "+c)
+	}
+}
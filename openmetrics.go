@@ -0,0 +1,421 @@
+package statuspage
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// openMetricsTagKey is the struct tag consulted for metric types and label
+// names, e.g.
+//
+//	Conns int `openmetrics:"type=counter" statuspage:"name=Open Conns,help=Connections opened"`
+//
+// Recognized directives:
+//   - type=gauge|counter: the metric type (defaults to gauge)
+//   - label=<name>: label name for the keys/indices of a map, slice, array or
+//     iterator field (by default, the keys of maps are labeled with the
+//     field's component of the metric name, and the indices of sequences with
+//     that component suffixed with _index, numbered if they're nested)
+//
+// A value of "-" omits the field (and everything below it) from the metrics.
+// Directives on a container field apply to every metric below it unless a
+// nested field overrides them.
+//
+// Metric names and help text come from the statuspage tag (see
+// statusPageTagKey): a field's component of the metric name is its name=
+// (lowercased, with spaces as underscores) if it has one, and its Go name in
+// snake_case otherwise, and help= is the HELP text of the metrics below it.
+// Fields hidden with `statuspage:"-"` are left out of the metrics too.
+const openMetricsTagKey = "openmetrics"
+
+const (
+	metricTypeGauge   = "gauge"
+	metricTypeCounter = "counter"
+)
+
+var durationReflectType = reflect.TypeFor[time.Duration]()
+
+// metricTag holds the parsed directives from an openmetrics struct tag, along
+// with the help text from the field's statuspage tag
+type metricTag struct {
+	skip  bool
+	typ   string
+	help  string
+	label string
+}
+
+func parseMetricTag(f *structField) (metricTag, error) {
+	raw, ok := f.Tag.Lookup(openMetricsTagKey)
+	if raw == "-" {
+		return metricTag{skip: true}, nil
+	}
+	out := metricTag{help: f.tag.help}
+	if !ok || raw == "" {
+		return out, nil
+	}
+	for directive := range strings.SplitSeq(raw, ",") {
+		k, v, _ := strings.Cut(directive, "=")
+		v = strings.TrimSpace(v)
+		switch k = strings.TrimSpace(k); k {
+		case "type":
+			switch v {
+			case metricTypeGauge, metricTypeCounter:
+				out.typ = v
+			default:
+				return metricTag{}, fmt.Errorf("field %q: unknown metric type %q in %s tag", f.Name, v, openMetricsTagKey)
+			}
+		case "label":
+			out.label = sanitizeMetricName(v)
+		case "name", "help":
+			return metricTag{}, fmt.Errorf("field %q: %s= belongs in the %s tag, not the %s tag", f.Name, k, statusPageTagKey, openMetricsTagKey)
+		default:
+			return metricTag{}, fmt.Errorf("field %q: unknown directive %q in %s tag", f.Name, directive, openMetricsTagKey)
+		}
+	}
+	return out, nil
+}
+
+// metricNamePart returns the field's component of metric names
+func metricNamePart(f *structField) string {
+	if f.tag.name == "" {
+		return sanitizeMetricName(snakeCase(f.Name))
+	}
+	return sanitizeMetricName(strings.ToLower(strings.Join(strings.Fields(f.tag.name), "_")))
+}
+
+// inherit fills in unset directives in t from the enclosing field's tag
+func (t metricTag) inherit(parent metricTag) metricTag {
+	if t.typ == "" {
+		t.typ = parent.typ
+	}
+	if t.help == "" {
+		t.help = parent.help
+	}
+	return t
+}
+
+// snakeCase converts a Go identifier (e.g. "HTTPRequestCount") to
+// snake_case ("http_request_count")
+func snakeCase(name string) string {
+	rs := []rune(name)
+	b := strings.Builder{}
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]))
+			nextLower := i > 0 && i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sanitizeMetricName replaces any characters that aren't valid in an
+// OpenMetrics metric or label name with underscores
+func sanitizeMetricName(name string) string {
+	b := strings.Builder{}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r < unicode.MaxASCII && unicode.IsLetter(r)):
+			b.WriteRune(r)
+		case i > 0 && r < unicode.MaxASCII && unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+type metricLabel struct {
+	name, value string
+}
+
+type metricSample struct {
+	labels []metricLabel
+	value  string
+}
+
+type metricFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []metricSample
+}
+
+// metricCollector accumulates metric families while walking a value. Families
+// are emitted in the order they're first encountered, which keeps the output
+// in struct-declaration order.
+//
+// Collection is bounded by the RenderLimits, as renders are: MaxDepth limits
+// the nesting of the values walked, MaxNodes the number of series, and
+// MaxBytes the (approximate) size of the output. Whatever lies past them is
+// left out.
+type metricCollector struct {
+	opts     *options
+	families []*metricFamily
+	byName   map[string]*metricFamily
+	// onStack holds the pointers, maps and slices enclosing the value
+	// being walked, so cycles can be skipped
+	onStack map[visitKey]struct{}
+	budget  renderBudget
+}
+
+func newMetricCollector(opts *options) *metricCollector {
+	return &metricCollector{opts: opts, byName: map[string]*metricFamily{}, onStack: map[visitKey]struct{}{}}
+}
+
+func (mc *metricCollector) add(nameParts []string, tag metricTag, labels []metricLabel, value string) {
+	name := strings.Join(nameParts, "_")
+	if name == "" {
+		name = "value"
+	}
+	typ := tag.typ
+	if typ == "" {
+		typ = metricTypeGauge
+	}
+	if typ == metricTypeCounter {
+		// the _total suffix belongs on the sample, not the family
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fam, ok := mc.byName[name]
+	if !ok {
+		fam = &metricFamily{name: name, typ: typ, help: tag.help}
+		mc.byName[name] = fam
+		mc.families = append(mc.families, fam)
+	}
+	fam.samples = append(fam.samples, metricSample{labels: labels, value: value})
+	mc.budget.nodes++
+	mc.budget.bytes += len(name) + len(value) + 2
+	for _, l := range labels {
+		mc.budget.bytes += len(l.name) + len(l.value) + 4
+	}
+}
+
+// metricLabelValue renders a map key as a label value
+func metricLabelValue(k reflect.Value) string {
+	if ks, ok := jsonKeyString(k); ok {
+		return ks
+	}
+	if st, ok := scalarText(k); ok {
+		return st
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+func metricFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// collect walks v, recording every numeric, bool and time.Duration value as a
+// sample. nameParts is the metric name so far (derived from the field path),
+// and labels holds the map keys and slice offsets leading to v.
+func (mc *metricCollector) collect(v reflect.Value, nameParts []string, labels []metricLabel, tag metricTag) error {
+	if mc.budget.exhausted(mc.opts.limits) != 0 {
+		return nil
+	}
+	if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface && descends(v) {
+		if l := mc.opts.limits; l.MaxDepth > 0 && mc.budget.depth >= l.MaxDepth {
+			mc.budget.hit |= limitDepth
+			return nil
+		}
+		mc.budget.depth++
+		defer func() { mc.budget.depth-- }()
+	}
+	if key, trackable := visitKeyOf(v); trackable {
+		if _, cycle := mc.onStack[key]; cycle {
+			// we're already emitting everything below this value
+			return nil
+		}
+		mc.onStack[key] = struct{}{}
+		defer delete(mc.onStack, key)
+	}
+	if v.Type() == durationReflectType {
+		mc.add(append(nameParts[:len(nameParts):len(nameParts)], "seconds"), tag, labels,
+			metricFloat(time.Duration(v.Int()).Seconds()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		val := "0"
+		if v.Bool() {
+			val = "1"
+		}
+		mc.add(nameParts, tag, labels, val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		mc.add(nameParts, tag, labels, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		mc.add(nameParts, tag, labels, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		mc.add(nameParts, tag, labels, metricFloat(v.Float()))
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return mc.collect(v.Elem(), nameParts, labels, tag)
+	case reflect.Struct:
+		if eligibleStringer(v.Type()) {
+			// Stringer structs (e.g. time.Time) don't have a numeric form
+			return nil
+		}
+		fields, fieldsErr := visibleFields(v)
+		if fieldsErr != nil {
+			return fieldsErr
+		}
+		for _, f := range fields {
+			ft, tagErr := parseMetricTag(&f)
+			if tagErr != nil {
+				return tagErr
+			}
+			if ft.skip {
+				continue
+			}
+			part := metricNamePart(&f)
+			ft = ft.inherit(tag)
+			fieldParts := append(nameParts[:len(nameParts):len(nameParts)], part)
+			if cErr := mc.collect(v.FieldByIndex(f.Index), fieldParts, labels, ft); cErr != nil {
+				return fmt.Errorf("field %q: %w", f.Name, cErr)
+			}
+		}
+	case reflect.Map:
+		if isSet(v.Type()) {
+			// sets don't have any numeric values
+			return nil
+		}
+		return mc.collectSeq2(v, nameParts, labels, tag)
+	case reflect.Array, reflect.Slice:
+		return mc.collectSeq(v, nameParts, labels, tag)
+	case reflect.Func:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().CanSeq2() {
+			return mc.collectSeq2(v, nameParts, labels, tag)
+		} else if v.Type().CanSeq() {
+			return mc.collectSeq(v, nameParts, labels, tag)
+		}
+	}
+	// Everything else (strings, channels, complex numbers, etc.) has no
+	// metric representation.
+	return nil
+}
+
+// defaultMetricLabel returns the label name for the keys of a map or iterator
+// (or the offsets of a sequence, if seq is set) with the metric name parts
+// nameParts: the name of the field holding it, suffixed with _index for
+// sequences. Nested containers get the same name, which uniqueLabelName
+// numbers.
+func defaultMetricLabel(nameParts []string, seq bool) string {
+	name := "value"
+	if len(nameParts) > 0 {
+		name = nameParts[len(nameParts)-1]
+	}
+	if seq {
+		return name + "_index"
+	}
+	return name
+}
+
+// uniqueLabelName returns name, suffixed with a number if needed to avoid
+// colliding with a label already in labels (e.g. for nested maps)
+func uniqueLabelName(labels []metricLabel, name string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		if !slices.ContainsFunc(labels, func(l metricLabel) bool { return l.name == candidate }) {
+			return candidate
+		}
+		candidate = name + "_" + strconv.Itoa(n)
+	}
+}
+
+func (mc *metricCollector) collectSeq2(v reflect.Value, nameParts []string, labels []metricLabel, tag metricTag) error {
+	labelName := tag.label
+	if labelName == "" {
+		labelName = defaultMetricLabel(nameParts, false)
+	}
+	// The label name only applies to this level of nesting
+	labelName = uniqueLabelName(labels, labelName)
+	childTag := tag
+	childTag.label = ""
+	for ik, iv := range mc.opts.mapEntries(v) {
+		kLabels := append(labels[:len(labels):len(labels)], metricLabel{name: labelName, value: metricLabelValue(ik)})
+		if cErr := mc.collect(iv, nameParts, kLabels, childTag); cErr != nil {
+			return fmt.Errorf("key %q: %w", metricLabelValue(ik), cErr)
+		}
+	}
+	return nil
+}
+
+func (mc *metricCollector) collectSeq(v reflect.Value, nameParts []string, labels []metricLabel, tag metricTag) error {
+	labelName := tag.label
+	if labelName == "" {
+		labelName = defaultMetricLabel(nameParts, true)
+	}
+	labelName = uniqueLabelName(labels, labelName)
+	childTag := tag
+	childTag.label = ""
+	offset := 0
+	for ev := range seqElems(v) {
+		eLabels := append(labels[:len(labels):len(labels)], metricLabel{name: labelName, value: strconv.Itoa(offset)})
+		if cErr := mc.collect(ev, nameParts, eLabels, childTag); cErr != nil {
+			return fmt.Errorf("index %d: %w", offset, cErr)
+		}
+		offset++
+	}
+	return nil
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var metricHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// writeTo writes the collected metrics in the OpenMetrics text format
+func (mc *metricCollector) writeTo(w io.Writer) error {
+	b := strings.Builder{}
+	for _, fam := range mc.families {
+		b.WriteString("# TYPE " + fam.name + " " + fam.typ + "\n")
+		if fam.help != "" {
+			b.WriteString("# HELP " + fam.name + " " + metricHelpEscaper.Replace(fam.help) + "\n")
+		}
+		sampleName := fam.name
+		if fam.typ == metricTypeCounter {
+			sampleName += "_total"
+		}
+		for _, smp := range fam.samples {
+			b.WriteString(sampleName)
+			if len(smp.labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range smp.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(l.name + `="` + metricLabelEscaper.Replace(l.value) + `"`)
+				}
+				b.WriteByte('}')
+			}
+			b.WriteString(" " + smp.value + "\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	_, wErr := io.WriteString(w, b.String())
+	return wErr
+}
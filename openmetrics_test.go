@@ -0,0 +1,138 @@
+package statuspage_test
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	statuspage "github.com/vimeo/go-status-page"
+)
+
+type metricsBackend struct {
+	Conns   int
+	Latency time.Duration
+	Addr    string
+}
+
+type metricsVal struct {
+	Requests  uint64 `openmetrics:"type=counter" statuspage:"help=Requests served"`
+	ErrsTotal int    `openmetrics:"type=counter"`
+	Up        bool
+	Ratio     float64 `statuspage:"name=Hit Ratio"`
+	Inf       float64
+	Name      string
+	Started   time.Time
+	Backends  map[string]metricsBackend `openmetrics:"label=backend"`
+	Queues    map[string][]int
+	Seq       []int
+	Grid      [][]int
+	Ptr       *int
+	Skipped   int `openmetrics:"-"`
+	Hidden    int `statuspage:"-"`
+}
+
+func TestOpenMetrics(t *testing.T) {
+	seven := 7
+	v := metricsVal{
+		Requests:  10,
+		ErrsTotal: 2,
+		Up:        true,
+		Ratio:     0.5,
+		Inf:       math.Inf(1),
+		Name:      "x",
+		Started:   time.Unix(0, 0),
+		Backends:  map[string]metricsBackend{"b": {Conns: 2}, "a": {Conns: 1, Latency: 1500 * time.Millisecond}},
+		Queues:    map[string][]int{"x": {3}},
+		Seq:       []int{4, 5},
+		Grid:      [][]int{{6}},
+		Ptr:       &seven,
+		Skipped:   1,
+		Hidden:    1,
+	}
+	got := serveStatus(t, v, "?format=openmetrics")
+	want := `# TYPE requests counter
+# HELP requests Requests served
+requests_total 10
+# TYPE errs counter
+errs_total 2
+# TYPE up gauge
+up 1
+# TYPE hit_ratio gauge
+hit_ratio 0.5
+# TYPE inf gauge
+inf +Inf
+# TYPE backends_conns gauge
+backends_conns{backend="a"} 1
+backends_conns{backend="b"} 2
+# TYPE backends_latency_seconds gauge
+backends_latency_seconds{backend="a"} 1.5
+backends_latency_seconds{backend="b"} 0
+# TYPE queues gauge
+queues{queues="x",queues_index="0"} 3
+# TYPE seq gauge
+seq{seq_index="0"} 4
+seq{seq_index="1"} 5
+# TYPE grid gauge
+grid{grid_index="0",grid_index_2="0"} 6
+# TYPE ptr gauge
+ptr 7
+# EOF
+`
+	if got != want {
+		t.Errorf("metrics:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOpenMetricsLimits(t *testing.T) {
+	v := struct{ Seq []int }{Seq: make([]int, 100)}
+	s := statuspage.New("Test", func() struct{ Seq []int } { return v },
+		statuspage.WithRenderLimits(statuspage.RenderLimits{MaxNodes: 10}))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=openmetrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if n := strings.Count(rec.Body.String(), "\nseq{"); n != 10 {
+		t.Errorf("got %d series; want 10", n)
+	}
+	if got := rec.Header().Get("X-Status-Page-Truncated"); got != "value limit" {
+		t.Errorf("truncation header = %q; want \"value limit\"", got)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "# EOF\n") {
+		t.Errorf("truncated metrics don't end with # EOF:\n%s", rec.Body)
+	}
+}
+
+func TestOpenMetricsTagErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		serve   func(w http.ResponseWriter, r *http.Request)
+		wantErr string
+	}{
+		{"name in the openmetrics tag", statuspage.New("Test", func() struct {
+			X int `openmetrics:"name=x"`
+		} {
+			return struct {
+				X int `openmetrics:"name=x"`
+			}{}
+		}).ServeHTTP, "name= belongs in the statuspage tag"},
+		{"unknown type", statuspage.New("Test", func() struct {
+			X int `openmetrics:"type=histogram"`
+		} {
+			return struct {
+				X int `openmetrics:"type=histogram"`
+			}{}
+		}).ServeHTTP, `unknown metric type "histogram"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.serve(rec, httptest.NewRequest(http.MethodGet, "/?format=openmetrics", nil))
+			if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), tc.wantErr) {
+				t.Errorf("status %d: %s; want a 500 containing %q", rec.Code, rec.Body, tc.wantErr)
+			}
+		})
+	}
+}
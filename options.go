@@ -0,0 +1,216 @@
+package statuspage
+
+import (
+	"html/template"
+	"reflect"
+	"time"
+)
+
+// Option configures optional behavior of a Status
+type Option func(*options)
+
+type options struct {
+	basePath       string
+	maxInlineDepth int
+	limits         RenderLimits
+	pageSize       int
+	mapOrder       MapOrder
+	// mapCompare holds the key comparison functions set with
+	// WithMapCompare, by map type
+	mapCompare map[reflect.Type]func(a, b reflect.Value) int
+	// renderers holds the renderers set with WithRenderer, by type
+	renderers map[reflect.Type]typeRenderer
+	timeout   time.Duration
+	cacheTTL  time.Duration
+	// maxConcurrentRenders bounds the renders in progress (0 is unbounded)
+	maxConcurrentRenders int
+	liveInterval         time.Duration
+	historyInterval      time.Duration
+	historySize          int
+	trendPoints          int
+	trendInterval        time.Duration
+	stylesheetURL        string
+	layout               *template.Template
+}
+
+// defaultOptions returns the options a Status starts with, before any
+// Options are applied.
+func defaultOptions() options {
+	return options{limits: defaultRenderLimits, pageSize: defaultPageSize, trendPoints: defaultTrendPoints, timeout: defaultTimeout}
+}
+
+// WithBasePath sets the URL path the Status is served at (e.g. "/status").
+// Sub-pages are served beneath it, so links need to know where that is.
+//
+// When unset, it's inferred from the net/http.ServeMux pattern the request
+// matched (e.g. "/status/" or "/status/{path...}"). Handlers mounted on other
+// routers should set it explicitly.
+func WithBasePath(p string) Option {
+	return func(o *options) {
+		o.basePath = p
+	}
+}
+
+// WithMaxInlineDepth limits how many levels of nested tables are rendered
+// inline. Tables nested more deeply are replaced with a link to a sub-page
+// rendering just that value. A depth of 0 (the default) renders everything
+// inline.
+func WithMaxInlineDepth(depth int) Option {
+	return func(o *options) {
+		o.maxInlineDepth = depth
+	}
+}
+
+// RenderLimits bounds the work done by a single render of a Status, so one
+// request can't allocate an unbounded amount of memory for a huge (or deeply
+// nested) value. When a limit is reached, rendering stops descending and
+// truncation markers (linking to sub-pages where possible) are emitted
+// instead. A zero field disables that limit.
+type RenderLimits struct {
+	// MaxDepth is the maximum nesting depth of values rendered
+	MaxDepth int
+	// MaxNodes is the maximum number of values rendered
+	MaxNodes int
+	// MaxBytes is the (approximate) maximum size of the rendered output
+	MaxBytes int
+}
+
+// defaultRenderLimits are generous enough that they should only be hit by
+// pathological values.
+var defaultRenderLimits = RenderLimits{
+	MaxDepth: 64,
+	MaxNodes: 250_000,
+	MaxBytes: 32 << 20,
+}
+
+// WithRenderLimits overrides the default RenderLimits (a depth of 64, 250,000
+// values and 32MiB of output).
+func WithRenderLimits(l RenderLimits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// WithPageSize sets how many elements of a slice, array, map or iterator are
+// rendered per page (100 by default). Longer sequences get a caption noting
+// which elements are shown, along with links to the previous and next pages.
+// A size of 0 renders every element on one page.
+//
+// Pagination only applies to the HTML and text formats; JSON output always
+// includes every element (subject to the RenderLimits).
+func WithPageSize(n int) Option {
+	return func(o *options) {
+		o.pageSize = n
+	}
+}
+
+// WithTimeout bounds how long each call to the Status's callback may take (30
+// seconds by default). If it hasn't returned by then, the requests waiting on
+// it fail with a 503 and the callback's context is canceled. Calls are shared
+// by the requests arriving while they're in progress, so they aren't canceled
+// when the request that made them is: the timeout is all that bounds them. A
+// timeout of 0 or less restores the default.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d <= 0 {
+			d = defaultTimeout
+		}
+		o.timeout = d
+	}
+}
+
+// WithCacheTTL caches the value returned by the Status's callback, along with
+// its renderings, for d: requests within d of the callback returning are
+// served from the cache, and pages note when the snapshot they show was
+// taken (as does the response's Age header). Failed calls aren't cached. A TTL
+// of 0 (the default) calls the callback for each request (though concurrent
+// requests still share a call).
+func WithCacheTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.cacheTTL = d
+	}
+}
+
+// WithMaxConcurrentRenders bounds the number of renders in progress at once.
+// Requests beyond that are rejected with a 429 (Too Many Requests) and a
+// Retry-After header; requests served from the cache (see WithCacheTTL)
+// don't count. A limit of 0 (the default) leaves renders unbounded.
+func WithMaxConcurrentRenders(n int) Option {
+	return func(o *options) {
+		o.maxConcurrentRenders = n
+	}
+}
+
+// WithLiveUpdates turns on live mode: HTML pages update themselves in place
+// as the value changes, without needing to be reloaded. The page opens a
+// Server-Sent Events stream from the Status (with ?live=sse), which calls the
+// callback every interval (subject to WithCacheTTL) and pushes the sections
+// of the page that changed. Where the stream doesn't get through (e.g. behind
+// a proxy that buffers responses), the page falls back to long polling
+// (?live=poll), which responds once the rendered page changes.
+//
+// An interval of 0 (the default) disables live mode.
+func WithLiveUpdates(interval time.Duration) Option {
+	return func(o *options) {
+		o.liveInterval = interval
+	}
+}
+
+// WithHistory starts a background sampler that calls the Status's callback
+// every interval, keeping the last n values it returns. Pages get a timeline
+// across the top for browsing them, and any format can render the snapshot
+// in effect at a given time with ?at=<RFC 3339 timestamp>, so the state
+// leading up to an incident is still around afterwards. HTML pages can also
+// show what changed since a snapshot in the history: ?diff=<RFC 3339
+// timestamp> diffs against the snapshot in effect then, and ?diff=seen
+// against the one the viewer last loaded (tracked with a cookie), which
+// pages link to. Since only the sampler's snapshots are kept, that's the
+// sampled snapshot in effect when the viewer's was loaded.
+//
+// The values are deep copies of the parts that render, so mutating the value
+// the callback returned doesn't change the history. Unexported fields aren't
+// copied, and values that render themselves (e.g. with String methods) are
+// copied by assignment, sharing whatever they point to, as are channels and
+// funcs. Call Status.Close to stop the sampler.
+func WithHistory(interval time.Duration, n int) Option {
+	return func(o *options) {
+		o.historyInterval, o.historySize = interval, n
+	}
+}
+
+// WithTrends configures the trends kept for fields tagged with trend (see
+// statusPageTagKey): each keeps its last points samples (60 by default).
+// Values are sampled whenever the callback is called, including by the
+// sampler started by WithHistory. An interval greater than 0 starts a
+// sampler of its own, calling the callback every interval; call
+// Status.Close to stop it.
+func WithTrends(points int, interval time.Duration) Option {
+	return func(o *options) {
+		o.trendPoints, o.trendInterval = points, interval
+	}
+}
+
+// WithStylesheet adds the stylesheet at url to HTML pages, after the default
+// one, so it can override any of its rules. Every element on the page carries
+// a class naming what it renders (e.g. sp-struct, sp-map, sp-field-name,
+// sp-num, sp-bool-true, sp-nil), to give it something to select.
+func WithStylesheet(url string) Option {
+	return func(o *options) {
+		o.stylesheetURL = url
+	}
+}
+
+// WithLayout renders HTML pages (and error pages) through the layout t,
+// rather than as standalone documents, so they can be wrapped in a team's own
+// chrome (navigation, links to runbooks, deploy info and so on). t is
+// executed with a *LayoutData, and should include its Head (for the
+// stylesheets and scripts the content relies on) and its Body (or the
+// Sections it wants, placed individually). FuncMap's functions let it render
+// other values (such as fields of LayoutData.Value) as tables.
+//
+// Diff views are still rendered as standalone documents.
+func WithLayout(t *template.Template) Option {
+	return func(o *options) {
+		o.layout = t
+	}
+}
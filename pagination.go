@@ -0,0 +1,287 @@
+package statuspage
+
+import (
+	"iter"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultPageSize is the number of elements of a slice, array, map or
+// iterator shown per page unless overridden with WithPageSize.
+const defaultPageSize = 100
+
+// pageOffsetParam returns the name of the query parameter holding the
+// offset of the first element shown from the sequence at p. Each table has
+// its own, so paging through one table leaves the others alone.
+func pageOffsetParam(p fieldPath) string {
+	return "o" + p.String()
+}
+
+// isPageOffsetParam reports whether the query parameter k holds the offset
+// of one of the page's tables
+func isPageOffsetParam(k string) bool {
+	return k == pageOffsetParam(nil) || strings.HasPrefix(k, pageOffsetParam(nil)+"/")
+}
+
+// pageWindow is the range of elements of a sequence rendered in one table
+type pageWindow struct {
+	// elements in [start, end) are rendered
+	start, end int
+	// total is the number of elements, or -1 if it's unknown (iterators)
+	total int
+	// more is set when iterating over an iterator if we ran into elements
+	// past end
+	more bool
+	// seen is the number of elements iterated over, and done is set if
+	// that's all of them (so an iterator's length is known once it ran
+	// out within the window)
+	seen int
+	done bool
+	// paged is false if there's no page size (so everything is rendered)
+	paged bool
+}
+
+// pageWindow returns the window of the sequence v (at the current path) to
+// render, based on the page size and any offset in the request.
+func (r *renderer) pageWindow(v reflect.Value) *pageWindow {
+	pw := &pageWindow{total: -1, end: -1}
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		pw.total = v.Len()
+	}
+	size := r.opts.pageSize
+	if size <= 0 {
+		return pw
+	}
+	pw.paged = true
+	if off, convErr := strconv.Atoi(r.query.Get(pageOffsetParam(r.path))); convErr == nil && off > 0 {
+		pw.start = off
+	}
+	if pw.total >= 0 {
+		pw.start = min(pw.start, max(pw.total-1, 0))
+	}
+	pw.end = pw.start + size
+	return pw
+}
+
+// inWindow reports whether the element at offset should be rendered. It
+// returns false (and keep=false) once we're past the end of the window, so
+// callers can stop iterating.
+func (pw *pageWindow) inWindow(offset int) (render, keep bool) {
+	if pw.end >= 0 && offset >= pw.end {
+		pw.more = true
+		return false, false
+	}
+	pw.seen = max(pw.seen, offset+1)
+	return offset >= pw.start, true
+}
+
+// elems iterates over the elements of seq within the window, along with
+// their offsets.
+func (pw *pageWindow) elems(seq iter.Seq[reflect.Value]) iter.Seq2[int, reflect.Value] {
+	return func(yield func(int, reflect.Value) bool) {
+		offset := 0
+		for ev := range seq {
+			render, keep := pw.inWindow(offset)
+			if !keep {
+				return
+			}
+			if render && !yield(offset, ev) {
+				return
+			}
+			offset++
+		}
+		pw.done = true
+	}
+}
+
+// pairs iterates over the key/value pairs of seq within the window
+func (pw *pageWindow) pairs(seq iter.Seq2[reflect.Value, reflect.Value]) iter.Seq2[reflect.Value, reflect.Value] {
+	return func(yield func(reflect.Value, reflect.Value) bool) {
+		offset := 0
+		for k, v := range seq {
+			render, keep := pw.inWindow(offset)
+			if !keep {
+				return
+			}
+			if render && !yield(k, v) {
+				return
+			}
+			offset++
+		}
+		pw.done = true
+	}
+}
+
+// truncated reports whether some elements weren't rendered
+func (pw *pageWindow) truncated() bool {
+	if !pw.paged {
+		return false
+	}
+	return pw.start > 0 || pw.more || (pw.total >= 0 && pw.end < pw.total)
+}
+
+// length returns the number of elements, or -1 if it's unknown (iterators
+// that haven't run out)
+func (pw *pageWindow) length() int {
+	if pw.total < 0 && pw.done {
+		return pw.seen
+	}
+	return pw.total
+}
+
+// lastShown returns the offset one past the last element rendered
+func (pw *pageWindow) lastShown() int {
+	if n := pw.length(); n >= 0 {
+		return min(pw.end, n)
+	}
+	return pw.end
+}
+
+// groupDigits formats n with thousands separators (e.g. 48,213)
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	neg := n < 0
+	if neg {
+		s = s[1:]
+	}
+	out := make([]byte, 0, len(s)+len(s)/3)
+	for i := range len(s) {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, s[i])
+	}
+	if neg {
+		return "-" + string(out)
+	}
+	return string(out)
+}
+
+// caption describes the window, e.g. "showing 1–100 of 48,213"
+func (pw *pageWindow) caption() string {
+	n := pw.length()
+	switch {
+	case n < 0 && pw.start == 0:
+		return "first " + groupDigits(pw.end) + " shown"
+	case n < 0:
+		return "showing " + groupDigits(pw.start+1) + "–" + groupDigits(pw.lastShown())
+	case pw.start >= n:
+		// an iterator that ran out before the window
+		return "none shown of " + groupDigits(n)
+	default:
+		return "showing " + groupDigits(pw.start+1) + "–" + groupDigits(pw.lastShown()) + " of " + groupDigits(n)
+	}
+}
+
+// pageQuery returns the query string for the current request with the
+// offset of the sequence at the current path set to offset.
+func (r *renderer) pageQuery(offset int) string {
+	q := url.Values{}
+	for k, vs := range r.query {
+		q[k] = vs
+	}
+	if offset > 0 {
+		q.Set(pageOffsetParam(r.path), strconv.Itoa(offset))
+	} else {
+		q.Del(pageOffsetParam(r.path))
+	}
+	return q.Encode()
+}
+
+// prevNextOffsets returns the offsets of the previous and next pages, with
+// -1 indicating there isn't one
+func (pw *pageWindow) prevNextOffsets() (prev, next int) {
+	prev, next = -1, -1
+	if pw.start > 0 {
+		start := pw.start
+		if n := pw.length(); n >= 0 {
+			// an iterator's window may start past its end
+			start = min(start, n)
+		}
+		prev = max(start-(pw.end-pw.start), 0)
+	}
+	if pw.more || (pw.total >= 0 && pw.end < pw.total) {
+		next = pw.end
+	}
+	return prev, next
+}
+
+// pageCaption appends the window's caption and prev/next links to capNode
+// if the window doesn't cover the whole sequence.
+func (r *renderer) pageCaption(capNode *html.Node, pw *pageWindow) {
+	if !pw.truncated() {
+		return
+	}
+	if capNode.FirstChild != nil {
+		capNode.AppendChild(createElemAtom(atom.Br))
+	}
+	capNode.AppendChild(textNode(pw.caption()))
+	prev, next := pw.prevNextOffsets()
+	link := func(offset int, label string) {
+		capNode.AppendChild(textNode(" "))
+		a := createElemClass(atom.A, "sp-page-link")
+		a.Attr = append(a.Attr, html.Attribute{Key: atom.Href.String(), Val: "?" + r.pageQuery(offset) + "#" + anchorID(r.path)})
+		a.AppendChild(textNode(label))
+		capNode.AppendChild(a)
+	}
+	if prev >= 0 {
+		link(prev, "‹ prev")
+	}
+	if next >= 0 {
+		link(next, "next ›")
+	}
+}
+
+// pageCaptionText is the plain-text counterpart to pageCaption
+func (r *renderer) pageCaptionText(pw *pageWindow) []string {
+	if !pw.truncated() {
+		return nil
+	}
+	out := []string{pw.caption()}
+	prev, next := pw.prevNextOffsets()
+	if prev >= 0 {
+		out = append(out, "prev: "+r.pathURL(r.path)+"?"+r.pageQuery(prev))
+	}
+	if next >= 0 {
+		out = append(out, "next: "+r.pathURL(r.path)+"?"+r.pageQuery(next))
+	}
+	return out
+}
+
+// moreLabel returns the label for a cell standing in for n elements that
+// were cut (n < 0 if the count is unknown)
+func moreLabel(n int) string {
+	if n < 0 {
+		return "… more"
+	}
+	return "…" + groupDigits(n) + " more"
+}
+
+// moreCell returns a table cell standing in for n elements of the sequence
+// at the current path that were cut, linking to its sub-page if possible.
+func (r *renderer) moreCell(n int) *html.Node {
+	cell := createElemClass(atom.Td, "sp-more")
+	if !r.subPages {
+		cell.AppendChild(textNode(moreLabel(n)))
+		return cell
+	}
+	a := createElemAtom(atom.A)
+	a.Attr = append(a.Attr, html.Attribute{Key: atom.Href.String(), Val: r.pathURL(r.path)})
+	a.AppendChild(textNode(moreLabel(n)))
+	cell.AppendChild(a)
+	return cell
+}
+
+// moreText is the plain-text counterpart to moreCell
+func (r *renderer) moreText(n int) string {
+	if !r.subPages {
+		return moreLabel(n)
+	}
+	return moreLabel(n) + ": " + r.pathURL(r.path)
+}
@@ -0,0 +1,169 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestGroupDigits(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 48213: "48,213", 1234567: "1,234,567", -1234: "-1,234"} {
+		if got := groupDigits(n); got != want {
+			t.Errorf("groupDigits(%d) = %q; want %q", n, got, want)
+		}
+	}
+}
+
+// windowOver returns the window over v with the offset off in the query,
+// after iterating over it
+func windowOver(v any, size int, off string) (*pageWindow, []int) {
+	r := newRenderer(&options{pageSize: size}, "Test")
+	r.path = fieldPath{fieldStep("S")}
+	r.query = url.Values{}
+	if off != "" {
+		r.query.Set(pageOffsetParam(r.path), off)
+	}
+	rv := reflect.ValueOf(v)
+	pw := r.pageWindow(rv)
+	offsets := []int{}
+	seq := func(yield func(reflect.Value) bool) {
+		if rv.Kind() == reflect.Func {
+			for ev := range rv.Seq() {
+				if !yield(ev) {
+					return
+				}
+			}
+			return
+		}
+		for i := range rv.Len() {
+			if !yield(rv.Index(i)) {
+				return
+			}
+		}
+	}
+	for off := range pw.elems(seq) {
+		offsets = append(offsets, off)
+	}
+	return pw, offsets
+}
+
+func count(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := range n {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func TestPageWindow(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		v          any
+		size       int
+		off        string
+		first      int
+		n          int
+		caption    string
+		prev, next int
+	}{
+		{"first page", make([]int, 30), 10, "", 0, 10, "showing 1–10 of 30", -1, 10},
+		{"middle page", make([]int, 30), 10, "10", 10, 10, "showing 11–20 of 30", 0, 20},
+		{"last page", make([]int, 25), 10, "20", 20, 5, "showing 21–25 of 25", 10, -1},
+		{"unaligned offset", make([]int, 30), 10, "5", 5, 10, "showing 6–15 of 30", 0, 15},
+		{"offset past the end", make([]int, 30), 10, "100", 29, 1, "showing 30–30 of 30", 19, -1},
+		{"bad offset", make([]int, 30), 10, "x", 0, 10, "showing 1–10 of 30", -1, 10},
+		{"thousands", make([]int, 48213), 100, "", 0, 100, "showing 1–100 of 48,213", -1, 100},
+		{"iterator", count(30), 10, "", 0, 10, "first 10 shown", -1, 10},
+		{"iterator later", count(30), 10, "10", 10, 10, "showing 11–20", 0, 20},
+		{"iterator's end", count(25), 10, "20", 20, 5, "showing 21–25 of 25", 10, -1},
+		{"iterator's end on the first page", count(25), 30, "", 0, 25, "", -1, -1},
+		{"past an iterator's end", count(25), 10, "100", 0, 0, "none shown of 25", 15, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pw, offsets := windowOver(tc.v, tc.size, tc.off)
+			if len(offsets) != tc.n || (tc.n > 0 && offsets[0] != tc.first) {
+				t.Errorf("rendered offsets %v; want %d from %d", offsets, tc.n, tc.first)
+			}
+			if got := pw.truncated(); got != (tc.caption != "") {
+				t.Errorf("truncated = %t; want %t", got, tc.caption != "")
+			}
+			if tc.caption == "" {
+				return
+			}
+			if got := pw.caption(); got != tc.caption {
+				t.Errorf("caption = %q; want %q", got, tc.caption)
+			}
+			if prev, next := pw.prevNextOffsets(); prev != tc.prev || next != tc.next {
+				t.Errorf("prev, next = %d, %d; want %d, %d", prev, next, tc.prev, tc.next)
+			}
+		})
+	}
+
+	if pw, offsets := windowOver(make([]int, 10), 10, ""); pw.truncated() || len(offsets) != 10 {
+		t.Errorf("a sequence that fits is truncated = %t, rendering %d", pw.truncated(), len(offsets))
+	}
+	if pw, offsets := windowOver(make([]int, 300), 0, "50"); pw.truncated() || len(offsets) != 300 {
+		t.Errorf("without a page size truncated = %t, rendering %d", pw.truncated(), len(offsets))
+	}
+}
+
+func TestPageCaption(t *testing.T) {
+	r := newRenderer(&options{pageSize: 10}, "Test")
+	r.path = fieldPath{fieldStep("S")}
+	r.query = url.Values{"format": {"html"}, "o/Other": {"5"}, "o/S": {"10"}}
+	pw := r.pageWindow(reflect.ValueOf(make([]int, 30)))
+	capNode := createElemAtom(atom.Caption)
+	capNode.AppendChild(textNode("[]int"))
+	r.pageCaption(capNode, pw)
+
+	b := strings.Builder{}
+	if err := html.Render(&b, capNode); err != nil {
+		t.Fatal(err)
+	}
+	// the links keep the rest of the query (including the other tables'
+	// offsets) and lead back to the table
+	want := `<caption>[]int<br/>showing 11–20 of 30 ` +
+		`<a class="sp-page-link" href="?format=html&amp;o%2FOther=5#sp/S">‹ prev</a> ` +
+		`<a class="sp-page-link" href="?format=html&amp;o%2FOther=5&amp;o%2FS=20#sp/S">next ›</a></caption>`
+	if got := b.String(); got != want {
+		t.Errorf("caption:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got, want := r.pageCaptionText(pw), []string{
+		"showing 11–20 of 30",
+		"prev: /S?format=html&o%2FOther=5",
+		"next: /S?format=html&o%2FOther=5&o%2FS=20",
+	}; !slices.Equal(got, want) {
+		t.Errorf("text caption = %q; want %q", got, want)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	v := struct{ Ints []int }{Ints: make([]int, 30)}
+	for i := range v.Ints {
+		v.Ints[i] = i
+	}
+	rec := httptest.NewRecorder()
+	s := New("Test", func() struct{ Ints []int } { return v }, WithPageSize(10))
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?o/Ints=10", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"showing 11–20 of 30", `id="row/Ints[10]"`, `id="row/Ints[19]"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, body)
+		}
+	}
+	for _, unwanted := range []string{`id="row/Ints[9]"`, `id="row/Ints[20]"`} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("page contains %q, outside the window", unwanted)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package statuspage
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// renderState is the part of a renderer's state that tracks where it is
+// within the value being rendered, so it can be restored after a failure
+// part way through rendering a value.
+type renderState struct {
+	path        fieldPath
+	depth       int
+	format      valueFormat
+	thresholds  levelThresholds
+	subPages    bool
+	budgetDepth int
+	anchorValue bool
+	// visits is the length of the visit log, so the values recorded as
+	// visited after it (which didn't finish rendering) can be forgotten
+	visits int
+}
+
+func (r *renderer) saveState() renderState {
+	return renderState{
+		path: r.path, depth: r.depth, format: r.format, thresholds: r.thresholds, subPages: r.subPages,
+		budgetDepth: r.budget.depth, anchorValue: r.anchorValue, visits: len(r.visitLog),
+	}
+}
+
+func (r *renderer) restoreState(s renderState) {
+	r.path, r.depth, r.format, r.thresholds, r.subPages = s.path, s.depth, s.format, s.thresholds, s.subPages
+	r.budget.depth, r.anchorValue = s.budgetDepth, s.anchorValue
+	for _, key := range r.visitLog[s.visits:] {
+		delete(r.visited, key)
+	}
+	r.visitLog = r.visitLog[:s.visits]
+}
+
+// isolate runs gen (which renders the value at the current path), so that an
+// error or panic while rendering that value only affects that value: it's
+// replaced with the result of onErr, and the rest of the page renders as
+// usual. Failures are counted, so they can be flagged at the top of the page.
+func isolate[R any](r *renderer, gen func() (R, error), onErr func(msg string) R) (out R) {
+	saved := r.saveState()
+	fail := func(err error) R {
+		r.restoreState(saved)
+		p := r.path.String()
+		if p == "" {
+			p = "/"
+		}
+		msg := "failed to render " + p + ": " + err.Error()
+		r.errors = append(r.errors, msg)
+		return onErr(msg)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			out = fail(fmt.Errorf("panic: %v", p))
+		}
+	}()
+	res, err := gen()
+	if err != nil {
+		return fail(err)
+	}
+	return res
+}
+
+// errorMarker prefixes inline error messages
+const errorMarker = "⚠ "
+
+// errorNodes returns the inline marker for a value that failed to render
+func errorNodes(msg string) []*html.Node {
+	n := createElemClass(atom.Strong, "sp-error")
+	n.AppendChild(textNode(errorMarker + msg))
+	return []*html.Node{n}
+}
+
+// errorCountText describes the number of values that failed to render (empty
+// if none did)
+func (r *renderer) errorCountText() string {
+	switch len(r.errors) {
+	case 0:
+		return ""
+	case 1:
+		return errorMarker + "1 value failed to render"
+	default:
+		return errorMarker + strconv.Itoa(len(r.errors)) + " values failed to render"
+	}
+}
+
+// errorsHeader is set on responses where some values failed to render, with
+// the number of failures.
+const errorsHeader = "X-Status-Page-Errors"
+
+func setErrorsHeader(w http.ResponseWriter, rn *renderer) {
+	if len(rn.errors) > 0 {
+		w.Header().Set(errorsHeader, strconv.Itoa(len(rn.errors)))
+	}
+}
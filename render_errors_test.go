@@ -0,0 +1,61 @@
+package statuspage_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	statuspage "github.com/vimeo/go-status-page"
+	"golang.org/x/net/html"
+)
+
+// halfRendered renders shared, then fails
+type halfRendered struct{ shared *elem }
+
+func (h halfRendered) StatusPageNodes(ctx statuspage.RenderContext) ([]*html.Node, error) {
+	if _, err := ctx.Render(h.shared); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("out of ink")
+}
+
+type panicky struct{}
+
+func (panicky) String() string { panic("boom") }
+
+type failures struct {
+	Half   halfRendered `statuspage:"summary"`
+	Shared *elem
+	Panics panicky
+	After  int
+}
+
+func TestRenderErrors(t *testing.T) {
+	shared := &elem{A: 1, B: "shared"}
+	v := failures{Half: halfRendered{shared: shared}, Shared: shared, After: 42}
+	page := serveStatus(t, v, "")
+	checkTokens(t, page)
+
+	for _, want := range []string{
+		"failed to render /Half: out of ink",
+		"failed to render /Panics: panic: boom",
+		// (Half fails in the summary too)
+		"3 values failed to render",
+		// the failed value's anchor goes on its error, so the summary's
+		// link to it resolves
+		`<strong class="sp-error" id="sp/Half">`,
+		">42 (0x2a)<",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, page)
+		}
+	}
+	// Half's rendering of shared was discarded, so it's rendered in full
+	// where it's next reached, rather than linking back to the error
+	if strings.Contains(page, "see above") {
+		t.Errorf("page links back to a value that failed to render:\n%s", page)
+	}
+	if !strings.Contains(page, ">shared<") {
+		t.Errorf("Shared isn't rendered:\n%s", page)
+	}
+}
@@ -2,6 +2,7 @@ package statuspage
 
 import (
 	"fmt"
+	"iter"
 	"reflect"
 	"strconv"
 
@@ -9,6 +10,29 @@ import (
 	"golang.org/x/net/html/atom"
 )
 
+// seqElems iterates over the elements of the array, slice or iter.Seq v.
+// (reflect.Value.Seq yields offsets rather than elements for arrays and slices)
+func seqElems(v reflect.Value) iter.Seq[reflect.Value] {
+	if v.Kind() == reflect.Func {
+		return v.Seq()
+	}
+	return func(yield func(reflect.Value) bool) {
+		for _, ev := range v.Seq2() {
+			if !yield(ev) {
+				return
+			}
+		}
+	}
+}
+
+// seqElemType returns the element type of an array, slice or iter.Seq type
+func seqElemType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Func {
+		return t.In(0).In(0)
+	}
+	return t.Elem()
+}
+
 func sliceArrayValScalar(et reflect.Type) bool {
 	if et.Implements(stringerReflectType) {
 		return true
@@ -44,11 +68,13 @@ func sliceArrayValScalar(et reflect.Type) bool {
 		// TODO: swtich to et.CanSeq() || et.CanSeq2() and generate linky things
 		return true
 	default:
-		panic(fmt.Errorf("unhandled element kind: %s type %s", et.Kind(), et))
+		// Anything else gets a cell to itself (and an error if it can't
+		// be rendered there)
+		return true
 	}
 }
 
-func (s *Status[T]) genSliceArrayTable(v reflect.Value) ([]*html.Node, error) {
+func (r *renderer) genSliceArrayTable(v reflect.Value) ([]*html.Node, error) {
 	tbl := (*html.Node)(nil)
 	capNode := createElemAtom(atom.Caption)
 	capNode.AppendChild(textNode(v.Type().String()))
@@ -62,7 +88,7 @@ func (s *Status[T]) genSliceArrayTable(v reflect.Value) ([]*html.Node, error) {
 		}
 	case reflect.Slice:
 		if v.IsNil() {
-			return []*html.Node{textNode(v.Type().String() + "(nil)")}, nil
+			return []*html.Node{r.nilNode(v.Type())}, nil
 		}
 		capNode.AppendChild(createElemAtom(atom.Br))
 		capNode.AppendChild(textNode("len() = " + strconv.Itoa(v.Len())))
@@ -75,78 +101,105 @@ func (s *Status[T]) genSliceArrayTable(v reflect.Value) ([]*html.Node, error) {
 		} else if v.Type().CanSeq() {
 			capNode.AppendChild(textNode("iter.Seq: " + v.Type().In(0).In(0).String()))
 		} else {
-			panic("non-iter.Seq2? passed to genSliceArrayTable: " + v.Type().String())
+			return nil, fmt.Errorf("non-iterator func passed to genSliceArrayTable: %s", v.Type())
 		}
 	default:
-		panic(fmt.Errorf("non-slice/array kind: %s type %s", v.Kind(), v.Type()))
+		return nil, fmt.Errorf("non-slice/array kind: %s type %s", v.Kind(), v.Type())
 	}
 
-	if sliceArrayValScalar(v.Type().Elem()) {
-		sNode, sErr := s.scalarSliceArrayTable(v)
+	pw := r.pageWindow(v)
+	if r.sliceArrayValScalar(seqElemType(v.Type())) {
+		sNode, sErr := r.scalarSliceArrayTable(v, pw)
 		if sErr != nil {
 			return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), sErr)
 		}
 		tbl = sNode
+		addClass(tbl, seqTableClass(v))
+		r.pageCaption(capNode, pw)
 		tbl.InsertBefore(capNode, tbl.FirstChild)
+		r.finishTable(tbl)
 		return []*html.Node{tbl}, nil
 	}
-	elemType := v.Type().Elem()
+	elemType := seqElemType(v.Type())
 	switch elemType.Kind() {
-	case reflect.Map:
-		// We'll punt and put a table in a table for now. (we'll generate links to pages per-map later)
-		panic("map element-type not handled as scalar array 🤷 (should have been handled in sliceArrayValScalar)")
 	case reflect.Struct, reflect.Pointer:
-		stNode, stErr := s.structSliceArrayTable(v)
+		stNode, stErr := r.structSliceArrayTable(v, pw)
 		if stErr != nil {
 			return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), stErr)
 		}
 		tbl = stNode
 	case reflect.Array, reflect.Slice:
-		slNode, slErr := s.sliceArraySliceValTable(v)
+		slNode, slErr := r.sliceArraySliceValTable(v, pw)
 		if slErr != nil {
 			return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), slErr)
 		}
 		tbl = slNode
-	case reflect.Func:
-		// We're only here if the function is iterable
-		panic("iterator element-type not handled as scalar array 🤷 (should have been handled in sliceArrayValScalar)")
 	case reflect.Interface:
 		// This will be fun: we'll have to check whether all the implementations are scalars, structs, etc.
-		elemT, uniform := allIfaceSliceElemsSame(v)
-		if !uniform {
+		elemT, uniform := allIfaceSliceElemsSame(v, pw)
+		if !uniform || !isStructType(elemT) {
 			// Just put tables inside tables. It's ugly, but for now, it's not the worst thing we can do
-			stNode, stErr := s.scalarSliceArrayTable(v)
+			stNode, stErr := r.scalarSliceArrayTable(v, pw)
 			if stErr != nil {
 				return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), stErr)
 			}
 			tbl = stNode
 		} else {
-			stNode, stErr := s.ifaceSliceArrayTable(v, elemT)
+			stNode, stErr := r.ifaceSliceArrayTable(v, elemT, pw)
 			if stErr != nil {
 				return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), stErr)
 			}
 			tbl = stNode
 		}
+	default:
+		// Maps and iterators are handled by sliceArrayValScalar, but
+		// just in case, put tables inside tables.
+		stNode, stErr := r.scalarSliceArrayTable(v, pw)
+		if stErr != nil {
+			return nil, fmt.Errorf("failed to generate table for slice/array of type %s: %w", v.Type(), stErr)
+		}
+		tbl = stNode
 	}
+	addClass(tbl, seqTableClass(v))
 	// add the caption we created at the top (it must be the first child of the table)
 	// Fortunately, InsertBefore handles a nil `oldChild` arg as a request to append to the end, so the empty table
 	// case should work properly.
+	r.pageCaption(capNode, pw)
 	tbl.InsertBefore(capNode, tbl.FirstChild)
+	r.finishTable(tbl)
 
 	return []*html.Node{tbl}, nil
 }
 
-func (s *Status[T]) scalarSliceArrayTable(v reflect.Value) (*html.Node, error) {
+// seqTableClass returns the class of the table rendering the slice, array
+// or iter.Seq v
+func seqTableClass(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Array:
+		return "sp-array"
+	case reflect.Func:
+		return "sp-seq"
+	default:
+		return "sp-slice"
+	}
+}
+
+func (r *renderer) scalarSliceArrayTable(v reflect.Value, pw *pageWindow) (*html.Node, error) {
 	// One-column table for this slice, array or iter.Seq
-	tbl := createElemAtom(atom.Table)
-	offset := 0
-	for ev := range v.Seq() {
-		row := createElemAtom(atom.Tr)
+	tbl := createElemClass(atom.Table, "sp-table")
+	for offset, ev := range pw.elems(seqElems(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.AppendChild(r.truncatedRow(l, 1))
+			break
+		}
+		ascend := r.descend(indexStep(offset))
+		row := r.pathRow()
 		tbl.AppendChild(row)
-		e := createElemAtom(atom.Td)
+		e := createElemClass(atom.Td, "sp-value")
 		row.AppendChild(e)
 		// since we're working with a scalar-ish value, we can append children for all return values from genValSection here.
-		ns, rendErr := s.genValSection(ev)
+		ns, rendErr := r.genValSection(ev)
+		ascend()
 		if rendErr != nil {
 			return nil, fmt.Errorf("failed to render table element at index %d in slice/array of type %s: %w",
 				offset, v.Type(), rendErr)
@@ -154,7 +207,7 @@ func (s *Status[T]) scalarSliceArrayTable(v reflect.Value) (*html.Node, error) {
 		for _, n := range ns {
 			e.AppendChild(n)
 		}
-		offset++
+		setCellLevel(e)
 	}
 	return tbl, nil
 }
@@ -164,28 +217,36 @@ func arraySliceStructHeaderRow(t reflect.Type) (*html.Node, int, error) {
 		return arraySliceStructHeaderRow(t.Elem())
 	}
 	if t.Kind() != reflect.Struct {
-		panic(fmt.Errorf("non-struct type passed: %s", t))
+		return nil, 0, fmt.Errorf("non-struct type passed: %s", t)
 	}
 	row := createElemAtom(atom.Tr)
-	fs := reflect.VisibleFields(t)
-	nCols := 0
+	fs, fsErr := structFields(t)
+	if fsErr != nil {
+		return nil, 0, fsErr
+	}
 	for _, fs := range fs {
-		if shouldSkipField(fs) {
-			continue
-		}
-		h := createElemAtom(atom.Th)
+		h := createElemClass(atom.Th, "sp-field-name")
 		row.AppendChild(h)
-		h.Attr = []html.Attribute{{Key: atom.Alt.String(), Val: fs.Type.String()}}
-		h.AppendChild(textNode(fs.Name))
-		nCols++
+		// the field's help (if any) takes the place of its type
+		setAttr(h, atom.Title.String(), fs.Type.String())
+		h.AppendChild(textNode(fs.displayName()))
+		setHelp(h, &fs)
+	}
+	return row, len(fs), nil
+}
+
+// isStructType reports whether t is a struct type, or a pointer to one
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
 	}
-	return row, nCols, nil
+	return t.Kind() == reflect.Struct
 }
 
-// iterates over an array or slice, and returns a type+true if all elements are the one type or nil
-func allIfaceSliceElemsSame(v reflect.Value) (reflect.Type, bool) {
+// iterates over the elements of an array or slice within pw, and returns a type+true if all elements are the one type or nil
+func allIfaceSliceElemsSame(v reflect.Value, pw *pageWindow) (reflect.Type, bool) {
 	t := reflect.Type(nil)
-	for iv := range v.Seq() {
+	for _, iv := range pw.elems(seqElems(v)) {
 		if iv.IsNil() {
 			// interface has nil-type
 			continue
@@ -208,30 +269,54 @@ func allIfaceSliceElemsSame(v reflect.Value) (reflect.Type, bool) {
 	return t, t != nil
 }
 
-func (s *Status[T]) arraySliceStructDataRow(v reflect.Value, nCols int) (*html.Node, error) {
+func (r *renderer) arraySliceStructDataRow(v reflect.Value, nCols int) (*html.Node, error) {
 	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
 		if v.IsNil() {
 			row := createElemAtom(atom.Tr)
-			nilVal := createElemAtom(atom.Td)
-			nilVal.Attr = []html.Attribute{{Key: atom.Colspan.String(), Val: strconv.Itoa(nCols)}}
-			nilVal.AppendChild(textNode(v.Type().String() + "(nil)"))
+			nilVal := createElemClass(atom.Td, "sp-value")
+			setColspan(nilVal, nCols)
+			nilVal.AppendChild(r.nilNode(v.Type()))
+			row.AppendChild(nilVal)
+			return row, nil
+		}
+		ns, repeat, rowErr := r.visitOnce(v, func() ([]*html.Node, error) {
+			row, err := r.arraySliceStructDataRow(v.Elem(), nCols)
+			return []*html.Node{row}, err
+		})
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		if repeat {
+			// wrap the link back to the first occurrence in a row of its own
+			row := createElemAtom(atom.Tr)
+			cell := createElemClass(atom.Td, "sp-value")
+			setColspan(cell, nCols)
+			row.AppendChild(cell)
+			for _, n := range ns {
+				cell.AppendChild(n)
+			}
 			return row, nil
 		}
-		return s.arraySliceStructDataRow(v.Elem(), nCols)
+		return ns[0], nil
 	}
 	if v.Kind() != reflect.Struct {
-		panic(fmt.Errorf("non-struct type passed: %s", v.Type()))
+		return nil, fmt.Errorf("non-struct type passed: %s", v.Type())
 	}
 	row := createElemAtom(atom.Tr)
-	fs := reflect.VisibleFields(v.Type())
+	fs, fsErr := structFields(v.Type())
+	if fsErr != nil {
+		return nil, fsErr
+	}
 	for _, fs := range fs {
-		if shouldSkipField(fs) {
+		d := createElemClass(atom.Td, "sp-value")
+		row.AppendChild(d)
+		if fs.omitted(v) {
 			continue
 		}
-		d := createElemAtom(atom.Td)
-		row.AppendChild(d)
 		fd := v.FieldByIndex(fs.Index)
-		ns, nErr := s.genValSection(fd)
+		ascend := r.enterField(&fs)
+		ns, nErr := r.genValSection(fd)
+		ascend()
 		if nErr != nil {
 			return nil, fmt.Errorf("failed to generate element for field %q of type %s: %w",
 				fs.Name, fd.Type(), nErr)
@@ -239,21 +324,31 @@ func (s *Status[T]) arraySliceStructDataRow(v reflect.Value, nCols int) (*html.N
 		for _, n := range ns {
 			d.AppendChild(n)
 		}
+		setCellLevel(d)
 	}
 	return row, nil
 }
 
-func (s *Status[T]) structSliceArrayTable(v reflect.Value) (*html.Node, error) {
-	tbl := createElemAtom(atom.Table)
-	h, nCols, hErr := arraySliceStructHeaderRow(v.Type().Elem())
+func (r *renderer) structSliceArrayTable(v reflect.Value, pw *pageWindow) (*html.Node, error) {
+	tbl := createElemClass(atom.Table, "sp-table")
+	h, nCols, hErr := arraySliceStructHeaderRow(seqElemType(v.Type()))
 	if hErr != nil {
 		return nil, fmt.Errorf("failed to generate header for type %s: %w", v.Type(), hErr)
 	}
 	tbl.AppendChild(h)
-	for offset, ev := range v.Seq2() {
-		dr, drErr := s.arraySliceStructDataRow(ev, nCols)
+	for offset, ev := range pw.elems(seqElems(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.AppendChild(r.truncatedRow(l, nCols))
+			break
+		}
+		ascend := r.descend(indexStep(offset))
+		dr, drErr := r.arraySliceStructDataRow(ev, nCols)
+		if drErr == nil {
+			setAttr(dr, atom.Id.String(), rowID(r.path))
+		}
+		ascend()
 		if drErr != nil {
-			return nil, fmt.Errorf("failed to generate row %d for type %s: %w", offset.Int(), v.Type(), drErr)
+			return nil, fmt.Errorf("failed to generate row %d for type %s: %w", offset, v.Type(), drErr)
 		}
 		tbl.AppendChild(dr)
 		// TODO: should we have an index column?
@@ -261,36 +356,43 @@ func (s *Status[T]) structSliceArrayTable(v reflect.Value) (*html.Node, error) {
 	return tbl, nil
 }
 
-func (s *Status[T]) ifaceSliceArrayTable(v reflect.Value, uniformType reflect.Type) (*html.Node, error) {
-	tbl := createElemAtom(atom.Table)
+func (r *renderer) ifaceSliceArrayTable(v reflect.Value, uniformType reflect.Type, pw *pageWindow) (*html.Node, error) {
+	tbl := createElemClass(atom.Table, "sp-table")
 	h, nCols, hErr := arraySliceStructHeaderRow(uniformType)
 	if hErr != nil {
 		return nil, fmt.Errorf("failed to generate header for type %s: %w", v.Type(), hErr)
 	}
 	tbl.AppendChild(h)
-	offset := 0
-	for ev := range v.Seq() {
-		dr, drErr := s.arraySliceStructDataRow(ev, nCols)
+	for offset, ev := range pw.elems(seqElems(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.AppendChild(r.truncatedRow(l, nCols))
+			break
+		}
+		ascend := r.descend(indexStep(offset))
+		dr, drErr := r.arraySliceStructDataRow(ev, nCols)
+		if drErr == nil {
+			setAttr(dr, atom.Id.String(), rowID(r.path))
+		}
+		ascend()
 		if drErr != nil {
 			return nil, fmt.Errorf("failed to generate row %d for type %s: %w", offset, v.Type(), drErr)
 		}
 		tbl.AppendChild(dr)
 		// TODO: should we have an index column?
-		offset++
 	}
 	return tbl, nil
 }
 
 // handle two-dimensional arrays/slices
-func (s *Status[T]) sliceArraySliceValTable(v reflect.Value) (*html.Node, error) {
-	// get the max slice-length
-	// TODO: define a max width where we start doing something clever with omitting middle members and generating links to the relevant entries
+func (r *renderer) sliceArraySliceValTable(v reflect.Value, pw *pageWindow) (*html.Node, error) {
+	// get the max slice-length (rows are cut off at the page size, with a
+	// cell standing in for the rest)
 	maxElemLen := 0
 	switch v.Kind() {
 	case reflect.Array:
 		maxElemLen = v.Type().Len()
 	case reflect.Slice:
-		for ev := range v.Seq() {
+		for _, ev := range pw.elems(seqElems(v)) {
 			if ev.IsNil() {
 				// nil, keep going
 				continue
@@ -298,24 +400,35 @@ func (s *Status[T]) sliceArraySliceValTable(v reflect.Value) (*html.Node, error)
 			maxElemLen = max(ev.Len(), maxElemLen)
 		}
 	}
+	maxCols := -1
+	if pw.paged {
+		maxCols = pw.end - pw.start
+		maxElemLen = min(maxElemLen, maxCols+1)
+	}
 
-	tbl := createElemAtom(atom.Table)
-	offset := 0
+	tbl := createElemClass(atom.Table, "sp-table")
 	// now, we can generate the table
-	for ev := range v.Seq() {
-		row := createElemAtom(atom.Tr)
+ROWITER:
+	for offset, ev := range pw.elems(seqElems(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.AppendChild(r.truncatedRow(l, maxElemLen))
+			break
+		}
+		ascendRow := r.descend(indexStep(offset))
+		row := r.pathRow()
 		tbl.AppendChild(row)
 		if ev.Kind() != reflect.Array {
 			// if it's not an array, iteratively unwrap
 			for {
 				if ev.IsNil() {
 					// TODO: include option for offset column and column-headings
-					nilVal := createElemAtom(atom.Td)
-					nilVal.Attr = []html.Attribute{{Key: atom.Colspan.String(), Val: strconv.Itoa(maxElemLen)}}
-					nilVal.AppendChild(textNode(ev.Type().String() + "(nil)"))
+					nilVal := createElemClass(atom.Td, "sp-value")
+					setColspan(nilVal, maxElemLen)
+					nilVal.AppendChild(r.nilNode(ev.Type()))
 					row.AppendChild(nilVal)
 
-					continue
+					ascendRow()
+					continue ROWITER
 				}
 				// do the loop check at the bottom so slices get the nil-check as well :)
 				if ev.Kind() == reflect.Array || ev.Kind() == reflect.Slice {
@@ -328,11 +441,17 @@ func (s *Status[T]) sliceArraySliceValTable(v reflect.Value) (*html.Node, error)
 				ev = ev.Elem()
 			}
 		}
-		colOffset := 0
-		for colVal := range ev.Seq() {
-			colElem := createElemAtom(atom.Td)
+		for colOffset := range ev.Len() {
+			if colOffset == maxCols {
+				row.AppendChild(r.moreCell(ev.Len() - maxCols))
+				break
+			}
+			colVal := ev.Index(colOffset)
+			colElem := createElemClass(atom.Td, "sp-value")
 			row.AppendChild(colElem)
-			ns, tblCellGenErr := s.genValSection(colVal)
+			ascend := r.descend(indexStep(colOffset))
+			ns, tblCellGenErr := r.genValSection(colVal)
+			ascend()
 			if tblCellGenErr != nil {
 				return nil, fmt.Errorf("failed to generate html for value at offset [%d][%d] in array/slice of type %s: %w",
 					offset, colOffset, v.Type(), tblCellGenErr)
@@ -340,9 +459,8 @@ func (s *Status[T]) sliceArraySliceValTable(v reflect.Value) (*html.Node, error)
 			for _, n := range ns {
 				colElem.AppendChild(n)
 			}
-			colOffset++
 		}
-		offset++
+		ascendRow()
 	}
 	return tbl, nil
 }
@@ -1,80 +1,705 @@
 package statuspage
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
 // Status implements net/http.Handler, and provides a status page for the value returned by cb
+//
+// The page is HTML by default. Requests with an Accept header preferring
+// application/json (or a format=json query parameter) get a JSON
+// rendering of the same value instead, and requests preferring text/plain
+// (or format=text), as well as command-line clients such as curl that
+// don't express a preference, get plain-text tables. format=openmetrics
+// (or an Accept header preferring application/openmetrics-text) exposes the
+// numeric, bool and time.Duration values within T in the OpenMetrics text
+// format; see openMetricsTagKey for the struct tags that control it.
+//
+// Values nested within T can be viewed on their own by appending their path
+// to the URL the Status is served at (e.g. /status/Backends/us-east/Conns[3]),
+// which works in every format. See WithBasePath and WithMaxInlineDepth.
+// Within HTML pages, values can be linked to by fragment: sections are
+// #sec/<path>, tables #sp/<path> and rows #row/<path> (e.g.
+// #sec/Backends), and a table of contents links to the sections.
+//
+// If the callback fails (only possible with NewCtx) or times out (see
+// WithTimeout), the response is an error page (or JSON error object) with a
+// 503 status.
+//
+// Fields can be tagged as health checks (see statusPageTagKey), and T can
+// implement HealthChecker. If either fails, pages for the latest snapshot
+// are served with a 503 (other than OpenMetrics) and open with a banner
+// listing the failing checks. The _healthz sub-path (e.g.
+// /status/_healthz) serves just the verdict.
+//
+// Fields tagged with summary are shown in cards at the top of the page. The
+// _summary sub-path serves just them, as JSON or plain text (for chat bots
+// and the like).
+//
+// HTML pages are styled by a default stylesheet (with a dark mode), served
+// from the _static sub-path, or inlined if the Status can't tell where it's
+// mounted. See WithStylesheet for restyling them, and WithLayout for
+// wrapping them in a page of your own.
+//
+// Concurrent requests share a single call to the callback. See
+// WithCacheTTL and WithMaxConcurrentRenders for bounding the work done under
+// heavier load, and WithLiveUpdates for pages that update themselves.
 type Status[T any] struct {
 	title string
-	cb    func() T
+	cb    func(ctx context.Context) (T, error)
+	opts  options
+	cache statusCache[T]
+	// history holds the snapshots taken by the sampler (nil unless
+	// WithHistory was used)
+	history *history[T]
+	// trends holds the trends of fields tagged with trend (nil if T has
+	// none)
+	trends *trendStore
+	// stopSamplers stops the background samplers, which are tracked by
+	// samplers
+	stopSamplers context.CancelFunc
+	samplers     sync.WaitGroup
 }
 
 // New constructs a new Status[T] with the passed callback.
-func New[T any](title string, cb func() T) *Status[T] {
-	return &Status[T]{title: title, cb: cb}
+func New[T any](title string, cb func() T, opts ...Option) *Status[T] {
+	return NewCtx(title, func(context.Context) (T, error) { return cb(), nil }, opts...)
+}
+
+// NewCtx constructs a new Status[T] with a callback that may fail. Since
+// concurrent requests share calls to the callback, its context carries the
+// values of the request that triggered the call, but not its cancellation;
+// it's bounded by WithTimeout instead.
+func NewCtx[T any](title string, cb func(ctx context.Context) (T, error), opts ...Option) *Status[T] {
+	s := &Status[T]{title: title, cb: cb, opts: defaultOptions()}
+	for _, o := range opts {
+		o(&s.opts)
+	}
+	if s.opts.maxConcurrentRenders > 0 {
+		s.cache.renders = make(chan struct{}, s.opts.maxConcurrentRenders)
+	}
+	if mayHaveTrends(reflect.TypeFor[T]()) && s.opts.trendPoints > 0 {
+		s.trends = newTrendStore(s.opts.trendPoints)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopSamplers = cancel
+	if s.opts.historyInterval > 0 && s.opts.historySize > 0 {
+		s.history = newHistory[T](s.opts.historySize, &s.opts)
+		s.startSampler(ctx, s.opts.historyInterval, s.history.record)
+	}
+	if s.trends != nil && s.opts.trendInterval > 0 {
+		// trends are recorded whenever the callback's called, so
+		// there's nothing more to do with the snapshots
+		s.startSampler(ctx, s.opts.trendInterval, func(*snapshot[T]) {})
+	}
+	return s
+}
+
+// renderer holds the state for a single rendering of a value
+type renderer struct {
+	opts  *options
+	title string
+	// basePath is the (escaped) URL path the Status is served at, and
+	// subPages indicates whether it's known, so we can link to sub-pages.
+	basePath string
+	subPages bool
+	// path is the path to the value currently being rendered
+	path fieldPath
+	// depth is the number of tables enclosing the value currently being rendered
+	depth int
+	// visited records the first occurrence of each pointer, map and slice
+	// rendered so far, and visitLog the order they were recorded in (so
+	// those recorded while rendering a value that fails can be forgotten)
+	visited  map[visitKey]fieldPath
+	visitLog []visitKey
+	// onStack holds the pointers, maps and slices enclosing the value
+	// currently being rendered (used by walkers that only break cycles)
+	onStack map[visitKey]fieldPath
+	// budget tracks usage against opts.limits
+	budget renderBudget
+	// errors holds the messages for the values that failed to render
+	errors []string
+	// query holds the request's query parameters (for pagination links)
+	query url.Values
+	// format is the number format for the field currently being rendered
+	format valueFormat
+	// thresholds are the warn and crit thresholds for the field currently
+	// being rendered
+	thresholds levelThresholds
+	// crits counts the values rendered so far that are past their crit
+	// thresholds
+	crits int
+	// anchorValue is set (by enterField) when the value about to be
+	// rendered needs an anchor, even if it wouldn't otherwise get one
+	anchorValue bool
+	// snapshotAt is when the (cached or historical) value being rendered
+	// was loaded, or the zero time if it's neither
+	snapshotAt time.Time
+	// historical is set when rendering a snapshot from the history
+	historical bool
+	// timeline holds the times of the snapshots in the history, oldest first
+	timeline []time.Time
+	// diffSince is the time of the snapshot a diff is against (zero unless
+	// rendering a diff)
+	diffSince time.Time
+	// trends holds the trends to draw sparklines for (nil if there are
+	// none, or the value rendered isn't the live one they lead up to)
+	trends *trendStore
+	// health is the verdict of the health checks for the banner (nil if
+	// there are none, or the banner doesn't apply)
+	health *healthVerdict
+}
+
+func newRenderer(opts *options, title string) *renderer {
+	return &renderer{
+		opts:    opts,
+		title:   title,
+		visited: map[visitKey]fieldPath{},
+		onStack: map[visitKey]fieldPath{},
+	}
+}
+
+func (s *Status[T]) newRenderer(basePath string, subPages bool, path fieldPath) *renderer {
+	rn := newRenderer(&s.opts, s.title)
+	rn.basePath, rn.subPages, rn.path = basePath, subPages, path
+	if s.history != nil {
+		rn.timeline = s.history.times()
+	}
+	rn.trends = s.trends
+	return rn
+}
+
+// patternBasePath extracts the fixed path prefix from a net/http.ServeMux
+// pattern (e.g. "GET /status/{path...}" -> "/status")
+func patternBasePath(pattern string) (string, bool) {
+	if _, p, hasMethod := strings.Cut(pattern, " "); hasMethod {
+		pattern = strings.TrimSpace(p)
+	}
+	// strip off any host
+	slash := strings.IndexByte(pattern, '/')
+	if slash < 0 {
+		return "", false
+	}
+	pattern = pattern[slash:]
+	if wildcard := strings.IndexByte(pattern, '{'); wildcard >= 0 {
+		pattern = pattern[:wildcard]
+	}
+	return strings.TrimSuffix(pattern, "/"), true
+}
+
+// requestPaths splits the request's (escaped) URL path into the path the
+// Status is served at and the sub-path identifying the value to render.
+// subPages is false if we can't tell where the Status is mounted, in which
+// case the whole URL path is treated as the root.
+func (s *Status[T]) requestPaths(r *http.Request) (basePath, subPath string, subPages bool) {
+	escaped := r.URL.EscapedPath()
+	basePath, subPages = s.opts.basePath, s.opts.basePath != ""
+	if !subPages {
+		basePath, subPages = patternBasePath(r.Pattern)
+	}
+	if !subPages {
+		return strings.TrimSuffix(escaped, "/"), "", false
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+	// If the path doesn't start with the base path, assume something like
+	// http.StripPrefix already removed it.
+	return basePath, strings.TrimPrefix(escaped, basePath), true
 }
 
 func (s *Status[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	v := s.cb()
-	rootN, genErr := s.genTopLevelHTML(reflect.ValueOf(v))
+	basePath, subPath, subPages := s.requestPaths(r)
+	if subPath == healthzPath || (!subPages && strings.HasSuffix(basePath, healthzPath)) {
+		s.serveHealthz(w, r)
+		return
+	}
+	if subPath == summaryPath {
+		s.serveSummary(w, r, basePath, subPages)
+		return
+	}
+	if name, isStatic := strings.CutPrefix(subPath, staticPathPrefix); isStatic {
+		serveStatic(w, r, name)
+		return
+	}
+	steps, parseErr := parseFieldPath(subPath)
+	if parseErr != nil {
+		http.Error(w, parseErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mode := r.URL.Query().Get(liveQueryParam); mode != "" && s.opts.liveInterval > 0 {
+		s.serveLive(w, r, mode, steps)
+		return
+	}
+
+	format := negotiateFormat(r)
+	at := r.URL.Query().Get(historyQueryParam)
+	var snap *snapshot[T]
+	if at != "" {
+		hs, code, histErr := s.historySnapshot(at)
+		if histErr != nil {
+			http.Error(w, histErr.Error(), code)
+			return
+		}
+		snap = hs
+	} else {
+		var loadErr error
+		snap, loadErr = s.snapshot(r.Context())
+		if loadErr != nil {
+			serveError(w, s.newRenderer(basePath, subPages, nil), format, http.StatusServiceUnavailable, loadErr)
+			return
+		}
+	}
+	if at == "" && s.history != nil && format == formatHTML {
+		setSeenCookie(w, basePath, subPages, snap.loadedAt)
+	}
+	// diffs depend on the viewer's cookies, so they aren't cached
+	diffSince := r.URL.Query().Get(diffQueryParam)
+	key := outputKey{format: format, basePath: basePath, subPath: subPath, query: r.URL.RawQuery}
+	if out, cached := snap.cachedOutput(key); cached && diffSince == "" {
+		out.writeTo(w, snap.loadedAt)
+		return
+	}
+
+	release, acquired := s.acquireRender()
+	if !acquired {
+		w.Header().Set("Retry-After", strconv.Itoa(renderRetryAfter))
+		http.Error(w, "too many status page renders in progress", http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+	target, path, resolveErr := resolvePath(reflect.ValueOf(&snap.v).Elem(), steps)
+	if resolveErr != nil {
+		http.Error(w, resolveErr.Error(), http.StatusNotFound)
+		return
+	}
+	rn := s.newRenderer(basePath, subPages, path)
+	rn.query = r.URL.Query()
+	if diffSince != "" {
+		base, code, baseErr := s.diffBase(r, diffSince)
+		if baseErr != nil {
+			http.Error(w, baseErr.Error(), code)
+			return
+		}
+		// If the value didn't exist back then, it's all new
+		baseTarget, _, _ := resolvePath(reflect.ValueOf(&base.v).Elem(), steps)
+		rn.diffSince = base.loadedAt
+		serveDiff(w, rn, format, baseTarget, target)
+		return
+	}
+	hv, checksHealth := s.healthVerdict(snap)
+	if checksHealth {
+		rn.health = hv
+	}
+	if at != "" {
+		// Historical snapshots aren't cached, since there could be a lot
+		// of them
+		rn.snapshotAt, rn.historical = snap.loadedAt, true
+		// the trends run up to now, not to the snapshot
+		rn.trends = nil
+		serveFormat(w, rn, format, target)
+		return
+	}
+	// The latest snapshot's health decides the status code, so load
+	// balancers can check the page itself (metrics scrapes aren't health
+	// checks, so they're left alone)
+	code := http.StatusOK
+	if !hv.healthy() && format != formatOpenMetrics {
+		code = http.StatusServiceUnavailable
+	}
+	if s.opts.cacheTTL <= 0 {
+		serveFormat(&statusCodeWriter{ResponseWriter: w, code: code}, rn, format, target)
+		return
+	}
+	rn.snapshotAt = snap.loadedAt
+	out := newBufferedResponse()
+	serveFormat(&statusCodeWriter{ResponseWriter: out, code: code}, rn, format, target)
+	if out.code == code {
+		snap.cacheOutput(key, out)
+	}
+	out.writeTo(w, snap.loadedAt)
+}
+
+// serveFormat renders v in the requested format
+func serveFormat(w http.ResponseWriter, rn *renderer, format outputFormat, target reflect.Value) {
+	switch format {
+	case formatJSON:
+		serveJSON(w, rn, target)
+	case formatText:
+		serveText(w, rn, target)
+	case formatOpenMetrics:
+		serveOpenMetrics(w, rn, target)
+	default:
+		serveHTML(w, rn, target)
+	}
+}
+
+// truncatedHeader is set on responses whose rendering hit one of the
+// RenderLimits, listing the limits reached.
+const truncatedHeader = "X-Status-Page-Truncated"
+
+func setTruncatedHeader(w http.ResponseWriter, rn *renderer) {
+	if hit := rn.limitsHit(); hit != "" {
+		w.Header().Set(truncatedHeader, hit)
+	}
+}
+
+func serveHTML(w http.ResponseWriter, rn *renderer, v reflect.Value) {
+	if rn.opts.layout != nil {
+		serveLayout(w, rn, v)
+		return
+	}
+	rootN, genErr := rn.genTopLevelHTML(v)
 	if genErr != nil {
-		http.Error(w, fmt.Sprintf("failed to generate HTML for struct of type %T: %s", v, genErr), 500)
+		http.Error(w, fmt.Sprintf("failed to generate HTML for struct of type %s: %s", v.Type(), genErr), 500)
 		return
 	}
+	setTruncatedHeader(w, rn)
+	setErrorsHeader(w, rn)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if renderErr := html.Render(w, rootN); renderErr != nil {
-		http.Error(w, fmt.Sprintf("failed to render response for struct of type %T: %s", v, renderErr), 500)
+		http.Error(w, fmt.Sprintf("failed to render response for struct of type %s: %s", v.Type(), renderErr), 500)
+	}
+}
+
+func serveJSON(w http.ResponseWriter, rn *renderer, v reflect.Value) {
+	jv, genErr := rn.genJSONVal(v)
+	if genErr != nil {
+		http.Error(w, fmt.Sprintf("failed to generate JSON for struct of type %s: %s", v.Type(), genErr), 500)
+		return
+	}
+	setTruncatedHeader(w, rn)
+	setErrorsHeader(w, rn)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(jv); encErr != nil {
+		http.Error(w, fmt.Sprintf("failed to encode JSON response for struct of type %s: %s", v.Type(), encErr), 500)
+	}
+}
+
+func serveText(w http.ResponseWriter, rn *renderer, v reflect.Value) {
+	buf := bytes.Buffer{}
+	if genErr := rn.genTopLevelText(&buf, v); genErr != nil {
+		http.Error(w, fmt.Sprintf("failed to generate text for struct of type %s: %s", v.Type(), genErr), 500)
+		return
+	}
+	setTruncatedHeader(w, rn)
+	setErrorsHeader(w, rn)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func serveOpenMetrics(w http.ResponseWriter, rn *renderer, v reflect.Value) {
+	mc := newMetricCollector(rn.opts)
+	collect := func() (err error) {
+		// There's no partial result for metrics, but a panic should
+		// still produce an error response
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return mc.collect(v, nil, nil, metricTag{})
+	}
+	if collectErr := collect(); collectErr != nil {
+		http.Error(w, fmt.Sprintf("failed to collect metrics for struct of type %s: %s", v.Type(), collectErr), 500)
+		return
 	}
+	buf := bytes.Buffer{}
+	mc.writeTo(&buf)
+	if hit := mc.budget.limitsHit(); hit != "" {
+		w.Header().Set(truncatedHeader, hit)
+	}
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	w.Write(buf.Bytes())
 }
 
 // GenHTMLNodes makes it easy to leverage this package for a more structured/custom status page
 func GenHTMLNodes[T any](val T) ([]*html.Node, error) {
-	s := Status[T]{}
-	return s.genValSection(reflect.ValueOf(val))
+	opts := defaultOptions()
+	return newRenderer(&opts, "").genValSection(reflect.ValueOf(val))
+}
+
+// breadcrumbs returns the navigation trail from the root of the page to the
+// value being rendered, or nil at the root.
+func (r *renderer) breadcrumbs() *html.Node {
+	if len(r.path) == 0 {
+		return nil
+	}
+	nav := createElemClass(atom.Nav, "sp-breadcrumbs")
+	setAttr(nav, "aria-label", "Breadcrumbs")
+	link := func(p fieldPath, label string) {
+		a := createElemAtom(atom.A)
+		a.Attr = append(a.Attr, html.Attribute{Key: atom.Href.String(), Val: r.pathURL(p)})
+		a.AppendChild(textNode(label))
+		nav.AppendChild(a)
+		nav.AppendChild(textNode(" › "))
+	}
+	link(nil, r.title)
+	for i := range len(r.path) - 1 {
+		link(r.path[:i+1], r.path[i].label)
+	}
+	current := createElemAtom(atom.Span)
+	setAttr(current, "aria-current", "page")
+	current.AppendChild(textNode(r.path[len(r.path)-1].label))
+	nav.AppendChild(current)
+	return nav
 }
 
-func (s *Status[T]) genTopLevelHTML(v reflect.Value) (*html.Node, error) {
-	root := html.Node{Type: html.DocumentNode}
+// pathURL returns the URL path of the sub-page for the value at p
+func (r *renderer) pathURL(p fieldPath) string {
+	if len(p) == 0 {
+		return r.basePath + "/"
+	}
+	return r.basePath + p.String()
+}
+
+// pageTitle returns the title of the page, including the path for sub-pages
+func (r *renderer) pageTitle() string {
+	if len(r.path) == 0 {
+		return r.title
+	}
+	labels := make([]string, 0, len(r.path))
+	for _, st := range r.path {
+		labels = append(labels, st.label)
+	}
+	return r.title + ": " + strings.Join(labels, " › ")
+}
+
+// pageLang is the language of the pages' (fixed) text
+const pageLang = "en"
+
+// htmlDocument returns a new HTML document for the current page, along with
+// its main element, which the page's content goes in. The body holds the
+// page's header (its heading and navigation) ahead of it.
+func (r *renderer) htmlDocument() (root, main *html.Node) {
+	root = &html.Node{Type: html.DocumentNode}
 	root.AppendChild(&html.Node{
 		Type:     html.DoctypeNode,
 		DataAtom: atom.Html,
 		Data:     atom.Html.String(),
 	})
 	htmlElem := createElemAtom(atom.Html)
+	setAttr(htmlElem, atom.Lang.String(), pageLang)
 	root.AppendChild(htmlElem)
 
 	head := createElemAtom(atom.Head)
-
 	htmlElem.AppendChild(head)
+	charset := createElemAtom(atom.Meta)
+	setAttr(charset, atom.Charset.String(), "utf-8")
+	head.AppendChild(charset)
+	viewport := createElemAtom(atom.Meta)
+	setAttr(viewport, atom.Name.String(), "viewport")
+	setAttr(viewport, atom.Content.String(), "width=device-width, initial-scale=1")
+	head.AppendChild(viewport)
 	title := createElemAtom(atom.Title)
-	title.AppendChild(textNode(s.title))
+	title.AppendChild(textNode(r.pageTitle()))
 	head.AppendChild(title)
-	header := createElemAtom(atom.H1)
-	header.AppendChild(textNode(s.title))
-	head.AppendChild(header)
 
-	body := createElemAtom(atom.Body)
+	body := createElemClass(atom.Body, "sp-page")
 	htmlElem.AppendChild(body)
+	r.addHeadElems(head, body)
+	header := createElemClass(atom.Header, "sp-page-header")
+	body.AppendChild(header)
+	heading := createElemAtom(atom.H1)
+	heading.AppendChild(textNode(r.title))
+	header.AppendChild(heading)
+	for _, nav := range r.pageNavs() {
+		header.AppendChild(nav)
+	}
+	main = createElemClass(atom.Main, "sp-main")
+	body.AppendChild(main)
+	return root, main
+}
+
+// addHeadElems adds the stylesheets and scripts the page's content relies on
+// to head. page is the element holding the content.
+func (r *renderer) addHeadElems(head, page *html.Node) {
+	r.addStylesheets(head)
+	addCopyLinkScript(head)
+	if !r.historical && r.diffSince.IsZero() {
+		r.addLiveScript(head, page)
+	}
+}
+
+// pageNavs returns the page's navigation: the snapshot timeline (if there's
+// a history) and the breadcrumbs (on sub-pages).
+func (r *renderer) pageNavs() []*html.Node {
+	navs := []*html.Node{}
+	if r.timeline != nil {
+		navs = append(navs, r.timelineNav())
+	}
+	if nav := r.breadcrumbs(); nav != nil {
+		navs = append(navs, nav)
+	}
+	return navs
+}
+
+func (r *renderer) genTopLevelHTML(v reflect.Value) (*html.Node, error) {
+	root, main := r.htmlDocument()
+	body := main.Parent
+
+	ps, genErr := r.genPageSections(v)
+	if genErr != nil {
+		return nil, genErr
+	}
+	if ps.notes != nil {
+		main.AppendChild(ps.notes)
+	}
+	if ps.summary != nil {
+		main.AppendChild(ps.summary)
+	}
+	if toc := genTOC(ps.values); toc != nil {
+		body.InsertBefore(toc, main)
+		addClass(body, "sp-with-toc")
+	}
+	for _, sec := range ps.values {
+		// add a horizontal rule to separate sections
+		main.AppendChild(createElemAtom(atom.Hr))
+		main.AppendChild(sec)
+	}
+	if ps.footer != nil {
+		body.AppendChild(ps.footer)
+	}
+	if r.opts.liveInterval > 0 {
+		upd, stampErr := ps.stamp()
+		if stampErr != nil {
+			return nil, stampErr
+		}
+		setAttr(body, liveHashAttr, upd.Hash)
+	}
 
-	// TODO: add CSS references, etc. to the HEAD element
-	bodyNodes, bodyGenErr := s.genValSection(v)
+	return root, nil
+}
+
+// pageSections holds the parts of a page's body that are updated
+// independently in live mode. Each has a stable id.
+type pageSections struct {
+	// notes holds the notes at the top of the page (e.g. the number of
+	// values that failed to render). It's nil if there are none, unless
+	// live mode is on (so there's somewhere to put notes that appear later).
+	notes *html.Node
+	// summary holds the summary cards (nil if there are none)
+	summary *html.Node
+	// values holds the sections rendering the value itself
+	values []*html.Node
+	// footer notes that rendering was truncated (nil if it wasn't)
+	footer *html.Node
+}
+
+// all returns all of ps's sections, in page order
+func (ps *pageSections) all() []*html.Node {
+	out := make([]*html.Node, 0, len(ps.values)+3)
+	if ps.notes != nil {
+		out = append(out, ps.notes)
+	}
+	if ps.summary != nil {
+		out = append(out, ps.summary)
+	}
+	out = append(out, ps.values...)
+	if ps.footer != nil {
+		out = append(out, ps.footer)
+	}
+	return out
+}
+
+// Ids of the page sections other than those rendering the value
+const (
+	notesSectionID  = "sp-notes"
+	footerSectionID = "sp-footer"
+)
+
+// sectionID returns the id of the section rendering the value at p (or,
+// for sections without a path of their own, the i'th such section within it).
+func sectionID(p fieldPath, i int) string {
+	if i > 0 {
+		return "sec" + p.String() + "~" + strconv.Itoa(i)
+	}
+	return "sec" + p.String()
+}
+
+// genPageSections renders v into the sections of a page's body
+func (r *renderer) genPageSections(v reflect.Value) (*pageSections, error) {
+	bodyNodes, bodyGenErr := r.genValSection(v)
 	if bodyGenErr != nil {
 		return nil, bodyGenErr
 	}
+	ps := &pageSections{}
+	if len(r.path) == 0 {
+		// the summary is rendered after the rest of the page, so its
+		// values don't take the place of the originals on the page
+		summary, summaryErr := r.genSummary(v)
+		if summaryErr != nil {
+			return nil, summaryErr
+		}
+		ps.summary = summary
+	}
+
+	noteNodes := []*html.Node{}
+	if r.health != nil {
+		noteNodes = append(noteNodes, r.healthBanner())
+	}
+	if errs := r.errorCountText(); errs != "" {
+		p := createElemAtom(atom.P)
+		strong := createElemAtom(atom.Strong)
+		strong.AppendChild(textNode(errs))
+		p.AppendChild(strong)
+		noteNodes = append(noteNodes, p)
+	}
+	if !r.snapshotAt.IsZero() {
+		p := createElemAtom(atom.P)
+		p.AppendChild(textNode("Snapshot as of "))
+		t := createElemAtom(atom.Time)
+		t.Attr = append(t.Attr, html.Attribute{Key: atom.Datetime.String(), Val: r.snapshotAt.Format(time.RFC3339Nano)})
+		t.AppendChild(textNode(r.snapshotAt.Format(snapshotTimeLayout)))
+		p.AppendChild(t)
+		p.AppendChild(textNode(r.snapshotSource()))
+		noteNodes = append(noteNodes, p)
+	}
+	if len(noteNodes) > 0 || r.opts.liveInterval > 0 {
+		ps.notes = createElemClass(atom.Div, "sp-notes")
+		setAttr(ps.notes, atom.Id.String(), notesSectionID)
+		for _, n := range noteNodes {
+			ps.notes.AppendChild(n)
+		}
+	}
+
+	// Sections that already have a section id (the struct fields that get
+	// tables of their own) are used as-is; anything else is wrapped in a
+	// section of its own.
+	unnamed := 0
 	for _, bn := range bodyNodes {
-		// add a horizontal rule to separate sections
-		body.AppendChild(createElemAtom(atom.Hr))
-		body.AppendChild(bn)
+		if id, ok := attr(bn, atom.Id.String()); ok && strings.HasPrefix(id, "sec") {
+			ps.values = append(ps.values, bn)
+			continue
+		}
+		sec := createElemClass(atom.Section, "sp-section")
+		setAttr(sec, atom.Id.String(), sectionID(r.path, unnamed))
+		unnamed++
+		sec.AppendChild(bn)
+		ps.values = append(ps.values, sec)
 	}
 
-	return &root, nil
+	if hit := r.limitsHit(); hit != "" {
+		ps.footer = createElemClass(atom.Footer, "sp-footer")
+		setAttr(ps.footer, atom.Id.String(), footerSectionID)
+		ps.footer.AppendChild(textNode("Rendering was truncated (reached " + hit + "); follow the truncation links to see the rest."))
+	}
+	return ps, nil
 }
 
 func isNilableType(k reflect.Kind) bool {
@@ -87,17 +712,169 @@ func isNilableType(k reflect.Kind) bool {
 	}
 }
 
-func (s *Status[T]) genValSection(v reflect.Value) ([]*html.Node, error) {
+// scalarText returns the textual form genValSection uses for v when v is a
+// scalar (including nil values and fmt.Stringers), and false when v needs
+// more structure than a single string.
+func scalarText(v reflect.Value) (string, bool) {
+	k := v.Kind()
+	if isNilableType(k) && v.IsNil() {
+		return v.Type().String() + "(nil)", true
+	}
+	if eligibleStringer(v.Type()) {
+		return v.Interface().(fmt.Stringer).String(), true
+	}
+	switch k {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10) + " (0x" + strconv.FormatInt(v.Int(), 16) + ")", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10) + " (0x" + strconv.FormatUint(v.Uint(), 16) + ")", true
+	case reflect.UnsafePointer:
+		vp := uint64(uintptr(v.UnsafePointer()))
+		return strconv.FormatUint(vp, 10) + " (0x" + strconv.FormatUint(vp, 16) + ")", true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Chan:
+		return v.Type().String() + fmt.Sprintf("capacity %d; len %d", v.Cap(), v.Len()), true
+	case reflect.Func:
+		if v.Type().CanSeq() || v.Type().CanSeq2() {
+			return "", false
+		}
+		fnPtr := uintptr(v.UnsafePointer())
+		return v.Type().String() + "(0x" + strconv.FormatUint(uint64(fnPtr), 16) + "): " + runtime.FuncForPC(fnPtr).Name(), true
+	default:
+		return "", false
+	}
+}
+
+// descend moves the renderer to the child of the current value reached via
+// st, returning a func that moves it back.
+func (r *renderer) descend(st pathStep) func() {
+	parent := r.path
+	r.path = parent.child(st)
+	return func() { r.path = parent }
+}
+
+// inKey marks the renderer as rendering a map key (which has no path of its
+// own, so nothing within it can be linked to, and which the thresholds of the
+// map's field don't apply to), returning a func that undoes it.
+func (r *renderer) inKey() func() {
+	subPages, thresholds := r.subPages, r.thresholds
+	r.subPages, r.thresholds = false, levelThresholds{}
+	return func() { r.subPages, r.thresholds = subPages, thresholds }
+}
+
+// rendersTable reports whether genValSection renders v (which must not be a
+// pointer or interface) as one or more tables.
+func rendersTable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		return v.NumField() > 0
+	case reflect.Map, reflect.Slice:
+		return !v.IsNil()
+	case reflect.Array:
+		return v.Len() > 0
+	case reflect.Func:
+		return !v.IsNil() && (v.Type().CanSeq() || v.Type().CanSeq2())
+	default:
+		return false
+	}
+}
+
+// enterTable is called by genValSection before rendering a value as a table.
+// If the table would be nested too deeply it returns a link to the value's
+// sub-page instead; otherwise it bumps the depth and returns a func to
+// restore it.
+func (r *renderer) enterTable(v reflect.Value) ([]*html.Node, func()) {
+	if !rendersTable(v) {
+		return nil, func() {}
+	}
+	if r.subPages && r.opts.maxInlineDepth > 0 && r.depth >= r.opts.maxInlineDepth {
+		a := createElemClass(atom.A, "sp-more")
+		a.Attr = append(a.Attr, html.Attribute{Key: atom.Href.String(), Val: r.pathURL(r.path)})
+		a.AppendChild(textNode(v.Type().String() + " …"))
+		return []*html.Node{a}, nil
+	}
+	r.depth++
+	return nil, func() { r.depth-- }
+}
+
+// genValSection renders v. Failures (errors or panics) are rendered inline,
+// so they don't take the rest of the page down with them.
+func (r *renderer) genValSection(v reflect.Value) ([]*html.Node, error) {
+	return isolate(r, func() ([]*html.Node, error) {
+		limit, leave := r.enterValue(v)
+		if limit != 0 {
+			return r.truncated(limit), nil
+		}
+		defer leave()
+		anchor := r.anchorValue
+		r.anchorValue = false
+		ns, _, err := r.visitOnce(v, func() ([]*html.Node, error) { return r.genValNodes(v) })
+		if anchor && err == nil {
+			ns = r.setAnchor(ns)
+		}
+		ns = r.withLevel(v, ns)
+		ns = r.withTrend(v, ns)
+		for _, n := range ns {
+			if n.Type == html.TextNode {
+				r.spendBytes(len(n.Data))
+			}
+		}
+		return ns, err
+	}, func(msg string) []*html.Node {
+		// the value's anchor goes on the error in its place
+		ns := errorNodes(msg)
+		if r.anchorValue {
+			r.anchorValue = false
+			ns = r.setAnchor(ns)
+		}
+		return ns
+	}), nil
+}
+
+// genValNodes does the work for genValSection, once we know v hasn't been
+// rendered already.
+func (r *renderer) genValNodes(v reflect.Value) ([]*html.Node, error) {
+	// Registered renderers take precedence over everything else, followed
+	// by types that render themselves
+	if tr, ok := r.opts.valueRenderer(v); ok {
+		return tr.render(v)
+	}
+	if sr, ok := statusRendererOf(v); ok {
+		return sr.StatusPageNodes(RenderContext{r: r, v: v})
+	}
+	return r.genDefaultNodes(v)
+}
+
+// genDefaultNodes renders v according to its kind (or String method)
+func (r *renderer) genDefaultNodes(v reflect.Value) ([]*html.Node, error) {
 	k := v.Kind()
 
 	// If this type implements fmt.Stringer, delegate to that
-	// implementation as long as the value isn't nil.
-	if eligibleStringer(v.Type()) && !(isNilableType(k) && v.IsNil()) {
-		return []*html.Node{textNode(v.Interface().(fmt.Stringer).String())}, nil
+	// implementation as long as the value isn't nil (and doesn't point to
+	// a value with a renderer of its own).
+	if eligibleStringer(v.Type()) && !(isNilableType(k) && v.IsNil()) && !r.opts.pointsToRendered(v.Type()) {
+		return []*html.Node{r.scalarNode("sp-stringer", v.Interface().(fmt.Stringer).String())}, nil
+	}
+	if s, ok := r.formattedText(v); ok {
+		return []*html.Node{r.scalarNode("sp-num", s)}, nil
+	}
+	if k != reflect.Pointer && k != reflect.Interface {
+		link, exitTable := r.enterTable(v)
+		if link != nil {
+			return link, nil
+		}
+		defer exitTable()
 	}
 	switch k {
 	case reflect.Struct:
-		ns, tblErr := s.genStructTable(v)
+		ns, tblErr := r.genStructTable(v)
 		if tblErr != nil {
 			return nil, tblErr
 		}
@@ -105,64 +882,59 @@ func (s *Status[T]) genValSection(v reflect.Value) ([]*html.Node, error) {
 		return ns, nil
 	case reflect.Map:
 		if v.IsNil() {
-			return []*html.Node{textNode(v.Type().String() + "(nil)")}, nil
+			return []*html.Node{r.nilNode(v.Type())}, nil
 		}
-		ns, tblErr := s.genMapOrSeq2Table(v)
+		ns, tblErr := r.genMapOrSeq2Table(v)
 		if tblErr != nil {
 			return nil, tblErr
 		}
 
 		return ns, nil
 	case reflect.Array, reflect.Slice:
-		return s.genSliceArrayTable(v)
+		return r.genSliceArrayTable(v)
 	case reflect.Pointer, reflect.Interface:
 		if v.IsNil() {
-			return []*html.Node{textNode(v.Type().String() + "(nil)")}, nil
+			return []*html.Node{r.nilNode(v.Type())}, nil
 		}
 		// Delegate after following the bouncing ball
-		return s.genValSection(v.Elem())
+		return r.genValSection(v.Elem())
 	case reflect.Bool:
-		// TODO: wrap this text node in an element node so we can key some CSS styling
-		return []*html.Node{textNode(strconv.FormatBool(v.Bool()))}, nil
+		return []*html.Node{r.scalarNode("sp-bool-"+strconv.FormatBool(v.Bool()), strconv.FormatBool(v.Bool()))}, nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		// TODO: wrap this text node in an element node so we can key some CSS styling
-		return []*html.Node{textNode(strconv.FormatInt(v.Int(), 10) + " (0x" + strconv.FormatInt(v.Int(), 16) + ")")}, nil
+		return []*html.Node{r.scalarNode("sp-num", strconv.FormatInt(v.Int(), 10)+" (0x"+strconv.FormatInt(v.Int(), 16)+")")}, nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		// TODO: wrap this text node in an element node so we can key some CSS styling
-		return []*html.Node{textNode(strconv.FormatUint(v.Uint(), 10) + " (0x" + strconv.FormatUint(v.Uint(), 16) + ")")}, nil
+		return []*html.Node{r.scalarNode("sp-num", strconv.FormatUint(v.Uint(), 10)+" (0x"+strconv.FormatUint(v.Uint(), 16)+")")}, nil
 	case reflect.UnsafePointer:
 		vp := v.UnsafePointer()
-		return []*html.Node{textNode(strconv.FormatUint(uint64(uintptr(vp)), 10) + " (0x" + strconv.FormatUint(uint64(uintptr(vp)), 16) + ")")}, nil
+		return []*html.Node{r.scalarNode("sp-num", strconv.FormatUint(uint64(uintptr(vp)), 10)+" (0x"+strconv.FormatUint(uint64(uintptr(vp)), 16)+")")}, nil
 	case reflect.Float32, reflect.Float64:
-		// TODO: wrap this text node in an element node so we can key some CSS styling
-		return []*html.Node{textNode(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))}, nil
+		return []*html.Node{r.scalarNode("sp-num", strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))}, nil
 	case reflect.Complex64, reflect.Complex128:
-		// TODO: wrap this text node in an element node so we can key some CSS styling
-		return []*html.Node{textNode(strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))}, nil
+		return []*html.Node{r.scalarNode("sp-num", strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))}, nil
 	case reflect.String:
-		return []*html.Node{textNode(v.String())}, nil
+		return []*html.Node{r.scalarNode("sp-str", v.String())}, nil
 	case reflect.Chan:
 		if v.IsNil() {
-			return []*html.Node{textNode(v.Type().String() + "(nil)")}, nil
+			return []*html.Node{r.nilNode(v.Type())}, nil
 		}
-		return []*html.Node{textNode(v.Type().String() + fmt.Sprintf("capacity %d; len %d", v.Cap(), v.Len()))}, nil
+		return []*html.Node{r.scalarNode("sp-chan", v.Type().String()+fmt.Sprintf("capacity %d; len %d", v.Cap(), v.Len()))}, nil
 	case reflect.Func:
-		return s.genFuncNodes(v)
+		return r.genFuncNodes(v)
 	default:
-		panic(fmt.Sprintf("unhandled kind %s (type %T)", k, v.Type()))
+		return nil, fmt.Errorf("unhandled kind %s (type %s)", k, v.Type())
 	}
 }
 
-func (s *Status[T]) genFuncNodes(v reflect.Value) ([]*html.Node, error) {
+func (r *renderer) genFuncNodes(v reflect.Value) ([]*html.Node, error) {
 	if v.IsNil() {
-		return []*html.Node{textNode(v.Type().String() + "(nil)")}, nil
+		return []*html.Node{r.nilNode(v.Type())}, nil
 	}
 	if v.Type().CanSeq2() {
-		return s.genMapOrSeq2Table(v)
+		return r.genMapOrSeq2Table(v)
 	} else if v.Type().CanSeq() {
-		return s.genSliceArrayTable(v)
+		return r.genSliceArrayTable(v)
 	}
 	fnPtr := uintptr(v.UnsafePointer())
 	fn := runtime.FuncForPC(fnPtr)
-	return []*html.Node{textNode(v.Type().String() + "(0x" + strconv.FormatUint(uint64(fnPtr), 16) + "): " + fn.Name())}, nil
+	return []*html.Node{r.scalarNode("sp-func", v.Type().String()+"(0x"+strconv.FormatUint(uint64(fnPtr), 16)+"): "+fn.Name())}, nil
 }
@@ -0,0 +1,80 @@
+package statuspage
+
+import (
+	"reflect"
+
+	"golang.org/x/net/html"
+)
+
+// StatusRenderer is implemented by types that render themselves on HTML
+// status pages. It takes precedence over fmt.Stringer (but not over
+// renderers set with RegisterRenderer or WithRenderer, which let callers
+// override a type's own rendering). Other formats render the value as if it
+// didn't implement StatusRenderer.
+//
+// A type can put a summary on top of its default rendering by returning its
+// own nodes followed by those from ctx.RenderDefault().
+type StatusRenderer interface {
+	StatusPageNodes(ctx RenderContext) ([]*html.Node, error)
+}
+
+var statusRendererReflectType = reflect.TypeFor[StatusRenderer]()
+
+// RenderContext describes where a StatusRenderer is being rendered, and lets
+// it render values with the package's default rules.
+type RenderContext struct {
+	r *renderer
+	v reflect.Value
+}
+
+// Path returns the URL path of the value being rendered relative to the
+// root of the status page (e.g. /Backends/us-east/Conns[3]), or "" for the
+// root itself.
+func (c RenderContext) Path() string {
+	return c.r.path.String()
+}
+
+// Depth returns the number of tables enclosing the value being rendered
+func (c RenderContext) Depth() int {
+	return c.r.depth
+}
+
+// Render renders v the way it would be rendered if it were a child of the
+// value being rendered. (including calling its StatusPageNodes method if
+// it has one)
+func (c RenderContext) Render(v any) ([]*html.Node, error) {
+	return c.r.genValSection(reflect.ValueOf(v))
+}
+
+// RenderDefault renders the value being rendered as if it didn't implement
+// StatusRenderer.
+func (c RenderContext) RenderDefault() ([]*html.Node, error) {
+	v := c.v
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return c.r.genDefaultNodes(v)
+}
+
+// statusRendererOf returns v as a StatusRenderer if it implements it (and
+// isn't nil).
+func statusRendererOf(v reflect.Value) (StatusRenderer, bool) {
+	if !v.IsValid() || !v.CanInterface() || isNilRef(v) || !v.Type().Implements(statusRendererReflectType) {
+		return nil, false
+	}
+	return v.Interface().(StatusRenderer), true
+}
+
+// rendersItself reports whether values of type t (following pointers)
+// implement StatusRenderer
+func rendersItself(t reflect.Type) bool {
+	for {
+		if t.Implements(statusRendererReflectType) {
+			return true
+		}
+		if t.Kind() != reflect.Pointer {
+			return false
+		}
+		t = t.Elem()
+	}
+}
@@ -0,0 +1,78 @@
+package statuspage_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	statuspage "github.com/vimeo/go-status-page"
+	"golang.org/x/net/html"
+)
+
+// pool puts a summary on top of its default rendering
+type pool struct {
+	Size int
+	Free int
+}
+
+func (p pool) StatusPageNodes(ctx statuspage.RenderContext) ([]*html.Node, error) {
+	summary := boldText(strconv.Itoa(p.Size-p.Free) + " in use at " + ctx.Path() + " (depth " + strconv.Itoa(ctx.Depth()) + ")")
+	def, err := ctx.RenderDefault()
+	return append(summary, def...), err
+}
+
+// labelled renders itself in place of its String method
+type labelled struct{}
+
+func (labelled) String() string { return "plain" }
+
+func (labelled) StatusPageNodes(statuspage.RenderContext) ([]*html.Node, error) {
+	return boldText("fancy"), nil
+}
+
+// wrapper renders a child value with the library's rules
+type wrapper struct{ inner any }
+
+func (w wrapper) StatusPageNodes(ctx statuspage.RenderContext) ([]*html.Node, error) {
+	return ctx.Render(w.inner)
+}
+
+func TestStatusRenderer(t *testing.T) {
+	v := struct {
+		Pool    pool
+		PoolPtr *pool
+		NilPool *pool
+		Label   labelled
+		Wrapped wrapper
+	}{
+		Pool:    pool{Size: 10, Free: 3},
+		PoolPtr: &pool{Size: 4, Free: 4},
+		Wrapped: wrapper{inner: []int{1, 2}},
+	}
+	page := serveStatus(t, v, "")
+	checkTokens(t, page)
+	for _, want := range []string{
+		// the default rendering follows the summary
+		`<b>7 in use at /Pool (depth 1)</b><table class="sp-table sp-struct" id="sp/Pool"`,
+		// (with a single anchor, on the default rendering)
+		`<b>0 in use at /PoolPtr (depth 1)</b><table class="sp-table sp-struct" id="sp/PoolPtr"`,
+		`*statuspage_test.pool(nil)`,
+		// it takes precedence over String
+		`<td class="sp-value"><b>fancy</b></td>`,
+		// children rendered through the context get the usual rendering
+		`<table class="sp-table sp-slice" id="sp/Wrapped"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, ">plain<") {
+		t.Errorf("String was used despite StatusPageNodes:\n%s", page)
+	}
+
+	// other formats render the value as if it didn't implement
+	// StatusRenderer
+	if js := serveStatus(t, v, "?format=json"); !strings.Contains(js, `"Label": "plain"`) {
+		t.Errorf("JSON didn't use String:\n%s", js)
+	}
+}
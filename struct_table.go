@@ -3,7 +3,6 @@ package statuspage
 import (
 	"fmt"
 	"reflect"
-	"slices"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -25,49 +24,25 @@ func needsTable(t reflect.Type) bool {
 	}
 }
 
-func shouldSkipField(f reflect.StructField) bool {
-	v, ok := f.Tag.Lookup("statuspage")
-	return ok && v == "-"
-}
-
-func (s *Status[T]) genStructTable(v reflect.Value) ([]*html.Node, error) {
+func (r *renderer) genStructTable(v reflect.Value) ([]*html.Node, error) {
 	if v.Kind() != reflect.Struct {
-		panic(fmt.Errorf("non-struct kind: %s type %s", v.Kind(), v.Type()))
+		return nil, fmt.Errorf("non-struct kind: %s type %s", v.Kind(), v.Type())
 	}
-	vt := v.Type()
 
 	// get all the fields visible at the top-level. We'll split them into
 	// simple fields that can be dropped into a table at the top, and
 	// tableFields that need their own tables.
-	fields := reflect.VisibleFields(vt)
-	simpleFields := make([]reflect.StructField, 0, len(fields))
-	tableFields := make([]reflect.StructField, 0, len(fields))
-	nilParentFields := [][]int{}
-FIELDITER:
+	fields, fieldsErr := renderableFields(v)
+	if fieldsErr != nil {
+		return nil, fieldsErr
+	}
+	simpleFields := make([]structField, 0, len(fields))
+	tableFields := make([]structField, 0, len(fields))
 	for _, field := range fields {
-		// we have to skip its children if it's anonymous and nil, too
-		if field.Anonymous && field.Type.Kind() == reflect.Pointer && v.FieldByIndex(field.Index).IsNil() {
-			nilParentFields = append(nilParentFields, field.Index)
-			continue
-		}
-		for _, idx := range nilParentFields {
-			// skip anything that's embedded, but has a nil parent
-			if len(field.Index) > len(idx) && slices.Equal(idx, field.Index[:len(idx)]) {
-				continue FIELDITER
-			}
-		}
-		if !field.IsExported() {
-			// skip the unexported fields for now
-			continue
-		}
-		if shouldSkipField(field) {
-			// The caller asked us to skip this
-			continue
-		}
 		// TODO: separate out interface-typed fields, so we can put
 		// them in the right section depending on what value is present
 		// internally.
-		if needsTable(field.Type) {
+		if r.needsTable(field.Type) {
 			tableFields = append(tableFields, field)
 			continue
 		}
@@ -77,53 +52,52 @@ FIELDITER:
 	// TODO: include the type-name at the top
 	out := make([]*html.Node, 0, len(tableFields)+1)
 	if len(simpleFields) > 0 {
-		simpleTable := createElemAtom(atom.Table)
+		simpleTable := createElemClass(atom.Table, "sp-table sp-struct")
 		out = append(out, simpleTable)
 
 		// iterate over the simple fields and add rows for each row.
-	SIMPLEFIELDITER:
 		for _, sf := range simpleFields {
-			row := createElemAtom(atom.Tr)
+			ascend := r.enterField(&sf)
+			row := r.pathRow()
 			simpleTable.AppendChild(row)
-			fieldCol := createElemAtom(atom.Td)
-			fieldCol.AppendChild(textNode(sf.Name))
+			fieldCol := createElemClass(atom.Th, "sp-field-name")
+			fieldCol.AppendChild(textNode(sf.displayName()))
+			setHelp(fieldCol, &sf)
 			row.AppendChild(fieldCol)
 
-			valCol := createElemAtom(atom.Td)
+			valCol := createElemClass(atom.Td, "sp-value")
 			row.AppendChild(valCol)
-			for _, idx := range nilParentFields {
-				// skip anything that's embedded, but has a nil parent
-				if len(sf.Index) > len(idx) && slices.Equal(idx, sf.Index[:len(idx)]) {
-					valCol.AppendChild(textNode("parent nil"))
-					continue SIMPLEFIELDITER
-				}
-			}
 			sv := v.FieldByIndex(sf.Index)
 			// We've already validated that this is a simple-enough type, so use
 			// genValSection to render into a (small number of?) nodes
-			valNs, valSectionErr := s.genValSection(sv)
+			valNs, valSectionErr := r.genValSection(sv)
+			ascend()
 			if valSectionErr != nil {
 				return nil, fmt.Errorf("failed to render field %q: %w", sf.Name, valSectionErr)
 			}
 			for _, valN := range valNs {
 				valCol.AppendChild(valN)
 			}
+			setCellLevel(valCol)
 		}
+		r.finishTable(simpleTable)
 	}
 
 	// iterate over the remaining table fields and generate sections for each field (with their
 	// own tables)
 	// they'll each delegate to genValSection() to create the fields.
 	for _, tf := range tableFields {
-		section := createElemAtom(atom.Div)
+		section := createElemClass(atom.Div, "sp-section")
 		out = append(out, section)
-		fieldName := createElemAtom(atom.H3)
-		fieldName.AppendChild(textNode(tf.Name))
-		section.AppendChild(fieldName)
+		sv := v.FieldByIndex(tf.Index)
+		ascend := r.enterField(&tf)
+		id := sectionID(r.path, 0)
+		section.Attr = append(section.Attr, html.Attribute{Key: atom.Id.String(), Val: id})
+		section.AppendChild(sectionHeading(&tf, id))
 		section.AppendChild(createElemAtom(atom.Br))
 
-		sv := v.FieldByIndex(tf.Index)
-		valNs, valSectionErr := s.genValSection(sv)
+		valNs, valSectionErr := r.genValSection(sv)
+		ascend()
 		if valSectionErr != nil {
 			return nil, fmt.Errorf("failed to render field %q: %w", tf.Name, valSectionErr)
 		}
@@ -0,0 +1,118 @@
+package statuspage
+
+import (
+	"bytes"
+	"embed"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// staticFS holds the assets served under staticPathPrefix
+//
+//go:embed static
+var staticFS embed.FS
+
+// staticPathPrefix is the sub-path the embedded assets (e.g. the default
+// stylesheet) are served under
+const staticPathPrefix = "/_static/"
+
+// defaultStylesheetName is the name of the default stylesheet within staticFS
+const defaultStylesheetName = "statuspage.css"
+
+// staticMaxAge is how long clients may cache the embedded assets. Links to
+// them carry a hash of their content, so new versions aren't held up.
+const staticMaxAge = 7 * 24 * time.Hour
+
+// staticAsset is an embedded asset, along with the hash identifying its
+// content
+type staticAsset struct {
+	content []byte
+	hash    string
+}
+
+// staticAssets maps the names of the embedded assets to their contents
+var staticAssets = func() map[string]staticAsset {
+	out := map[string]staticAsset{}
+	entries, _ := fs.ReadDir(staticFS, "static")
+	for _, e := range entries {
+		b, _ := fs.ReadFile(staticFS, "static/"+e.Name())
+		h := fnv.New64a()
+		h.Write(b)
+		out[e.Name()] = staticAsset{content: b, hash: strconv.FormatUint(h.Sum64(), 36)}
+	}
+	return out
+}()
+
+// serveStatic serves the embedded asset name, with headers letting clients
+// cache it (and revalidate it with If-None-Match).
+func serveStatic(w http.ResponseWriter, r *http.Request, name string) {
+	asset, ok := staticAssets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if strings.HasSuffix(name, ".css") {
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(staticMaxAge.Seconds())))
+	w.Header().Set("ETag", `"`+asset.hash+`"`)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(asset.content))
+}
+
+// addStylesheets adds the page's stylesheets to head: the default one
+// (linked if we know where it's served, and inlined otherwise), followed by
+// the one set with WithStylesheet (if any), so it can override the default.
+func (r *renderer) addStylesheets(head *html.Node) {
+	meta := createElemAtom(atom.Meta)
+	setAttr(meta, atom.Name.String(), "color-scheme")
+	setAttr(meta, atom.Content.String(), "light dark")
+	head.AppendChild(meta)
+
+	asset := staticAssets[defaultStylesheetName]
+	if r.subPages {
+		head.AppendChild(stylesheetLink(r.basePath + staticPathPrefix + defaultStylesheetName + "?v=" + asset.hash))
+	} else {
+		style := createElemAtom(atom.Style)
+		style.AppendChild(textNode(string(asset.content)))
+		head.AppendChild(style)
+	}
+	if r.opts.stylesheetURL != "" {
+		head.AppendChild(stylesheetLink(r.opts.stylesheetURL))
+	}
+}
+
+func stylesheetLink(href string) *html.Node {
+	link := createElemAtom(atom.Link)
+	setAttr(link, atom.Rel.String(), "stylesheet")
+	setAttr(link, atom.Href.String(), href)
+	return link
+}
+
+// createElemClass returns a new element of type d with the class c
+func createElemClass(d atom.Atom, c string) *html.Node {
+	n := createElemAtom(d)
+	setAttr(n, atom.Class.String(), c)
+	return n
+}
+
+// scalarNode returns the element rendering a scalar as text, with the class
+// c (e.g. sp-num)
+func (r *renderer) scalarNode(c, text string) *html.Node {
+	r.spendBytes(len(text))
+	n := createElemClass(atom.Span, c)
+	n.AppendChild(textNode(text))
+	return n
+}
+
+// nilNode returns the marker rendered for a nil value of type t
+func (r *renderer) nilNode(t reflect.Type) *html.Node {
+	return r.scalarNode("sp-nil", t.String()+"(nil)")
+}
@@ -0,0 +1,147 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestServeStatic(t *testing.T) {
+	s := New("Test", func() int { return 1 }, WithBasePath("/status"))
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/status/_static/statuspage.css")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	asset := staticAssets[defaultStylesheetName]
+	for header, want := range map[string]string{
+		"Content-Type":  "text/css; charset=utf-8",
+		"Cache-Control": "public, max-age=604800",
+		"ETag":          `"` + asset.hash + `"`,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q; want %q", header, got, want)
+		}
+	}
+	css := rec.Body.String()
+	for _, want := range []string{"@media (prefers-color-scheme: dark)", "position: sticky;"} {
+		if !strings.Contains(css, want) {
+			t.Errorf("stylesheet doesn't contain %q", want)
+		}
+	}
+
+	if rec := get("/status/_static/statuspage.css", "If-None-Match", `"`+asset.hash+`"`); rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation: status %d; want 304", rec.Code)
+	}
+	if rec := get("/status/_static/missing.css"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing asset: status %d; want 404", rec.Code)
+	}
+}
+
+func TestStylesheets(t *testing.T) {
+	asset := staticAssets[defaultStylesheetName]
+	for _, tc := range []struct {
+		name    string
+		s       http.Handler
+		want    []string
+		notWant []string
+	}{
+		{"mounted", New("Test", func() int { return 1 }, WithBasePath("/status")), []string{
+			`<meta name="color-scheme" content="light dark"/>`,
+			`<link rel="stylesheet" href="/status/_static/statuspage.css?v=` + asset.hash + `"/>`,
+		}, []string{"<style>"}},
+		// if we can't tell where the assets are served, the stylesheet's
+		// inlined
+		{"unmounted", New("Test", func() int { return 1 }), []string{
+			"<style>" + string(asset.content) + "</style>",
+		}, []string{`rel="stylesheet"`}},
+		{"custom", New("Test", func() int { return 1 }, WithBasePath("/status"), WithStylesheet("/theme.css")), []string{
+			`statuspage.css?v=` + asset.hash + `"/><link rel="stylesheet" href="/theme.css"/>`,
+		}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/", nil))
+			page := rec.Body.String()
+			for _, want := range tc.want {
+				if !strings.Contains(page, want) {
+					t.Errorf("page doesn't contain %q:\n%s", want, page)
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if strings.Contains(page, notWant) {
+					t.Errorf("page contains %q:\n%s", notWant, page)
+				}
+			}
+		})
+	}
+}
+
+type styledVal struct {
+	On, Off bool
+	N       int
+	F       float64
+	S       string
+	Nil     *int
+	M       map[string]int
+	L       []struct{ A int }
+	Err     error
+}
+
+func TestClasses(t *testing.T) {
+	s := New("Test", func() styledVal {
+		return styledVal{On: true, S: "s", M: map[string]int{"a": 1}, L: []struct{ A int }{{1}}}
+	})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	doc, parseErr := html.Parse(rec.Body)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	// structural elements are left to be selected by their context
+	unclassed := map[string]bool{"tbody": true, "thead": true, "tr": true, "caption": true, "br": true, "hr": true, "ul": true, "li": true, "a": true}
+	classes := map[string]bool{}
+	inMain := false
+	for d := range doc.Descendants() {
+		if d.Type == html.TextNode && d.Parent.Data == "td" && strings.TrimSpace(d.Data) != "" {
+			t.Errorf("bare text %q in a cell", d.Data)
+		}
+		if d.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := attr(d, "style"); ok {
+			t.Errorf("<%s> has an inline style", d.Data)
+		}
+		if d.Data == "main" {
+			inMain = true
+		}
+		class, ok := attr(d, "class")
+		if inMain && !ok && !unclassed[d.Data] {
+			t.Errorf("<%s> in the page's content has no class", d.Data)
+		}
+		for c := range strings.FieldsSeq(class) {
+			classes[c] = true
+		}
+	}
+	for _, want := range []string{
+		"sp-struct", "sp-map", "sp-slice", "sp-field-name", "sp-key", "sp-value",
+		"sp-bool-true", "sp-bool-false", "sp-num", "sp-str", "sp-nil",
+	} {
+		if !classes[want] {
+			t.Errorf("no element has the class %s", want)
+		}
+	}
+}
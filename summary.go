@@ -0,0 +1,199 @@
+package statuspage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// summaryPath is the sub-path serving just the summary (see Status)
+const summaryPath = "/_summary"
+
+// summarySectionID is the id of the summary cards at the top of the page
+const summarySectionID = "sp-summary"
+
+// maxSummaryItems bounds the number of values summarized, since fields
+// tagged with summary within maps and slices get an item per entry.
+const maxSummaryItems = 64
+
+// summaryItem is a value within a field tagged with summary
+type summaryItem struct {
+	path  fieldPath
+	v     reflect.Value
+	field structField
+}
+
+// summaryTypesCache caches mayHaveSummary by type
+var summaryTypesCache sync.Map
+
+// mayHaveSummary reports whether values of type t may hold fields tagged
+// with summary
+func mayHaveSummary(t reflect.Type) bool {
+	return mayHaveTagged(t, &summaryTypesCache, func(ft *fieldTag) bool { return ft.summary })
+}
+
+// summaryCollector holds the state for a single summaryItems
+type summaryCollector struct {
+	opts  *options
+	items []summaryItem
+	// seen holds the pointers already walked, to break cycles
+	seen map[visitKey]struct{}
+}
+
+// summaryItems returns the values within v (at path p) in fields tagged with
+// summary, in page order.
+func summaryItems(opts *options, v reflect.Value, p fieldPath) []summaryItem {
+	if !mayHaveSummary(v.Type()) {
+		return nil
+	}
+	c := summaryCollector{opts: opts, seen: map[visitKey]struct{}{}}
+	c.collect(v, p)
+	return c.items
+}
+
+func (c *summaryCollector) collect(v reflect.Value, p fieldPath) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Pointer {
+			key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+			if _, seen := c.seen[key]; seen {
+				return
+			}
+			c.seen[key] = struct{}{}
+		}
+		v = v.Elem()
+	}
+	if !mayHaveSummary(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, fieldsErr := renderableFields(v)
+		if fieldsErr != nil {
+			return
+		}
+		for _, f := range fields {
+			if len(c.items) >= maxSummaryItems {
+				return
+			}
+			fv, fp := v.FieldByIndex(f.Index), p.child(f.step())
+			if f.tag.summary {
+				c.items = append(c.items, summaryItem{path: fp, v: fv, field: f})
+				continue
+			}
+			c.collect(fv, fp)
+		}
+	case reflect.Map:
+		for k, e := range c.opts.mapEntries(v) {
+			c.collect(e, p.child(keyStep(k)))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			c.collect(v.Index(i), p.child(indexStep(i)))
+		}
+	}
+}
+
+// inSummary renders it (via gen) as it's rendered within its field, but
+// without affecting the rendering of the rest of the page: values within it
+// are rendered again in their places, rather than linking back to the
+// summary.
+func inSummary[R any](r *renderer, it *summaryItem, gen func() (R, error)) (R, error) {
+	path := r.path
+	r.path = it.path
+	exitVisits := r.withFreshVisits()
+	exitFormat := r.withFormat(it.field.tag.format)
+	exitThresholds := r.withThresholds(it.field.tag.thresholds)
+	defer func() {
+		exitThresholds()
+		exitFormat()
+		exitVisits()
+		r.path = path
+	}()
+	return gen()
+}
+
+// genSummary renders the summary cards for the fields within v tagged with
+// summary, each linking to where the value lives on the page. It returns nil
+// if there are none.
+func (r *renderer) genSummary(v reflect.Value) (*html.Node, error) {
+	items := summaryItems(r.opts, v, r.path)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	grid := createElemClass(atom.Section, "sp-summary")
+	setAttr(grid, atom.Id.String(), summarySectionID)
+	for _, it := range items {
+		ns, genErr := inSummary(r, &it, func() ([]*html.Node, error) { return r.genValSection(it.v) })
+		if genErr != nil {
+			return nil, genErr
+		}
+		card := createElemClass(atom.Div, "sp-card")
+		label := createElemAtom(atom.A)
+		setAttr(label, atom.Href.String(), "#"+anchorID(it.path))
+		label.AppendChild(textNode(it.path.label()))
+		setHelp(label, &it.field)
+		card.AppendChild(label)
+		val := wrapNodes(atom.Div, ns)
+		// the anchors belong to the values' places on the page
+		stripIDs(val)
+		addClass(val, "sp-card-value")
+		setCellLevel(val)
+		card.AppendChild(val)
+		grid.AppendChild(card)
+	}
+	return grid, nil
+}
+
+// serveSummary responds with just the summary of the latest snapshot: a JSON
+// object mapping the summarized values' labels to their values, or a line
+// per value in plain text for every other format.
+func (s *Status[T]) serveSummary(w http.ResponseWriter, r *http.Request, basePath string, subPages bool) {
+	format := negotiateFormat(r)
+	if format != formatJSON {
+		format = formatText
+	}
+	rn := s.newRenderer(basePath, subPages, nil)
+	snap, loadErr := s.snapshot(r.Context())
+	if loadErr != nil {
+		serveError(w, rn, format, http.StatusServiceUnavailable, loadErr)
+		return
+	}
+	release, acquired := s.acquireRender()
+	if !acquired {
+		w.Header().Set("Retry-After", strconv.Itoa(renderRetryAfter))
+		http.Error(w, "too many status page renders in progress", http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+	v := reflect.ValueOf(&snap.v).Elem()
+	items := summaryItems(rn.opts, v, nil)
+	if format == formatJSON {
+		obj := make(jsonObject, 0, len(items))
+		for _, it := range items {
+			jv, _ := inSummary(rn, &it, func() (any, error) { return rn.genJSONVal(it.v) })
+			obj = append(obj, jsonMember{Key: it.path.label(), Val: jv})
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(obj)
+		return
+	}
+	b := strings.Builder{}
+	for _, it := range items {
+		lines, _ := inSummary(rn, &it, func() (textBlock, error) { return rn.genTextVal(it.v) })
+		b.WriteString(it.path.label() + ": " + strings.Join(lines, "\n") + "\n")
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,159 @@
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+type shardSummary struct {
+	QPS   int `statuspage:"summary"`
+	Other int
+}
+
+type summaryVal struct {
+	Leader bool    `statuspage:"summary"`
+	Rate   float64 `statuspage:"summary,format=percent"`
+	Shards map[string]shardSummary
+	Tags   *[]string `statuspage:"summary"`
+	Self   *summaryVal
+	Plain  int
+}
+
+func TestSummaryItems(t *testing.T) {
+	v := &summaryVal{Shards: map[string]shardSummary{"b": {}, "a": {}}, Tags: &[]string{"x"}}
+	v.Self = v
+	labels := []string{}
+	for _, it := range summaryItems(&options{}, reflect.ValueOf(v), nil) {
+		labels = append(labels, it.path.label())
+	}
+	// in page order, walking the cycle once
+	if want := []string{"Leader", "Rate", "Shards › a › QPS", "Shards › b › QPS", "Tags"}; !slices.Equal(labels, want) {
+		t.Errorf("summary items = %q; want %q", labels, want)
+	}
+
+	if items := summaryItems(&options{}, reflect.ValueOf(struct{ A int }{}), nil); items != nil {
+		t.Errorf("summary items of a type without any = %v", items)
+	}
+	many := map[int]shardSummary{}
+	for i := range 2 * maxSummaryItems {
+		many[i] = shardSummary{}
+	}
+	if n := len(summaryItems(&options{}, reflect.ValueOf(many), nil)); n != maxSummaryItems {
+		t.Errorf("got %d summary items; want them capped at %d", n, maxSummaryItems)
+	}
+}
+
+func summaryStatus() *Status[summaryVal] {
+	return New("Test", func() summaryVal {
+		return summaryVal{
+			Leader: true,
+			Rate:   0.25,
+			Shards: map[string]shardSummary{"a": {QPS: 1, Other: 2}, "b": {QPS: 3, Other: 4}},
+			Tags:   &[]string{"x", "y"},
+		}
+	}, WithBasePath("/status"))
+}
+
+func TestSummaryCards(t *testing.T) {
+	rec := httptest.NewRecorder()
+	summaryStatus().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/", nil))
+	doc, parseErr := html.Parse(rec.Body)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	ids := map[string]bool{}
+	var grid *html.Node
+	for d := range doc.Descendants() {
+		if id, ok := attr(d, "id"); ok {
+			ids[id] = true
+			if id == summarySectionID {
+				grid = d
+			}
+		}
+	}
+	if grid == nil {
+		t.Fatal("page has no summary")
+	}
+
+	cards := []string{}
+	for card := range grid.ChildNodes() {
+		link := card.FirstChild
+		href, _ := attr(link, "href")
+		cards = append(cards, textContent(link)+"="+textContent(link.NextSibling))
+		if !ids[strings.TrimPrefix(href, "#")] {
+			t.Errorf("card %s links to %s, which isn't on the page", textContent(link), href)
+		}
+		// the anchors stay with the values in their places
+		for d := range link.NextSibling.Descendants() {
+			if id, ok := attr(d, "id"); ok {
+				t.Errorf("card %s has an element with the id %q", textContent(link), id)
+			}
+		}
+	}
+	want := []string{
+		"Leader=true",
+		"Rate=25.0%",
+		"Shards › a › QPS=1 (0x1)",
+		"Shards › b › QPS=3 (0x3)",
+		"Tags=[]stringlen() = 2cap() = 2xy",
+	}
+	if !slices.Equal(cards, want) {
+		t.Errorf("cards = %q; want %q", cards, want)
+	}
+}
+
+func TestSummaryEndpoint(t *testing.T) {
+	s := summaryStatus()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/status/_summary?format=json")
+	wantJSON := `{
+  "Leader": true,
+  "Rate": 0.25,
+  "Shards › a › QPS": 1,
+  "Shards › b › QPS": 3,
+  "Tags": [
+    "x",
+    "y"
+  ]
+}
+`
+	if rec.Code != http.StatusOK || rec.Body.String() != wantJSON {
+		t.Errorf("JSON summary: %d:\n%s\nwant:\n%s", rec.Code, rec.Body, wantJSON)
+	}
+
+	// every other format gets plain text
+	for _, format := range []string{"text", "html", "openmetrics"} {
+		rec := get("/status/_summary?format=" + format)
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("%s summary: Content-Type %q; want text/plain", format, ct)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"Leader: true\n", "Rate: 25.0%\n", "Shards › b › QPS: 3 (0x3)\n", "Tags: "} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s summary doesn't contain %q:\n%s", format, want, body)
+			}
+		}
+	}
+
+	failing := NewCtx("Test", func(context.Context) (summaryVal, error) {
+		return summaryVal{}, errors.New("no leader")
+	}, WithBasePath("/status"))
+	rec = httptest.NewRecorder()
+	failing.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/_summary", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "no leader") {
+		t.Errorf("summary of a failing status: %d: %s; want a 503 with the error", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,115 @@
+package statuspage
+
+import (
+	"strconv"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// finishTable readies tbl (a table rendering the value at the renderer's
+// current path) for the page once its rows are in: it flags any rows
+// holding critical values, anchors and labels the table with the value's
+// path, and sections its rows (see sectionTable).
+func (r *renderer) finishTable(tbl *html.Node) {
+	r.flagCritRows(tbl)
+	if _, hasID := attr(tbl, atom.Id.String()); !hasID {
+		setAttr(tbl, atom.Id.String(), anchorID(r.path))
+	}
+	if label := r.pageTitle(); label != "" {
+		setAttr(tbl, "aria-label", label)
+	}
+	sectionTable(tbl)
+}
+
+// pathRow returns a new table row for the value at the current path
+func (r *renderer) pathRow() *html.Node {
+	row := createElemAtom(atom.Tr)
+	setAttr(row, atom.Id.String(), rowID(r.path))
+	return row
+}
+
+// sectionTable moves the rows of tbl (which must all be its children) into
+// a thead holding the leading rows of header cells, and a tbody holding the
+// rest. Along the way, header cells get a scope: the column for those in
+// rows of header cells, and the row for those heading rows of values.
+func sectionTable(tbl *html.Node) {
+	var thead, tbody *html.Node
+	for row := tbl.FirstChild; row != nil; {
+		next := row.NextSibling
+		if row.DataAtom != atom.Tr {
+			row = next
+			continue
+		}
+		header := headerRow(row)
+		for c := range row.ChildNodes() {
+			if c.DataAtom != atom.Th {
+				continue
+			}
+			if header {
+				setAttr(c, "scope", "col")
+			} else {
+				setAttr(c, "scope", "row")
+			}
+		}
+		tbl.RemoveChild(row)
+		if header && tbody == nil {
+			if thead == nil {
+				thead = createElemAtom(atom.Thead)
+			}
+			thead.AppendChild(row)
+		} else {
+			if tbody == nil {
+				tbody = createElemAtom(atom.Tbody)
+			}
+			tbody.AppendChild(row)
+		}
+		row = next
+	}
+	// the caption (if any) stays ahead of the sections
+	if thead != nil {
+		tbl.AppendChild(thead)
+	}
+	if tbody != nil {
+		tbl.AppendChild(tbody)
+	}
+}
+
+// headerRow reports whether row is made up of header cells alone
+func headerRow(row *html.Node) bool {
+	if row.FirstChild == nil {
+		return false
+	}
+	for c := range row.ChildNodes() {
+		if c.DataAtom != atom.Th {
+			return false
+		}
+	}
+	return true
+}
+
+// rowWidth returns the number of columns row's cells span
+func rowWidth(row *html.Node) int {
+	width := 0
+	for c := range row.ChildNodes() {
+		if c.DataAtom != atom.Td && c.DataAtom != atom.Th {
+			continue
+		}
+		span := 1
+		if s, ok := attr(c, atom.Colspan.String()); ok {
+			if n, convErr := strconv.Atoi(s); convErr == nil && n > 0 {
+				span = n
+			}
+		}
+		width += span
+	}
+	return width
+}
+
+// setColspan sets the number of columns cell spans (leaving it alone if it's
+// just the one, or if n isn't known)
+func setColspan(cell *html.Node, n int) {
+	if n > 1 {
+		setAttr(cell, atom.Colspan.String(), strconv.Itoa(n))
+	}
+}
@@ -0,0 +1,412 @@
+package statuspage
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// textBlock is a rectangular-ish chunk of plain-text output, one string per line
+type textBlock []string
+
+// width returns the width (in runes) of the widest line in the block
+func (b textBlock) width() int {
+	w := 0
+	for _, l := range b {
+		w = max(w, utf8.RuneCountInString(l))
+	}
+	return w
+}
+
+func padRight(s string, w int) string {
+	if n := utf8.RuneCountInString(s); n < w {
+		return s + strings.Repeat(" ", w-n)
+	}
+	return s
+}
+
+// textTable is the plain-text analog of an HTML table. Rows may have fewer
+// cells than the widest row; missing cells are left blank.
+type textTable struct {
+	caption []string
+	header  []string
+	rows    [][]textBlock
+}
+
+// render draws the table with Unicode box-drawing characters
+func (t *textTable) render() textBlock {
+	nCols := len(t.header)
+	for _, r := range t.rows {
+		nCols = max(nCols, len(r))
+	}
+	out := append(textBlock{}, t.caption...)
+	if nCols == 0 {
+		return out
+	}
+	widths := make([]int, nCols)
+	for i, h := range t.header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, r := range t.rows {
+		for i, c := range r {
+			widths[i] = max(widths[i], c.width())
+		}
+	}
+
+	rule := func(left, mid, right string) string {
+		b := strings.Builder{}
+		b.WriteString(left)
+		for i, w := range widths {
+			if i > 0 {
+				b.WriteString(mid)
+			}
+			b.WriteString(strings.Repeat("─", w+2))
+		}
+		b.WriteString(right)
+		return b.String()
+	}
+	line := func(cells []string) string {
+		b := strings.Builder{}
+		b.WriteString("│")
+		for i, w := range widths {
+			if i > 0 {
+				b.WriteString("│")
+			}
+			c := ""
+			if i < len(cells) {
+				c = cells[i]
+			}
+			b.WriteString(" " + padRight(c, w) + " ")
+		}
+		b.WriteString("│")
+		return b.String()
+	}
+
+	out = append(out, rule("┌", "┬", "┐"))
+	if len(t.header) > 0 {
+		out = append(out, line(t.header), rule("├", "┼", "┤"))
+	}
+	for _, r := range t.rows {
+		height := 0
+		for _, c := range r {
+			height = max(height, len(c))
+		}
+		for l := range height {
+			cells := make([]string, len(r))
+			for i, c := range r {
+				if l < len(c) {
+					cells[i] = c[l]
+				}
+			}
+			out = append(out, line(cells))
+		}
+	}
+	out = append(out, rule("└", "┴", "┘"))
+	return out
+}
+
+// genTopLevelText renders the full plain-text page for v
+func (r *renderer) genTopLevelText(w io.Writer, v reflect.Value) error {
+	b, genErr := r.genTextVal(v)
+	if genErr != nil {
+		return genErr
+	}
+	out := strings.Builder{}
+	title := r.pageTitle()
+	out.WriteString(title + "\n")
+	out.WriteString(strings.Repeat("═", utf8.RuneCountInString(title)) + "\n\n")
+	if r.health != nil {
+		out.WriteString(r.healthBannerText() + "\n")
+	}
+	if errs := r.errorCountText(); errs != "" {
+		out.WriteString(errs + "\n\n")
+	}
+	if !r.snapshotAt.IsZero() {
+		out.WriteString("Snapshot as of " + r.snapshotAt.Format(snapshotTimeLayout) + r.snapshotSource() + "\n\n")
+	}
+	for _, l := range b {
+		out.WriteString(strings.TrimRight(l, " ") + "\n")
+	}
+	if hit := r.limitsHit(); hit != "" {
+		out.WriteString("\nRendering was truncated (reached " + hit + ").\n")
+	}
+	_, wErr := io.WriteString(w, out.String())
+	return wErr
+}
+
+// genTextVal is the plain-text counterpart to genValSection: it walks v
+// following the same rules and returns the lines to display. Failures are
+// rendered inline.
+func (r *renderer) genTextVal(v reflect.Value) (textBlock, error) {
+	return isolate(r, func() (textBlock, error) { return r.genTextLines(v) },
+		func(msg string) textBlock { return textBlock{errorMarker + msg} }), nil
+}
+
+// genTextLines does the work for genTextVal
+func (r *renderer) genTextLines(v reflect.Value) (textBlock, error) {
+	limit, leave := r.enterValue(v)
+	if limit != 0 {
+		return textBlock{r.truncatedText(limit)}, nil
+	}
+	defer leave()
+	if key, trackable := visitKeyOf(v); trackable {
+		if first, seen := r.visited[key]; seen {
+			return textBlock{seeAboveLabel + ": " + r.pathLabel(first)}, nil
+		}
+		r.markVisited(key)
+	}
+	if tr, ok := r.opts.valueRenderer(v); ok {
+		ns, rErr := tr.render(v)
+		if rErr != nil {
+			return nil, rErr
+		}
+		st := nodesText(ns)
+		r.spendBytes(len(st))
+		return strings.Split(st, "\n"), nil
+	}
+	if v.Kind() == reflect.Pointer && !v.IsNil() && r.opts.pointsToRendered(v.Type()) {
+		return r.genTextVal(v.Elem())
+	}
+	if st, ok := r.formattedText(v); ok {
+		r.spendBytes(len(st))
+		return textBlock{st}, nil
+	}
+	if st, ok := scalarText(v); ok {
+		r.spendBytes(len(st))
+		return strings.Split(st, "\n"), nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return r.genStructText(v)
+	case reflect.Map:
+		return r.genMapOrSeq2Text(v)
+	case reflect.Array, reflect.Slice:
+		return r.genSliceArrayText(v)
+	case reflect.Pointer, reflect.Interface:
+		// Delegate after following the bouncing ball
+		return r.genTextVal(v.Elem())
+	case reflect.Func:
+		if v.Type().CanSeq2() {
+			return r.genMapOrSeq2Text(v)
+		}
+		return r.genSliceArrayText(v)
+	default:
+		return nil, fmt.Errorf("unhandled kind %s (type %s)", v.Kind(), v.Type())
+	}
+}
+
+// genStructText renders the simple fields of a struct as a key/value block
+// followed by a titled section for each field that needs its own table,
+// mirroring genStructTable.
+func (r *renderer) genStructText(v reflect.Value) (textBlock, error) {
+	simple := textTable{}
+	sections := textBlock{}
+	fields, fieldsErr := renderableFields(v)
+	if fieldsErr != nil {
+		return nil, fieldsErr
+	}
+	for _, f := range fields {
+		ascend := r.enterField(&f)
+		fb, fErr := r.genTextVal(v.FieldByIndex(f.Index))
+		ascend()
+		if fErr != nil {
+			return nil, fmt.Errorf("failed to render field %q: %w", f.Name, fErr)
+		}
+		name := f.displayName()
+		if !r.needsTable(f.Type) {
+			simple.rows = append(simple.rows, []textBlock{{name}, fb})
+			continue
+		}
+		sections = append(sections, "", name, strings.Repeat("─", utf8.RuneCountInString(name)))
+		sections = append(sections, fb...)
+	}
+	out := textBlock{}
+	if len(simple.rows) > 0 {
+		out = simple.render()
+	}
+	if len(out) == 0 && len(sections) > 0 {
+		// drop the leading blank line
+		sections = sections[1:]
+	}
+	return append(out, sections...), nil
+}
+
+// genMapOrSeq2Text renders maps and iter.Seq2 values as key/value tables
+func (r *renderer) genMapOrSeq2Text(v reflect.Value) (textBlock, error) {
+	tbl := textTable{header: []string{mapKeyHeader, mapValueHeader}}
+	pw := r.pageWindow(v)
+	for ik, iv := range pw.pairs(r.opts.mapEntries(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.rows = append(tbl.rows, []textBlock{{r.truncatedText(l)}})
+			break
+		}
+		kb, kErr := r.genTextVal(ik)
+		if kErr != nil {
+			return nil, fmt.Errorf("failed to render key of type %s: %w", ik.Type(), kErr)
+		}
+		if iv.Kind() == reflect.Map && isSet(iv.Type()) && !iv.IsNil() {
+			// render sets as a list of their members, as genMapOrSeq2Table does
+			members := make([]string, 0, iv.Len())
+			for m := range r.opts.mapKeys(iv) {
+				mb, mErr := r.genTextVal(m)
+				if mErr != nil {
+					return nil, fmt.Errorf("failed to render set member for key %q: %w", strings.Join(kb, " "), mErr)
+				}
+				members = append(members, strings.Join(mb, " "))
+			}
+			tbl.rows = append(tbl.rows, []textBlock{kb, {strings.Join(members, ", ")}})
+			continue
+		}
+		ascend := r.descend(keyStep(ik))
+		vb, vErr := r.genTextVal(iv)
+		ascend()
+		if vErr != nil {
+			return nil, fmt.Errorf("failed to render value for key %q: %w", strings.Join(kb, " "), vErr)
+		}
+		tbl.rows = append(tbl.rows, []textBlock{kb, vb})
+	}
+	tbl.caption = r.pageCaptionText(pw)
+	return tbl.render(), nil
+}
+
+// textSeqCaption returns the caption lines describing an array, slice or
+// iter.Seq, matching the caption genSliceArrayTable generates.
+func textSeqCaption(v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Slice:
+		return []string{v.Type().String() + " len() = " + strconv.Itoa(v.Len()) + " cap() = " + strconv.Itoa(v.Cap())}
+	case reflect.Func:
+		return []string{"iter.Seq: " + seqElemType(v.Type()).String()}
+	default:
+		return []string{v.Type().String()}
+	}
+}
+
+// genSliceArrayText renders arrays, slices and iter.Seq values: scalars in a
+// single column, structs as one column per field (like structSliceArrayTable)
+// and nested slices/arrays as a grid.
+func (r *renderer) genSliceArrayText(v reflect.Value) (textBlock, error) {
+	tbl := textTable{caption: textSeqCaption(v)}
+	if v.Kind() == reflect.Array && v.Len() == 0 {
+		return tbl.caption, nil
+	}
+	elemType := seqElemType(v.Type())
+	pw := r.pageWindow(v)
+
+	structType := reflect.Type(nil)
+	switch {
+	case r.sliceArrayValScalar(elemType):
+	case elemType.Kind() == reflect.Struct || elemType.Kind() == reflect.Pointer:
+		structType = elemType
+	case elemType.Kind() == reflect.Interface:
+		if t, uniform := allIfaceSliceElemsSame(v, pw); uniform {
+			structType = t
+		}
+	}
+	for structType != nil && structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType != nil && structType.Kind() != reflect.Struct {
+		structType = nil
+	}
+
+	var fields []structField
+	if structType != nil {
+		var fieldsErr error
+		if fields, fieldsErr = structFields(structType); fieldsErr != nil {
+			return nil, fieldsErr
+		}
+		for _, f := range fields {
+			tbl.header = append(tbl.header, f.displayName())
+		}
+	}
+
+	for offset, ev := range pw.elems(seqElems(v)) {
+		if l := r.exhausted(); l != 0 {
+			tbl.rows = append(tbl.rows, []textBlock{{r.truncatedText(l)}})
+			break
+		}
+		ascend := r.descend(indexStep(offset))
+		row, rowErr := r.genSeqElemTextRow(ev, fields)
+		ascend()
+		if rowErr != nil {
+			return nil, fmt.Errorf("failed to render element %d of %s: %w", offset, v.Type(), rowErr)
+		}
+		tbl.rows = append(tbl.rows, row)
+	}
+	tbl.caption = append(tbl.caption, r.pageCaptionText(pw)...)
+	return tbl.render(), nil
+}
+
+// genSeqElemTextRow renders a single element of a sequence as a table row.
+// If fields is non-nil, the element is a struct (or pointer to one) and is
+// split into one cell per field.
+func (r *renderer) genSeqElemTextRow(ev reflect.Value, fields []structField) ([]textBlock, error) {
+	if fields != nil {
+		for ev.Kind() == reflect.Pointer || ev.Kind() == reflect.Interface {
+			if ev.IsNil() {
+				return []textBlock{{ev.Type().String() + "(nil)"}}, nil
+			}
+			if key, trackable := visitKeyOf(ev); trackable {
+				if first, seen := r.visited[key]; seen {
+					return []textBlock{{seeAboveLabel + ": " + r.pathLabel(first)}}, nil
+				}
+				r.markVisited(key)
+			}
+			ev = ev.Elem()
+		}
+		row := make([]textBlock, 0, len(fields))
+		for _, f := range fields {
+			if f.omitted(ev) {
+				row = append(row, textBlock{})
+				continue
+			}
+			ascend := r.enterField(&f)
+			fb, fErr := r.genTextVal(ev.FieldByIndex(f.Index))
+			ascend()
+			if fErr != nil {
+				return nil, fmt.Errorf("failed to render field %q: %w", f.Name, fErr)
+			}
+			row = append(row, fb)
+		}
+		return row, nil
+	}
+
+	// Nested arrays/slices get one cell per element
+	inner := ev
+	for inner.Kind() == reflect.Pointer || inner.Kind() == reflect.Interface {
+		if inner.IsNil() {
+			break
+		}
+		inner = inner.Elem()
+	}
+	if (inner.Kind() == reflect.Slice && !inner.IsNil()) || inner.Kind() == reflect.Array {
+		if !r.sliceArrayValScalar(inner.Type().Elem()) {
+			b, bErr := r.genTextVal(inner)
+			return []textBlock{b}, bErr
+		}
+		row := make([]textBlock, 0, inner.Len())
+		for colVal := range seqElems(inner) {
+			if r.opts.pageSize > 0 && len(row) == r.opts.pageSize {
+				row = append(row, textBlock{r.moreText(inner.Len() - len(row))})
+				break
+			}
+			ascend := r.descend(indexStep(len(row)))
+			cb, cErr := r.genTextVal(colVal)
+			ascend()
+			if cErr != nil {
+				return nil, fmt.Errorf("failed to render column %d: %w", len(row), cErr)
+			}
+			row = append(row, cb)
+		}
+		return row, nil
+	}
+	b, bErr := r.genTextVal(ev)
+	if bErr != nil {
+		return nil, bErr
+	}
+	return []textBlock{b}, nil
+}
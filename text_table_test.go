@@ -0,0 +1,118 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTextTableRender(t *testing.T) {
+	tbl := textTable{
+		caption: []string{"caption"},
+		header:  []string{"a", "b"},
+		rows: [][]textBlock{
+			{{"1"}, {"two", "lines"}},
+			{{"wïde"}},
+			{{"x"}, {"y"}, {"extra"}},
+		},
+	}
+	want := []string{
+		"caption",
+		"┌──────┬───────┬───────┐",
+		"│ a    │ b     │       │",
+		"├──────┼───────┼───────┤",
+		"│ 1    │ two   │       │",
+		"│      │ lines │       │",
+		"│ wïde │       │       │",
+		"│ x    │ y     │ extra │",
+		"└──────┴───────┴───────┘",
+	}
+	if got := tbl.render(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("render:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := (&textTable{caption: []string{"empty"}}).render(); len(got) != 1 || got[0] != "empty" {
+		t.Errorf("empty table = %q; want just its caption", got)
+	}
+}
+
+type textElem struct {
+	A int
+	B string `statuspage:"name=Bee"`
+}
+
+type textVal struct {
+	Name  string
+	Count int
+	Skip  int `statuspage:"-"`
+	Inner textElem
+	Items []textElem
+	M     map[string]int
+	Ptrs  []*textElem
+}
+
+func TestText(t *testing.T) {
+	shared := &textElem{A: 1, B: "x"}
+	v := textVal{
+		Name: "n", Count: 3, Skip: 1,
+		Inner: textElem{A: 2},
+		Items: []textElem{{1, "a"}, {22, "bb"}},
+		M:     map[string]int{"b": 2, "a": 1},
+		Ptrs:  []*textElem{shared, shared},
+	}
+	s := New("Test", func() textVal { return v })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "curl/8.5.0")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q; want plain text", ct)
+	}
+	want := `Test
+════
+
+┌───────┬─────────┐
+│ Name  │ n       │
+│ Count │ 3 (0x3) │
+└───────┴─────────┘
+
+Inner
+─────
+┌─────┬─────────┐
+│ A   │ 2 (0x2) │
+│ Bee │         │
+└─────┴─────────┘
+
+Items
+─────
+[]statuspage.textElem len() = 2 cap() = 2
+┌───────────┬─────┐
+│ A         │ Bee │
+├───────────┼─────┤
+│ 1 (0x1)   │ a   │
+│ 22 (0x16) │ bb  │
+└───────────┴─────┘
+
+M
+─
+┌─────┬─────────┐
+│ key │ value   │
+├─────┼─────────┤
+│ a   │ 1 (0x1) │
+│ b   │ 2 (0x2) │
+└─────┴─────────┘
+
+Ptrs
+────
+[]*statuspage.textElem len() = 2 cap() = 2
+┌────────────────────────────────┬─────┐
+│ A                              │ Bee │
+├────────────────────────────────┼─────┤
+│ 1 (0x1)                        │ x   │
+│ ↻ see above: Test › Ptrs › [0] │     │
+└────────────────────────────────┴─────┘
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("text:\n%s\nwant:\n%s", got, want)
+	}
+}
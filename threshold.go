@@ -0,0 +1,234 @@
+package statuspage
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// threshold is a comparison against a number (or duration) from a tag
+// directive, e.g. the ">=0.95" in crit>=0.95.
+type threshold struct {
+	op  string
+	val float64
+	// text is the value as written in the tag
+	text string
+}
+
+// thresholdOps are the comparisons thresholds may use, longest first so
+// they're matched greedily
+var thresholdOps = []string{">=", "<=", ">", "<"}
+
+// cutThreshold splits a directive of the form <name><op><value> (e.g.
+// warn>=0.8) into its name and the rest, reporting false if it isn't
+// of that form.
+func cutThreshold(directive string) (name, rest string, ok bool) {
+	i := strings.IndexAny(directive, "<>")
+	if i <= 0 {
+		return "", "", false
+	}
+	name = strings.TrimSpace(directive[:i])
+	if strings.ContainsAny(name, "= ") {
+		// a comparison within the value of some other directive
+		return "", "", false
+	}
+	return name, directive[i:], true
+}
+
+// parseThreshold parses the comparison in a threshold directive for a field
+// of type t, which must be a number or a map of numbers. Thresholds for
+// time.Durations are written as durations (e.g. >1.5s).
+func parseThreshold(s string, t reflect.Type) (threshold, error) {
+	if !isNumericType(valueOrMapElem(t)) {
+		return threshold{}, fmt.Errorf("thresholds require a number or a map of numbers, not %s", t)
+	}
+	th := threshold{}
+	for _, op := range thresholdOps {
+		if rest, found := strings.CutPrefix(s, op); found {
+			th.op, th.text = op, strings.TrimSpace(rest)
+			break
+		}
+	}
+	if th.op == "" {
+		return threshold{}, fmt.Errorf("invalid comparison %q (expected one of %s)", s, strings.Join(thresholdOps, " "))
+	}
+	if valueOrMapElem(t) == reflect.TypeFor[time.Duration]() {
+		d, parseErr := time.ParseDuration(th.text)
+		if parseErr != nil {
+			return threshold{}, parseErr
+		}
+		th.val = float64(d)
+		return th, nil
+	}
+	f, parseErr := strconv.ParseFloat(th.text, 64)
+	if parseErr != nil {
+		return threshold{}, fmt.Errorf("invalid threshold %q: %w", th.text, parseErr)
+	}
+	th.val = f
+	return th, nil
+}
+
+// crossed reports whether f is on the far side of the threshold
+func (th *threshold) crossed(f float64) bool {
+	switch th.op {
+	case ">=":
+		return f >= th.val
+	case "<=":
+		return f <= th.val
+	case ">":
+		return f > th.val
+	default:
+		return f < th.val
+	}
+}
+
+// String returns the threshold as written in the tag
+func (th *threshold) String() string { return th.op + th.text }
+
+// thresholdValText formats the number v for a failed threshold
+func thresholdValText(v reflect.Value) string {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		return time.Duration(v.Int()).String()
+	}
+	f, _ := numericValue(v)
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// valueLevel is how far past its field's warn and crit thresholds a number is
+type valueLevel int
+
+const (
+	levelNone valueLevel = iota
+	levelWarn
+	levelCrit
+)
+
+// Classes marking the values (and cells) past their thresholds
+const (
+	warnClass = "sp-warn"
+	critClass = "sp-crit"
+)
+
+// levelThresholds are the warn and crit thresholds of a field (either may be
+// nil)
+type levelThresholds struct {
+	warn, crit *threshold
+}
+
+// level returns the level of f, the crit threshold taking precedence
+func (lt *levelThresholds) level(f float64) valueLevel {
+	switch {
+	case lt.crit != nil && lt.crit.crossed(f):
+		return levelCrit
+	case lt.warn != nil && lt.warn.crossed(f):
+		return levelWarn
+	default:
+		return levelNone
+	}
+}
+
+// withThresholds sets the thresholds for the values about to be rendered,
+// returning a func that restores the previous ones.
+func (r *renderer) withThresholds(lt levelThresholds) func() {
+	thresholds := r.thresholds
+	r.thresholds = lt
+	return func() { r.thresholds = thresholds }
+}
+
+// withLevel wraps ns (which renders v) in a span marking it as past its
+// warn or crit threshold, if it is.
+func (r *renderer) withLevel(v reflect.Value, ns []*html.Node) []*html.Node {
+	if r.thresholds.warn == nil && r.thresholds.crit == nil {
+		return ns
+	}
+	f, numeric := numericValue(v)
+	if !numeric {
+		return ns
+	}
+	span := createElemAtom(atom.Span)
+	switch r.thresholds.level(f) {
+	case levelCrit:
+		r.crits++
+		setAttr(span, atom.Class.String(), critClass)
+		setAttr(span, atom.Title.String(), "critical ("+r.thresholds.crit.String()+")")
+	case levelWarn:
+		setAttr(span, atom.Class.String(), warnClass)
+		setAttr(span, atom.Title.String(), "warning ("+r.thresholds.warn.String()+")")
+	default:
+		return ns
+	}
+	for _, n := range ns {
+		span.AppendChild(n)
+	}
+	return []*html.Node{span}
+}
+
+// setCellLevel marks the table cell as past its threshold if the value it
+// holds is (as marked by withLevel).
+func setCellLevel(cell *html.Node) {
+	level := levelNone
+	for c := range cell.ChildNodes() {
+		switch {
+		case hasClass(c, critClass):
+			level = levelCrit
+		case hasClass(c, warnClass):
+			level = max(level, levelWarn)
+		}
+	}
+	switch level {
+	case levelCrit:
+		addClass(cell, critClass)
+	case levelWarn:
+		addClass(cell, warnClass)
+	}
+}
+
+// hasCrit reports whether n holds a value past its crit threshold
+func hasCrit(n *html.Node) bool {
+	if hasClass(n, critClass) {
+		return true
+	}
+	for c := range n.ChildNodes() {
+		if hasCrit(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagCritRows notes the number of tbl's rows holding values past their crit
+// thresholds in its caption (adding one if need be), so they stand out.
+func (r *renderer) flagCritRows(tbl *html.Node) {
+	if r.crits == 0 {
+		// there's nothing to find
+		return
+	}
+	n := 0
+	for row := range tbl.ChildNodes() {
+		if row.DataAtom == atom.Tr && hasCrit(row) {
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	capNode := tbl.FirstChild
+	if capNode == nil || capNode.DataAtom != atom.Caption {
+		capNode = createElemAtom(atom.Caption)
+		tbl.InsertBefore(capNode, tbl.FirstChild)
+	} else {
+		capNode.AppendChild(createElemAtom(atom.Br))
+	}
+	flag := createElemClass(atom.Strong, critClass)
+	rows := "rows hold"
+	if n == 1 {
+		rows = "row holds"
+	}
+	flag.AppendChild(textNode("‼ " + strconv.Itoa(n) + " " + rows + " critical values"))
+	capNode.AppendChild(flag)
+}
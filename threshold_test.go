@@ -0,0 +1,140 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseThreshold(t *testing.T) {
+	floatType, durType := reflect.TypeFor[float64](), reflect.TypeFor[time.Duration]()
+	for _, tc := range []struct {
+		s       string
+		typ     reflect.Type
+		want    threshold
+		wantErr string
+	}{
+		{">=0.95", floatType, threshold{op: ">=", val: 0.95, text: "0.95"}, ""},
+		{"<= 1", floatType, threshold{op: "<=", val: 1, text: "1"}, ""},
+		{"<-2", reflect.TypeFor[int8](), threshold{op: "<", val: -2, text: "-2"}, ""},
+		{">1m", durType, threshold{op: ">", val: float64(time.Minute), text: "1m"}, ""},
+		{">3", reflect.TypeFor[map[string]*uint](), threshold{op: ">", val: 3, text: "3"}, ""},
+		{">1s", reflect.TypeFor[map[string]time.Duration](), threshold{op: ">", val: float64(time.Second), text: "1s"}, ""},
+
+		{">1", reflect.TypeFor[string](), threshold{}, "thresholds require a number"},
+		{"=1", floatType, threshold{}, "invalid comparison"},
+		{">x", floatType, threshold{}, `invalid threshold "x"`},
+		{">1", durType, threshold{}, "missing unit"},
+	} {
+		got, err := parseThreshold(tc.s, tc.typ)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parseThreshold(%q, %s) = %+v, %v; want an error containing %q", tc.s, tc.typ, got, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseThreshold(%q, %s) = %+v, %v; want %+v", tc.s, tc.typ, got, err, tc.want)
+		}
+	}
+}
+
+func TestThresholdLevel(t *testing.T) {
+	lt := levelThresholds{
+		warn: &threshold{op: ">=", val: 0.8},
+		crit: &threshold{op: ">", val: 0.95},
+	}
+	for _, tc := range []struct {
+		f    float64
+		want valueLevel
+	}{
+		{0.5, levelNone},
+		{0.8, levelWarn},
+		{0.95, levelWarn},
+		{0.96, levelCrit},
+	} {
+		if got := lt.level(tc.f); got != tc.want {
+			t.Errorf("level(%v) = %d; want %d", tc.f, got, tc.want)
+		}
+	}
+	// either may be left out
+	critOnly := levelThresholds{crit: &threshold{op: "<", val: 0}}
+	if critOnly.level(1) != levelNone || critOnly.level(-1) != levelCrit {
+		t.Error("crit-only thresholds misjudged")
+	}
+}
+
+type loadRow struct {
+	Name string
+	Load float64 `statuspage:"warn>=0.8,crit>=0.95"`
+}
+
+type thresholdVal struct {
+	Load  float64       `statuspage:"warn>=0.8,crit>=0.95"`
+	Lat   time.Duration `statuspage:"warn>1s"`
+	Fine  int           `statuspage:"crit>5"`
+	Rows  []loadRow
+	Queue map[string]int `statuspage:"crit>5"`
+}
+
+func TestThresholdRendering(t *testing.T) {
+	v := thresholdVal{
+		Load:  0.96,
+		Lat:   2 * time.Second,
+		Fine:  1,
+		Rows:  []loadRow{{"a", 0.5}, {"b", 0.9}, {"c", 0.99}},
+		Queue: map[string]int{"x": 6, "y": 1, "z": 7},
+	}
+	s := New("Test", func() thresholdVal { return v })
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	doc, parseErr := html.Parse(strings.NewReader(body))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	// the levels of the cells past their thresholds, by row id
+	levels := map[string]string{}
+	flags := map[string]string{}
+	for d := range doc.Descendants() {
+		switch {
+		case d.Data == "td" && (hasClass(d, critClass) || hasClass(d, warnClass)):
+			id, _ := attr(d.Parent, "id")
+			class, _ := attr(d, "class")
+			levels[id] = class
+		case d.Data == "strong" && hasClass(d, critClass):
+			// the flag's in the caption of the table
+			id, _ := attr(d.Parent.Parent, "id")
+			flags[id] = textContent(d)
+		}
+	}
+	wantLevels := map[string]string{
+		"row/Load":    "sp-value sp-crit",
+		"row/Lat":     "sp-value sp-warn",
+		"row/Rows[1]": "sp-value sp-warn",
+		"row/Rows[2]": "sp-value sp-crit",
+		"row/Queue/x": "sp-value sp-crit",
+		"row/Queue/z": "sp-value sp-crit",
+	}
+	if !reflect.DeepEqual(levels, wantLevels) {
+		t.Errorf("cell levels = %v; want %v", levels, wantLevels)
+	}
+	wantFlags := map[string]string{
+		"sp":       "‼ 1 row holds critical values",
+		"sp/Rows":  "‼ 1 row holds critical values",
+		"sp/Queue": "‼ 2 rows hold critical values",
+	}
+	if !reflect.DeepEqual(flags, wantFlags) {
+		t.Errorf("caption flags = %v; want %v", flags, wantFlags)
+	}
+	if !strings.Contains(body, `title="critical (&gt;=0.95)"`) ||
+		!strings.Contains(body, `title="warning (&gt;1s)"`) {
+		t.Errorf("values past their thresholds don't say which:\n%s", body)
+	}
+}
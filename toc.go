@@ -0,0 +1,142 @@
+package statuspage
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// copyLinkClass marks the links next to section headings that copy the
+// sections' URLs
+const copyLinkClass = "sp-copy-link"
+
+// sectionHeading returns the heading of the section (with the id id)
+// rendering the field f, along with a link to the section that copies its
+// URL when clicked.
+func sectionHeading(f *structField, id string) *html.Node {
+	heading := createElemClass(atom.H3, "sp-field-name")
+	heading.AppendChild(textNode(f.displayName()))
+	setHelp(heading, f)
+	link := createElemClass(atom.A, copyLinkClass)
+	setAttr(link, atom.Href.String(), "#"+id)
+	setAttr(link, atom.Title.String(), "Copy link")
+	setAttr(link, "aria-label", "Copy link to "+f.displayName())
+	link.AppendChild(textNode("¶"))
+	heading.AppendChild(textNode(" "))
+	heading.AppendChild(link)
+	return heading
+}
+
+// copyLinkScript copies the URLs of the sections whose links are clicked
+// (without it, the links just point the page at them).
+const copyLinkScript = `document.addEventListener("click", function (e) {
+	var a = e.target.closest && e.target.closest("a.` + copyLinkClass + `");
+	if (!a || !navigator.clipboard) {
+		return;
+	}
+	e.preventDefault();
+	history.replaceState(null, "", a.hash);
+	navigator.clipboard.writeText(a.href).then(function () {
+		a.classList.add("sp-copied");
+		setTimeout(function () { a.classList.remove("sp-copied"); }, 1500);
+	});
+});
+`
+
+// addCopyLinkScript adds copyLinkScript to head
+func addCopyLinkScript(head *html.Node) {
+	script := createElemAtom(atom.Script)
+	script.AppendChild(textNode(copyLinkScript))
+	head.AppendChild(script)
+}
+
+// tocEntry is an entry in a page's table of contents: a section, along with
+// the sections nested within it
+type tocEntry struct {
+	id, label string
+	children  []tocEntry
+}
+
+// tocEntries returns the entries for the sections within n. Sections within
+// tables are left out: they belong to the entries of maps and slices, rather
+// than to the struct hierarchy.
+func tocEntries(n *html.Node) []tocEntry {
+	out := []tocEntry{}
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode || c.DataAtom == atom.Table {
+			continue
+		}
+		if e, ok := tocEntryOf(c); ok {
+			out = append(out, e)
+			continue
+		}
+		out = append(out, tocEntries(c)...)
+	}
+	return out
+}
+
+// tocEntryOf returns the entry for n, if it's a section with a heading
+func tocEntryOf(n *html.Node) (tocEntry, bool) {
+	id, hasID := attr(n, atom.Id.String())
+	if !hasID || !strings.HasPrefix(id, "sec") || !hasClass(n, "sp-section") {
+		return tocEntry{}, false
+	}
+	for c := range n.ChildNodes() {
+		if c.DataAtom != atom.H3 {
+			continue
+		}
+		if c.FirstChild == nil || c.FirstChild.Type != html.TextNode {
+			return tocEntry{}, false
+		}
+		return tocEntry{id: id, label: c.FirstChild.Data, children: tocEntries(n)}, true
+	}
+	return tocEntry{}, false
+}
+
+// genTOC returns the table of contents sidebar for the page sections, or nil
+// if they hold no sections with headings (i.e. there'd be nothing to
+// navigate between). Entries with sections nested within them can be
+// collapsed.
+func genTOC(sections []*html.Node) *html.Node {
+	entries := []tocEntry{}
+	for _, sec := range sections {
+		if e, ok := tocEntryOf(sec); ok {
+			entries = append(entries, e)
+			continue
+		}
+		entries = append(entries, tocEntries(sec)...)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	nav := createElemClass(atom.Nav, "sp-toc")
+	setAttr(nav, "aria-label", "Contents")
+	nav.AppendChild(collapsed("Contents", []*html.Node{tocList(entries)}))
+	setAttr(nav.FirstChild, atom.Open.String(), "")
+	return nav
+}
+
+// tocList returns the list of links to entries (and their children)
+func tocList(entries []tocEntry) *html.Node {
+	ul := createElemAtom(atom.Ul)
+	for _, e := range entries {
+		li := createElemAtom(atom.Li)
+		link := createElemAtom(atom.A)
+		setAttr(link, atom.Href.String(), "#"+e.id)
+		link.AppendChild(textNode(e.label))
+		if len(e.children) == 0 {
+			li.AppendChild(link)
+		} else {
+			details := createElemAtom(atom.Details)
+			setAttr(details, atom.Open.String(), "")
+			summary := createElemAtom(atom.Summary)
+			summary.AppendChild(link)
+			details.AppendChild(summary)
+			details.AppendChild(tocList(e.children))
+			li.AppendChild(details)
+		}
+		ul.AppendChild(li)
+	}
+	return ul
+}
@@ -0,0 +1,166 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+type tocBackend struct {
+	Addr  string
+	Conns []int
+}
+
+type tocDB struct {
+	Primary  tocBackend
+	Replicas []tocBackend
+}
+
+type tocVal struct {
+	Name     string
+	DB       tocDB
+	Backends map[string]tocBackend
+	Tags     []string
+}
+
+// renderPage serves v as an HTML page, parsed back into a tree
+func renderPage[T any](t *testing.T, v T) *html.Node {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	New("Test", func() T { return v }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	doc, parseErr := html.Parse(rec.Body)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	return doc
+}
+
+// tocOutline summarizes the entries of the list ul, with the entries nested
+// within them in braces
+func tocOutline(ul *html.Node) string {
+	out := []string{}
+	for li := range ul.ChildNodes() {
+		entry := li.FirstChild
+		if entry.Data == "a" {
+			out = append(out, textContent(entry))
+			continue
+		}
+		// a details, with the link in its summary, followed by the
+		// nested list
+		out = append(out, textContent(entry.FirstChild)+"{"+tocOutline(entry.LastChild)+"}")
+	}
+	return strings.Join(out, " ")
+}
+
+func TestTOC(t *testing.T) {
+	doc := renderPage(t, tocVal{
+		DB:       tocDB{Primary: tocBackend{Conns: []int{1}}, Replicas: []tocBackend{{}}},
+		Backends: map[string]tocBackend{"a": {Conns: []int{2}}},
+		Tags:     []string{"t"},
+	})
+	var nav *html.Node
+	byID := map[string]*html.Node{}
+	for d := range doc.Descendants() {
+		if hasClass(d, "sp-toc") {
+			nav = d
+		}
+		if id, ok := attr(d, "id"); ok {
+			byID[id] = d
+		}
+	}
+	if nav == nil {
+		t.Fatal("page has no table of contents")
+	}
+	if body := findElem(doc, "body"); !hasClass(body, "sp-with-toc") {
+		t.Error("body isn't marked as having a table of contents")
+	}
+
+	// the struct hierarchy, leaving out the sections within the map's
+	// table
+	contents := nav.FirstChild
+	if got, want := tocOutline(contents.LastChild), "DB{Primary{Conns} Replicas} Backends Tags"; got != want {
+		t.Errorf("contents = %s; want %s", got, want)
+	}
+	for d := range nav.Descendants() {
+		href, ok := attr(d, "href")
+		if !ok {
+			continue
+		}
+		sec := byID[strings.TrimPrefix(href, "#")]
+		if sec == nil || !hasClass(sec, "sp-section") {
+			t.Errorf("contents link to %s, which isn't a section", href)
+		}
+	}
+
+	// every section heading links to its own section
+	for id, sec := range byID {
+		if !hasClass(sec, "sp-section") || id == "sec" {
+			continue
+		}
+		heading := sec.FirstChild
+		link := heading.LastChild
+		if href, _ := attr(link, "href"); !hasClass(link, copyLinkClass) || href != "#"+id {
+			t.Errorf("heading of %s has no copy link to it", id)
+		}
+	}
+}
+
+func TestTOCOmitted(t *testing.T) {
+	doc := renderPage(t, struct{ A, B int }{})
+	for d := range doc.Descendants() {
+		if hasClass(d, "sp-toc") || hasClass(d, "sp-with-toc") {
+			t.Errorf("page without sections has a table of contents: <%s class=%q>", d.Data, attrVal(d, "class"))
+		}
+	}
+}
+
+func attrVal(n *html.Node, key string) string {
+	v, _ := attr(n, key)
+	return v
+}
+
+// pageIDs returns the ids on the page rendering v
+func pageIDs[T any](t *testing.T, v T) map[string]bool {
+	t.Helper()
+	ids := map[string]bool{}
+	for d := range renderPage(t, v).Descendants() {
+		if id, ok := attr(d, "id"); ok {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func TestStableAnchors(t *testing.T) {
+	before := pageIDs(t, tocVal{
+		DB:       tocDB{Replicas: []tocBackend{{}}},
+		Backends: map[string]tocBackend{"a b": {}},
+	})
+	// the anchors are derived from the values' paths, so the values'
+	// contents (and the other values around them) don't change them
+	after := pageIDs(t, tocVal{
+		Name:     "changed",
+		DB:       tocDB{Replicas: []tocBackend{{Addr: "x"}, {}}},
+		Backends: map[string]tocBackend{"a b": {Addr: "y"}, "0": {}},
+	})
+	for _, id := range []string{
+		"sec", "sp", "row/Name",
+		"sec/DB", "sec/DB/Replicas", "sp/DB/Replicas", "row/DB/Replicas[0]",
+		"sec/Backends", "sp/Backends", "row/Backends/a%20b",
+	} {
+		if !before[id] || !after[id] {
+			t.Errorf("id %s isn't on both pages (%t, %t)", id, before[id], after[id])
+		}
+	}
+	for id := range before {
+		if !after[id] {
+			t.Errorf("id %s went away", id)
+		}
+	}
+}
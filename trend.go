@@ -0,0 +1,307 @@
+package statuspage
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultTrendPoints is the number of samples kept for each trend unless
+// overridden with WithTrends.
+const defaultTrendPoints = 60
+
+// maxTrendSeries bounds the number of values trends are kept for, since
+// trends of map values get a series per key.
+const maxTrendSeries = 1024
+
+// trendPoint is a single sample of a trend
+type trendPoint struct {
+	at  time.Time
+	val float64
+}
+
+// trendSeries is a ring buffer of the most recent samples of a value
+type trendSeries struct {
+	points []trendPoint
+	next   int
+}
+
+func (ts *trendSeries) add(p trendPoint, size int) {
+	if len(ts.points) < size {
+		ts.points = append(ts.points, p)
+		return
+	}
+	ts.points[ts.next] = p
+	ts.next = (ts.next + 1) % len(ts.points)
+}
+
+// ordered returns the samples, oldest first
+func (ts *trendSeries) ordered() []trendPoint {
+	out := make([]trendPoint, 0, len(ts.points))
+	out = append(out, ts.points[ts.next:]...)
+	return append(out, ts.points[:ts.next]...)
+}
+
+// trendStore holds the trends of the values in fields tagged with trend, by
+// the (URL form of the) path to the value.
+type trendStore struct {
+	points int
+
+	mu     sync.Mutex
+	series map[string]*trendSeries
+}
+
+func newTrendStore(points int) *trendStore {
+	return &trendStore{points: points, series: map[string]*trendSeries{}}
+}
+
+// record samples the trends within v, a value loaded at t. Trends of values
+// that are no longer there (e.g. map entries that were deleted) are dropped.
+func (ts *trendStore) record(v reflect.Value, t time.Time) {
+	samples := map[string]float64{}
+	c := trendCollector{samples: samples, seen: map[visitKey]struct{}{}}
+	c.collect(v, nil, trendNone)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for p := range ts.series {
+		if _, ok := samples[p]; !ok {
+			delete(ts.series, p)
+		}
+	}
+	for p, val := range samples {
+		s, ok := ts.series[p]
+		if !ok {
+			if len(ts.series) >= maxTrendSeries {
+				continue
+			}
+			s = &trendSeries{}
+			ts.series[p] = s
+		}
+		s.add(trendPoint{at: t, val: val}, ts.points)
+	}
+}
+
+// trend returns the samples of the value at p, oldest first
+func (ts *trendStore) trend(p fieldPath) []trendPoint {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	s, ok := ts.series[p.String()]
+	if !ok {
+		return nil
+	}
+	return s.ordered()
+}
+
+// trendMode is how a value within a field tagged with trend is sampled
+type trendMode int
+
+const (
+	// trendNone values aren't sampled (though values within them may be)
+	trendNone trendMode = iota
+	// trendField values are the fields tagged with trend: numbers are
+	// sampled, as are the values of maps
+	trendField
+	// trendValue values are sampled if they're numbers
+	trendValue
+)
+
+// trendCollector holds the state for a single trendStore.record
+type trendCollector struct {
+	samples map[string]float64
+	// seen holds the pointers already walked, to break cycles
+	seen map[visitKey]struct{}
+}
+
+func (c *trendCollector) collect(v reflect.Value, p fieldPath, mode trendMode) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Pointer {
+			key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+			if _, seen := c.seen[key]; seen {
+				return
+			}
+			c.seen[key] = struct{}{}
+		}
+		v = v.Elem()
+	}
+	if mode != trendNone {
+		if f, ok := numericValue(v); ok {
+			c.samples[p.String()] = f
+			return
+		}
+	}
+	if !mayHaveTrends(v.Type()) && mode != trendField {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, fieldsErr := visibleFields(v)
+		if fieldsErr != nil {
+			return
+		}
+		for _, f := range fields {
+			fieldMode := trendNone
+			if f.tag.trend {
+				fieldMode = trendField
+			}
+			c.collect(v.FieldByIndex(f.Index), p.child(f.step()), fieldMode)
+		}
+	case reflect.Map:
+		elemMode := trendNone
+		if mode == trendField {
+			elemMode = trendValue
+		}
+		for k, e := range v.Seq2() {
+			c.collect(e, p.child(keyStep(k)), elemMode)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			c.collect(v.Index(i), p.child(indexStep(i)), trendNone)
+		}
+	}
+}
+
+// numericValue returns v as a float64 if it's a number
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// isNumericType reports whether t is a number type
+func isNumericType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// derefType follows pointer types to the type they point to
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// valueOrMapElem returns the type of the values held by a field of type t
+// (following pointers) for directives that apply to a field's value or, for
+// maps, to each of its values.
+func valueOrMapElem(t reflect.Type) reflect.Type {
+	t = derefType(t)
+	if t.Kind() == reflect.Map {
+		return derefType(t.Elem())
+	}
+	return t
+}
+
+// trendable reports whether a field of type t can be tagged with trend: it
+// must be a number, or a map of numbers (following pointers).
+func trendable(t reflect.Type) bool {
+	return isNumericType(valueOrMapElem(t))
+}
+
+// trendTypesCache caches mayHaveTrends by type
+var trendTypesCache sync.Map
+
+// mayHaveTrends reports whether values of type t may hold fields tagged with
+// trend
+func mayHaveTrends(t reflect.Type) bool {
+	return mayHaveTagged(t, &trendTypesCache, func(ft *fieldTag) bool { return ft.trend })
+}
+
+// Dimensions of sparklines (in CSS pixels)
+const (
+	sparklineWidth  = 80
+	sparklineHeight = 16
+)
+
+// svgElem returns a new SVG element named name
+func svgElem(name string, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name)), Namespace: "svg"}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
+
+// sparkline returns an inline SVG plotting points, with their min, max and
+// last values in a tooltip.
+func sparkline(points []trendPoint) *html.Node {
+	lo, hi := points[0].val, points[0].val
+	for _, pt := range points {
+		lo, hi = min(lo, pt.val), max(hi, pt.val)
+	}
+	first, last := points[0], points[len(points)-1]
+	span := last.at.Sub(first.at)
+	coords := make([]string, 0, len(points))
+	for _, pt := range points {
+		x := 0.0
+		if span > 0 {
+			x = float64(pt.at.Sub(first.at)) / float64(span) * sparklineWidth
+		}
+		// flat trends are drawn across the middle
+		y := sparklineHeight / 2.0
+		if hi > lo {
+			// leave room for the stroke at the top and bottom
+			y = 1 + (hi-pt.val)/(hi-lo)*(sparklineHeight-2)
+		}
+		coords = append(coords, strconv.FormatFloat(x, 'f', 1, 64)+","+strconv.FormatFloat(y, 'f', 1, 64))
+	}
+
+	svg := svgElem("svg",
+		"class", "sparkline",
+		"width", strconv.Itoa(sparklineWidth),
+		"height", strconv.Itoa(sparklineHeight),
+		"viewBox", "0 0 "+strconv.Itoa(sparklineWidth)+" "+strconv.Itoa(sparklineHeight),
+		"role", "img")
+	title := svgElem("title")
+	title.AppendChild(textNode("min " + formatTrendVal(lo) + ", max " + formatTrendVal(hi) + ", last " + formatTrendVal(last.val) +
+		" (" + strconv.Itoa(len(points)) + " samples over " + span.Round(time.Second).String() + ")"))
+	svg.AppendChild(title)
+	svg.AppendChild(svgElem("polyline",
+		"points", strings.Join(coords, " "),
+		"fill", "none",
+		"stroke", "currentColor",
+		"stroke-width", "1"))
+	return svg
+}
+
+func formatTrendVal(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// withTrend appends the sparkline for the value at the current path to ns
+// (which renders v), if it has a trend.
+func (r *renderer) withTrend(v reflect.Value, ns []*html.Node) []*html.Node {
+	if r.trends == nil {
+		return ns
+	}
+	if _, numeric := numericValue(v); !numeric {
+		return ns
+	}
+	points := r.trends.trend(r.path)
+	if len(points) < 2 {
+		return ns
+	}
+	return append(ns, textNode(" "), sparkline(points))
+}
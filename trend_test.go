@@ -0,0 +1,142 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestTrendSeries(t *testing.T) {
+	ts := trendSeries{}
+	for i := range 5 {
+		ts.add(trendPoint{val: float64(i)}, 3)
+	}
+	got := []float64{}
+	for _, p := range ts.ordered() {
+		got = append(got, p.val)
+	}
+	if want := []float64{2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("series = %v; want the latest 3, %v", got, want)
+	}
+}
+
+type trendInner struct {
+	Q int `statuspage:"trend"`
+}
+
+type trendVal struct {
+	A     int                `statuspage:"trend"`
+	M     map[string]float64 `statuspage:"trend"`
+	P     *uint8             `statuspage:"trend"`
+	Plain int
+	In    trendInner
+	L     []trendInner
+}
+
+func TestTrendRecord(t *testing.T) {
+	ts := newTrendStore(10)
+	n := uint8(4)
+	at := time.Unix(0, 0)
+	v := trendVal{A: 1, M: map[string]float64{"a b": 1.5, "gone": 2}, P: &n, Plain: 3, In: trendInner{Q: 5}, L: []trendInner{{Q: 6}}}
+	ts.record(reflect.ValueOf(v), at)
+	delete(v.M, "gone")
+	v.A = 2
+	ts.record(reflect.ValueOf(v), at.Add(time.Minute))
+
+	got := map[string][]float64{}
+	for p, s := range ts.series {
+		for _, pt := range s.ordered() {
+			got[p] = append(got[p], pt.val)
+		}
+	}
+	want := map[string][]float64{
+		"/A":       {1, 2},
+		"/M/a%20b": {1.5, 1.5},
+		"/P":       {4, 4},
+		"/In/Q":    {5, 5},
+		"/L[0]/Q":  {6, 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trends = %v; want %v", got, want)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	render := func(points []trendPoint) string {
+		b := strings.Builder{}
+		if err := html.Render(&b, sparkline(points)); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	t0 := time.Unix(0, 0)
+	got := render([]trendPoint{{t0, 1}, {t0.Add(30 * time.Second), 3}, {t0.Add(time.Minute), 2}})
+	for _, want := range []string{
+		`<svg class="sparkline" width="80" height="16" viewBox="0 0 80 16" role="img">`,
+		`<title>min 1, max 3, last 2 (3 samples over 1m0s)</title>`,
+		`points="0.0,15.0 40.0,1.0 80.0,8.0"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sparkline doesn't contain %q:\n%s", want, got)
+		}
+	}
+	// flat trends are drawn across the middle
+	if flat := render([]trendPoint{{t0, 1}, {t0.Add(time.Second), 1}}); !strings.Contains(flat, `points="0.0,8.0 80.0,8.0"`) {
+		t.Errorf("flat sparkline isn't across the middle:\n%s", flat)
+	}
+}
+
+type trendPage struct {
+	Conns int `statuspage:"trend"`
+	Other int
+}
+
+func TestTrends(t *testing.T) {
+	calls := 0
+	s := New("Test", func() trendPage { calls++; return trendPage{Conns: calls, Other: calls} },
+		WithTrends(10, 0), WithHistory(time.Hour, 3))
+	defer s.Close()
+	waitForSnapshots(t, s, 1)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", target, rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+	// the sampler's call and this one make two samples
+	page := get("/")
+	if n := strings.Count(page, `<svg class="sparkline"`); n != 1 {
+		t.Errorf("page has %d sparklines; want 1 (for Conns):\n%s", n, page)
+	}
+	if !strings.Contains(page, "2 samples") {
+		t.Errorf("sparkline doesn't have 2 samples:\n%s", page)
+	}
+	// the trends lead up to now, so they aren't drawn on past snapshots
+	at := s.history.times()[0].UTC().Format(time.RFC3339Nano)
+	if page := get("/?at=" + url.QueryEscape(at)); strings.Contains(page, `<svg class="sparkline"`) {
+		t.Errorf("historical page has a sparkline:\n%s", page)
+	}
+}
+
+func TestTrendSampler(t *testing.T) {
+	calls := 0
+	s := New("Test", func() trendPage { calls++; return trendPage{Conns: calls} }, WithTrends(10, time.Millisecond))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.trends.trend(fieldPath{}.child(fieldStep("Conns")))) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the sampler didn't record the trend")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+}
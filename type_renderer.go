@@ -0,0 +1,164 @@
+package statuspage
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// typeRenderer is a registered renderer for values of one type
+type typeRenderer struct {
+	render func(reflect.Value) ([]*html.Node, error)
+	// scalar indicates the rendered value fits in a single table cell
+	scalar bool
+}
+
+// RendererOption configures a renderer registered with RegisterRenderer or
+// WithRenderer.
+type RendererOption func(*typeRenderer)
+
+// NonScalar marks a renderer's output as needing room of its own, like a
+// table: struct fields of the type get a titled section of their own instead
+// of a row in the struct's table. By default, renderers are assumed to
+// produce something small enough to sit next to other values. Either way,
+// elements of slices and values in maps get a table cell each.
+func NonScalar() RendererOption {
+	return func(tr *typeRenderer) {
+		tr.scalar = false
+	}
+}
+
+func newTypeRenderer[T any](fn func(T) ([]*html.Node, error), opts []RendererOption) typeRenderer {
+	tr := typeRenderer{
+		render: func(v reflect.Value) ([]*html.Node, error) {
+			return fn(v.Interface().(T))
+		},
+		scalar: true,
+	}
+	for _, o := range opts {
+		o(&tr)
+	}
+	return tr
+}
+
+// registeredRenderers holds the renderers registered with RegisterRenderer,
+// keyed by reflect.Type.
+var registeredRenderers sync.Map
+
+// RegisterRenderer registers fn to render values of type T on every Status
+// (and in GenHTMLNodes), in place of the default rendering (including any
+// String method). It's usually called from an init func. Renderers set with
+// WithRenderer take precedence.
+//
+// Registered renderers are used wherever a value of type T appears: struct
+// fields, map keys and values, and slice, array and iterator elements. The
+// plain-text format uses the text content of the nodes fn returns. The JSON
+// and OpenMetrics formats aren't affected.
+func RegisterRenderer[T any](fn func(T) ([]*html.Node, error), opts ...RendererOption) {
+	registeredRenderers.Store(reflect.TypeFor[T](), newTypeRenderer(fn, opts))
+}
+
+// WithRenderer sets fn as the renderer for values of type T on this Status,
+// overriding any renderer registered with RegisterRenderer. See
+// RegisterRenderer for details.
+func WithRenderer[T any](fn func(T) ([]*html.Node, error), opts ...RendererOption) Option {
+	return func(o *options) {
+		if o.renderers == nil {
+			o.renderers = map[reflect.Type]typeRenderer{}
+		}
+		o.renderers[reflect.TypeFor[T]()] = newTypeRenderer(fn, opts)
+	}
+}
+
+// typeRendererFor returns the renderer for values of type t, if one has
+// been registered.
+func (o *options) typeRendererFor(t reflect.Type) (typeRenderer, bool) {
+	if tr, ok := o.renderers[t]; ok {
+		return tr, true
+	}
+	if tr, ok := registeredRenderers.Load(t); ok {
+		return tr.(typeRenderer), true
+	}
+	return typeRenderer{}, false
+}
+
+// valueRenderer returns the renderer to use for v, if there is one. Nil
+// pointers and interfaces get the default rendering.
+func (o *options) valueRenderer(v reflect.Value) (typeRenderer, bool) {
+	if !v.IsValid() || !v.CanInterface() || isNilRef(v) {
+		return typeRenderer{}, false
+	}
+	return o.typeRendererFor(v.Type())
+}
+
+// layoutRenderer returns the renderer that will end up rendering values of
+// type t (following pointers), for layout decisions.
+func (o *options) layoutRenderer(t reflect.Type) (typeRenderer, bool) {
+	for {
+		if tr, ok := o.typeRendererFor(t); ok {
+			return tr, true
+		}
+		if t.Kind() != reflect.Pointer {
+			return typeRenderer{}, false
+		}
+		t = t.Elem()
+	}
+}
+
+// pointsToRendered reports whether t is a pointer to a type with a renderer,
+// which takes precedence over any String method promoted to the pointer.
+func (o *options) pointsToRendered(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer {
+		return false
+	}
+	_, ok := o.layoutRenderer(t.Elem())
+	return ok
+}
+
+// needsTable is needsTable, taking registered renderers into account
+func (r *renderer) needsTable(t reflect.Type) bool {
+	if tr, ok := r.opts.layoutRenderer(t); ok {
+		return !tr.scalar
+	}
+	return needsTable(t)
+}
+
+// sliceArrayValScalar is sliceArrayValScalar, taking registered renderers
+// and StatusRenderers into account: elements with either are always rendered
+// into a single cell each, rather than being split into columns.
+func (r *renderer) sliceArrayValScalar(et reflect.Type) bool {
+	if _, ok := r.opts.layoutRenderer(et); ok || rendersItself(et) {
+		return true
+	}
+	return sliceArrayValScalar(et)
+}
+
+// ownCell reports whether values of type t get a single table cell in a map
+// table, rather than being split into columns.
+func (r *renderer) ownCell(t reflect.Type) bool {
+	if _, ok := r.opts.layoutRenderer(t); ok || rendersItself(t) {
+		return true
+	}
+	return !needsTable(t)
+}
+
+// nodesText returns the text content of ns, for rendering the output of a
+// registered renderer as plain text.
+func nodesText(ns []*html.Node) string {
+	b := strings.Builder{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range ns {
+		walk(n)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package statuspage_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	statuspage "github.com/vimeo/go-status-page"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type cents int64
+
+func (c cents) String() string { return "plain" }
+
+func boldText(s string) []*html.Node {
+	b := &html.Node{Type: html.ElementNode, DataAtom: atom.B, Data: "b"}
+	b.AppendChild(&html.Node{Type: html.TextNode, Data: s})
+	return []*html.Node{b}
+}
+
+func renderCents(c cents) ([]*html.Node, error) {
+	return boldText("$" + strconv.FormatInt(int64(c)/100, 10) + "." + strconv.FormatInt(int64(c)%100, 10)), nil
+}
+
+type centsVal struct {
+	Price   cents
+	Ptr     *cents
+	NilPtr  *cents
+	Prices  []cents
+	ByPrice map[cents]cents
+}
+
+func TestWithRenderer(t *testing.T) {
+	ten := cents(1000)
+	v := centsVal{Price: 125, Ptr: &ten, Prices: []cents{101, 202}, ByPrice: map[cents]cents{303: 404}}
+	page := serveStatus(t, v, "", statuspage.WithRenderer(renderCents))
+	checkTokens(t, page)
+	for _, want := range []string{
+		// struct rows, including through pointers (the renderer takes
+		// precedence over the pointer's String method)
+		"<b>$1.25</b>", "<b>$10.0</b>",
+		"*statuspage_test.cents(nil)",
+		// slice elements, and map keys and values
+		"<b>$1.1</b>", "<b>$2.2</b>", "<b>$3.3</b>", "<b>$4.4</b>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, ">plain<") {
+		t.Errorf("String was used despite the renderer:\n%s", page)
+	}
+
+	// the text format uses the text of the nodes
+	text := serveStatus(t, v, "?format=text", statuspage.WithRenderer(renderCents))
+	for _, want := range []string{"│ Price  │ $1.25", "│ Ptr    │ $10.0", "│ $1.1 │", "│ $3.3 │ $4.4  │"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text doesn't contain %q:\n%s", want, text)
+		}
+	}
+
+	// JSON isn't affected
+	if js := serveStatus(t, v, "?format=json", statuspage.WithRenderer(renderCents)); !strings.Contains(js, `"Price": "plain"`) {
+		t.Errorf("JSON used the renderer:\n%s", js)
+	}
+}
+
+type shardID int
+
+func init() {
+	statuspage.RegisterRenderer(func(s shardID) ([]*html.Node, error) {
+		return boldText("shard-" + strconv.Itoa(int(s))), nil
+	})
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	v := struct{ Shard shardID }{Shard: 7}
+	if page := serveStatus(t, v, ""); !strings.Contains(page, "<b>shard-7</b>") {
+		t.Errorf("registered renderer wasn't used:\n%s", page)
+	}
+	// renderers set on the Status take precedence
+	override := statuspage.WithRenderer(func(s shardID) ([]*html.Node, error) {
+		return boldText("override"), nil
+	})
+	if page := serveStatus(t, v, "", override); !strings.Contains(page, "<b>override</b>") {
+		t.Errorf("Status's renderer wasn't used:\n%s", page)
+	}
+}
+
+type bitset uint8
+
+func TestNonScalar(t *testing.T) {
+	render := func(b bitset) ([]*html.Node, error) {
+		return boldText(strconv.FormatUint(uint64(b), 2)), nil
+	}
+	v := struct {
+		Flags bitset
+		N     int
+	}{Flags: 5, N: 1}
+
+	// scalar renderers sit in the struct's table
+	page := serveStatus(t, v, "", statuspage.WithRenderer(render))
+	if !strings.Contains(page, `<tr id="row/Flags"><th class="sp-field-name" scope="row">Flags</th><td class="sp-value"><b>101</b></td></tr>`) {
+		t.Errorf("scalar renderer's output isn't in a row:\n%s", page)
+	}
+	// others get sections of their own
+	page = serveStatus(t, v, "", statuspage.WithRenderer(render, statuspage.NonScalar()))
+	if !strings.Contains(page, `<h3 class="sp-field-name">Flags `) || strings.Contains(page, `id="row/Flags"`) {
+		t.Errorf("non-scalar renderer's output isn't in a section:\n%s", page)
+	}
+}
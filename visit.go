@@ -0,0 +1,162 @@
+package statuspage
+
+import (
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// visitKey identifies the target of a pointer, or the contents of a map or
+// slice, so we can recognize it if we run into it again while walking a
+// value. The type is included because a pointer to a struct and a pointer to
+// its first field share an address.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// visitKeyOf returns the visitKey for v, and false if v isn't a kind of
+// value that can be aliased (or could only alias something trivial).
+func visitKeyOf(v reflect.Value) (visitKey, bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || eligibleStringer(v.Type()) {
+			return visitKey{}, false
+		}
+		// pointers to scalars can't lead to cycles, and are more useful
+		// rendered in place
+		switch v.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer, reflect.Interface:
+			return visitKey{ptr: v.Pointer(), typ: v.Type()}, true
+		default:
+			return visitKey{}, false
+		}
+	case reflect.Map:
+		if v.IsNil() || v.Len() == 0 {
+			return visitKey{}, false
+		}
+		return visitKey{ptr: v.Pointer(), typ: v.Type()}, true
+	case reflect.Slice:
+		// empty slices may all point at the same zero-sized allocation
+		if v.IsNil() || v.Len() == 0 {
+			return visitKey{}, false
+		}
+		return visitKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}, true
+	default:
+		return visitKey{}, false
+	}
+}
+
+// anchorID returns the HTML id for the element rendering the value at p
+func anchorID(p fieldPath) string {
+	return "sp" + p.String()
+}
+
+// rowID returns the id of the table row rendering the value at p (which is
+// distinct from its anchor, since the value may have a table of its own
+// within the row).
+func rowID(p fieldPath) string {
+	return "row" + p.String()
+}
+
+// setAnchor makes sure ns carries the anchor for the current path: unless
+// one of its nodes already has it, its first node gets an id if it's an
+// element, otherwise an empty element carrying the id is prepended. Rows keep
+// their own ids, so their anchors go in their first cells.
+func (r *renderer) setAnchor(ns []*html.Node) []*html.Node {
+	id := anchorID(r.path)
+	if len(ns) > 0 && ns[0].DataAtom == atom.Tr && ns[0].FirstChild != nil {
+		cell := ns[0].FirstChild
+		if first := cell.FirstChild; first != nil {
+			if existing, _ := attr(first, atom.Id.String()); existing == id {
+				return ns
+			}
+		}
+		anchor := createElemAtom(atom.Span)
+		setAttr(anchor, atom.Id.String(), id)
+		cell.InsertBefore(anchor, cell.FirstChild)
+		return ns
+	}
+	for _, n := range ns {
+		// values are transparently wrapped by pointers, and
+		// StatusRenderers may put their default rendering (which carries
+		// the anchor for the same path) after nodes of their own
+		if existing, _ := attr(n, atom.Id.String()); existing == id {
+			return ns
+		}
+	}
+	if len(ns) > 0 && ns[0].Type == html.ElementNode {
+		if _, hasID := attr(ns[0], atom.Id.String()); !hasID {
+			ns[0].Attr = append(ns[0].Attr, html.Attribute{Key: atom.Id.String(), Val: id})
+			return ns
+		}
+	}
+	anchor := createElemAtom(atom.Span)
+	anchor.Attr = append(anchor.Attr, html.Attribute{Key: atom.Id.String(), Val: id})
+	return append([]*html.Node{anchor}, ns...)
+}
+
+// visitOnce renders v (via gen) and records where it was rendered, unless v
+// was already rendered earlier in this render. Repeats (whether from a cycle
+// or from aliasing) are rendered as a link back to the first occurrence, and
+// repeat is set.
+func (r *renderer) visitOnce(v reflect.Value, gen func() ([]*html.Node, error)) (ns []*html.Node, repeat bool, err error) {
+	key, trackable := visitKeyOf(v)
+	if !trackable {
+		ns, err = gen()
+		return ns, false, err
+	}
+	if first, seen := r.visited[key]; seen {
+		return r.seeAbove(first), true, nil
+	}
+	r.markVisited(key)
+	ns, err = gen()
+	if err != nil {
+		return nil, false, err
+	}
+	return r.setAnchor(ns), false, nil
+}
+
+// markVisited records that the value with the key key was first rendered at
+// the current path
+func (r *renderer) markVisited(key visitKey) {
+	r.visited[key] = r.path
+	r.visitLog = append(r.visitLog, key)
+}
+
+// withFreshVisits starts a separate record of the values rendered, returning
+// a func that goes back to the previous one. Values rendered in between
+// neither link to nor are linked to from the rest of the render.
+func (r *renderer) withFreshVisits() func() {
+	visited, visitLog := r.visited, r.visitLog
+	r.visited, r.visitLog = map[visitKey]fieldPath{}, nil
+	return func() { r.visited, r.visitLog = visited, visitLog }
+}
+
+// pathLabel returns the human-readable form of p
+func (r *renderer) pathLabel(p fieldPath) string {
+	labels := make([]string, 0, len(p)+1)
+	labels = append(labels, r.title)
+	for _, st := range p {
+		labels = append(labels, st.label)
+	}
+	return strings.Join(labels, " › ")
+}
+
+// seeAboveLabel marks a value that was already rendered earlier on the page
+const seeAboveLabel = "↻ see above"
+
+// seeAbove returns the marker rendered in place of a value we've already
+// rendered (either because it's an ancestor of itself, or because it's
+// aliased) linking back to its first occurrence at path first.
+func (r *renderer) seeAbove(first fieldPath) []*html.Node {
+	a := createElemClass(atom.A, "sp-see-above")
+	a.Attr = append(a.Attr,
+		html.Attribute{Key: atom.Href.String(), Val: "#" + anchorID(first)},
+		html.Attribute{Key: atom.Title.String(), Val: r.pathLabel(first)})
+	a.AppendChild(textNode(seeAboveLabel))
+	return []*html.Node{a}
+}
@@ -0,0 +1,122 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestVisitKeyOf(t *testing.T) {
+	type pair struct{ A, B int }
+	p := &pair{}
+	s := []int{1, 2, 3}
+	m := map[string]int{"a": 1}
+	n := 1
+	for _, tc := range []struct {
+		name      string
+		v         any
+		trackable bool
+	}{
+		{"pointer to a struct", p, true},
+		{"nil pointer", (*pair)(nil), false},
+		{"pointer to a scalar", &n, false},
+		{"map", m, true},
+		{"empty map", map[string]int{}, false},
+		{"nil map", map[string]int(nil), false},
+		{"slice", s, true},
+		{"empty slice", s[:0], false},
+		{"struct", pair{}, false},
+		{"int", 1, false},
+	} {
+		if _, got := visitKeyOf(reflect.ValueOf(tc.v)); got != tc.trackable {
+			t.Errorf("%s: trackable = %t; want %t", tc.name, got, tc.trackable)
+		}
+	}
+
+	key := func(v any) visitKey {
+		k, _ := visitKeyOf(reflect.ValueOf(v))
+		return k
+	}
+	if key(s) == key(s[:2]) {
+		t.Error("slices of different lengths share a key")
+	}
+	if key(s) != key(s[:3]) {
+		t.Error("identical slices have different keys")
+	}
+	if key(p) == key(&struct{ A int }{}) || key(&p.A) == key(p) {
+		t.Error("a pointer to a struct and a pointer to its first field share a key")
+	}
+}
+
+type visitNode struct {
+	Name   string
+	Parent *visitNode
+	Kids   []*visitNode
+}
+
+type visitVal struct {
+	Root   *visitNode
+	Alias  *visitNode
+	M1, M2 map[string]int
+	S      []int
+	Prefix []int
+	Self   *visitNode
+}
+
+func TestCyclesAndAliases(t *testing.T) {
+	root := &visitNode{Name: "root"}
+	kid := &visitNode{Name: "kid", Parent: root}
+	root.Kids = []*visitNode{kid}
+	self := &visitNode{Name: "self"}
+	self.Parent = self
+	m := map[string]int{"a": 1}
+	s := []int{1, 2}
+	v := visitVal{Root: root, Alias: kid, M1: m, M2: m, S: s, Prefix: s[:1], Self: self}
+
+	rec := httptest.NewRecorder()
+	New("Test", func() visitVal { return v }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	doc, parseErr := html.Parse(strings.NewReader(rec.Body.String()))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	ids := map[string]bool{}
+	links := []string{}
+	for n := range doc.Descendants() {
+		if id, ok := attr(n, "id"); ok {
+			ids[id] = true
+		}
+		if hasClass(n, "sp-see-above") {
+			href, _ := attr(n, "href")
+			links = append(links, href)
+		}
+	}
+	want := []string{
+		// the kid's parent is an ancestor
+		"#sp/Root",
+		// aliases of values rendered earlier
+		"#sp/Root/Kids[0]",
+		"#sp/M1",
+		// a pointer to itself
+		"#sp/Self",
+	}
+	if !slices.Equal(links, want) {
+		t.Errorf("see-above links = %q; want %q", links, want)
+	}
+	for _, l := range links {
+		if !ids[strings.TrimPrefix(l, "#")] {
+			t.Errorf("link to %s, which isn't on the page", l)
+		}
+	}
+	// a prefix of a slice isn't the same value
+	if !ids["sp/Prefix"] {
+		t.Error("Prefix isn't rendered in full")
+	}
+}